@@ -0,0 +1,78 @@
+package immutable
+
+// History represents an undo/redo history of immutable values such as *List
+// or *Map. Since immutable collections share structure between versions,
+// retaining prior versions is inexpensive.
+//
+// History is itself immutable. Each change returns a new history so earlier
+// histories remain valid.
+type History[T any] struct {
+	current T
+	undo    *List[T] // prior versions, oldest first
+	redo    *List[T] // undone versions, most recently undone last
+}
+
+// NewHistory returns a new history with v as the current version.
+func NewHistory[T any](v T) *History[T] {
+	return &History[T]{
+		current: v,
+		undo:    NewList[T](),
+		redo:    NewList[T](),
+	}
+}
+
+// Current returns the current version.
+func (h *History[T]) Current() T {
+	return h.current
+}
+
+// Push returns a new history with v as the current version. The previous
+// current version can be restored with Undo(). Any undone versions are
+// discarded.
+func (h *History[T]) Push(v T) *History[T] {
+	return &History[T]{
+		current: v,
+		undo:    h.undo.Append(h.current),
+		redo:    NewList[T](),
+	}
+}
+
+// UndoLen returns the number of versions that can be restored with Undo().
+func (h *History[T]) UndoLen() int {
+	return h.undo.Len()
+}
+
+// RedoLen returns the number of versions that can be restored with Redo().
+func (h *History[T]) RedoLen() int {
+	return h.redo.Len()
+}
+
+// Undo returns the previous version and a history with that version as the
+// current version. Returns ok as false and the original history if there are
+// no prior versions.
+func (h *History[T]) Undo() (value T, other *History[T], ok bool) {
+	value, undo, ok := h.undo.PopLast()
+	if !ok {
+		return value, h, false
+	}
+	return value, &History[T]{
+		current: value,
+		undo:    undo,
+		redo:    h.redo.Append(h.current),
+	}, true
+}
+
+// Redo returns the most recently undone version and a history with that
+// version as the current version. Returns ok as false and the original history
+// if there are no undone versions.
+func (h *History[T]) Redo() (value T, other *History[T], ok bool) {
+	value, redo, ok := h.redo.PopLast()
+	if !ok {
+		return value, h, false
+	}
+	return value, &History[T]{
+		current: value,
+		undo:    h.undo.Append(h.current),
+		redo:    redo,
+	}, true
+}
@@ -0,0 +1,67 @@
+package immutable
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	t.Run("List", func(t *testing.T) {
+		h := NewHistory(NewList[int]())
+		for i := 0; i < 3; i++ {
+			h = h.Push(h.Current().Append(i))
+		}
+		if got, exp := fmt.Sprint(h.Current().ToSlice()), "[0 1 2]"; got != exp {
+			t.Fatalf("Current()=%s, expected %s", got, exp)
+		} else if h.UndoLen() != 3 || h.RedoLen() != 0 {
+			t.Fatalf("unexpected lengths: %d/%d", h.UndoLen(), h.RedoLen())
+		}
+
+		l, undone, ok := h.Undo()
+		if !ok || l.Len() != 2 || undone.Current() != l {
+			t.Fatalf("unexpected undo: %v", l.ToSlice())
+		}
+		_, undone, _ = undone.Undo()
+		if got, exp := fmt.Sprint(undone.Current().ToSlice()), "[0]"; got != exp {
+			t.Fatalf("Current()=%s, expected %s", got, exp)
+		} else if undone.RedoLen() != 2 {
+			t.Fatalf("unexpected redo len: %d", undone.RedoLen())
+		}
+
+		l, redone, ok := undone.Redo()
+		if !ok || l.Len() != 2 {
+			t.Fatalf("unexpected redo: %v", l.ToSlice())
+		} else if h.Current().Len() != 3 {
+			t.Fatal("original history changed")
+		}
+
+		// Pushing a new version discards undone versions.
+		if other := redone.Push(NewList(9)); other.RedoLen() != 0 || other.UndoLen() != 3 {
+			t.Fatalf("unexpected lengths: %d/%d", other.UndoLen(), other.RedoLen())
+		}
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		h := NewHistory(NewMap[string, int](nil))
+		h = h.Push(h.Current().Set("foo", 1))
+		h = h.Push(h.Current().Set("foo", 2))
+
+		m, h, _ := h.Undo()
+		if v, _ := m.Get("foo"); v != 1 {
+			t.Fatalf("unexpected value: %d", v)
+		}
+		m, _, _ = h.Redo()
+		if v, _ := m.Get("foo"); v != 2 {
+			t.Fatalf("unexpected value: %d", v)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		h := NewHistory(NewList[int]())
+		if _, other, ok := h.Undo(); ok || other != h {
+			t.Fatal("expected no undo")
+		} else if _, other, ok := h.Redo(); ok || other != h {
+			t.Fatal("expected no redo")
+		}
+	})
+}
@@ -14,6 +14,18 @@
 // with Go's built-in collection types so please evaluate for your specific
 // use.
 //
+// # Concurrency
+//
+// Read-only methods such as Get(), Len(), and Iterator() never modify a
+// collection so any number of goroutines may call them concurrently. Methods
+// which return a new collection also leave the original untouched and may be
+// called concurrently with readers.
+//
+// Iterators and builders, however, hold mutable state and are not safe for
+// concurrent use. Each goroutine should create its own iterator and a builder
+// should only be used by one goroutine at a time. Collections returned from a
+// builder's List(), Map(), or Build() methods are immutable and safe to share.
+//
 // # Collection Types
 //
 // The List type provides an API similar to Go slices. They allow appending,
@@ -42,6 +54,9 @@
 package immutable
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
 	"math/bits"
 	"reflect"
@@ -63,12 +78,17 @@ type List[T any] struct {
 
 // NewList returns a new empty instance of List.
 func NewList[T any](values ...T) *List[T] {
+	return NewListFromSlice(values)
+}
+
+// NewListFromSlice returns a new instance of List containing the elements of
+// s. Elements are copied into leaf nodes in bulk so the list is built in O(n)
+// time. The list does not retain a reference to s.
+func NewListFromSlice[T any](s []T) *List[T] {
 	l := &List[T]{
 		root: &listLeafNode[T]{},
 	}
-	for _, value := range values {
-		l.append(value, true)
-	}
+	l.appendSlice(s)
 	return l
 }
 
@@ -78,11 +98,23 @@ func (l *List[T]) clone() *List[T] {
 	return &other
 }
 
+// Clone returns a copy of the list which can be held independently of the
+// original. Nodes are shared between the copies since they are never mutated
+// in-place, so this is an O(1) operation.
+func (l *List[T]) Clone() *List[T] {
+	return l.clone()
+}
+
 // Len returns the number of elements in the list.
 func (l *List[T]) Len() int {
 	return l.size
 }
 
+// IsEmpty returns true if the list contains no elements.
+func (l *List[T]) IsEmpty() bool {
+	return l.size == 0
+}
+
 // cap returns the total number of possible elements for the current depth.
 func (l *List[T]) cap() int {
 	return 1 << (l.root.depth() * listNodeBits)
@@ -97,6 +129,40 @@ func (l *List[T]) Get(index int) T {
 	return l.root.get(l.origin + index)
 }
 
+// First returns the first value in the list. Returns false if the list is empty.
+func (l *List[T]) First() (value T, ok bool) {
+	if l.size == 0 {
+		return value, false
+	}
+	return l.root.get(l.origin), true
+}
+
+// Last returns the last value in the list. Returns false if the list is empty.
+func (l *List[T]) Last() (value T, ok bool) {
+	if l.size == 0 {
+		return value, false
+	}
+	return l.root.get(l.origin + l.size - 1), true
+}
+
+// PopFirst returns the first value in the list and a new list with that value
+// removed. Returns false and the original list if the list is empty.
+func (l *List[T]) PopFirst() (value T, other *List[T], ok bool) {
+	if l.size == 0 {
+		return value, l, false
+	}
+	return l.root.get(l.origin), l.Slice(1, l.size), true
+}
+
+// PopLast returns the last value in the list and a new list with that value
+// removed. Returns false and the original list if the list is empty.
+func (l *List[T]) PopLast() (value T, other *List[T], ok bool) {
+	if l.size == 0 {
+		return value, l, false
+	}
+	return l.root.get(l.origin + l.size - 1), l.Slice(0, l.size-1), true
+}
+
 // Set returns a new list with value set at index. Similar to slices, this
 // method will panic if index is below zero or if the index is greater than
 // or equal to the list size.
@@ -104,6 +170,45 @@ func (l *List[T]) Set(index int, value T) *List[T] {
 	return l.set(index, value, false)
 }
 
+// SetOrAppend returns a new list with value set at index. If index is equal
+// to the list size then value is appended instead, similar to writing at the
+// length of a Go slice with append(). This method will panic if index is below
+// zero or greater than the list size.
+func (l *List[T]) SetOrAppend(index int, value T) *List[T] {
+	if index == l.size {
+		return l.Append(value)
+	} else if index < 0 || index > l.size {
+		panic(fmt.Sprintf("immutable.List.SetOrAppend: index %d out of bounds", index))
+	}
+	return l.set(index, value, false)
+}
+
+// Swap returns a new list with the values at indexes i and j exchanged. The
+// nodes shared by the paths to both indexes are only copied once. Similar to
+// slices, this method will panic if either index is below zero or greater
+// than or equal to the list size.
+func (l *List[T]) Swap(i, j int) *List[T] {
+	return l.swap(i, j, false)
+}
+
+func (l *List[T]) swap(i, j int, mutable bool) *List[T] {
+	if i < 0 || i >= l.size {
+		panic(fmt.Sprintf("immutable.List.Swap: index %d out of bounds", i))
+	} else if j < 0 || j >= l.size {
+		panic(fmt.Sprintf("immutable.List.Swap: index %d out of bounds", j))
+	} else if i == j {
+		return l
+	}
+
+	other := l
+	if !mutable {
+		other = l.clone()
+	}
+	vi, vj := l.root.get(l.origin+i), l.root.get(l.origin+j)
+	other.root = other.root.setPair(l.origin+i, vj, l.origin+j, vi, mutable)
+	return other
+}
+
 func (l *List[T]) set(index int, value T, mutable bool) *List[T] {
 	if index < 0 || index >= l.size {
 		panic(fmt.Sprintf("immutable.List.Set: index %d out of bounds", index))
@@ -140,6 +245,50 @@ func (l *List[T]) append(value T, mutable bool) *List[T] {
 	return other
 }
 
+// AppendShared returns a new list with value added to the end of the list
+// along with the number of nodes allocated by the append. All other nodes are
+// shared with the original list. This is intended as a diagnostic for
+// understanding the copy-on-write cost of an access pattern.
+func (l *List[T]) AppendShared(value T) (*List[T], int) {
+	other := l.Append(value)
+	return other, countNewListNodes(l.root, other.root, other.origin+other.size-1)
+}
+
+// SetShared returns a new list with value set at index along with the number
+// of nodes copied by the update. See AppendShared() for more details.
+func (l *List[T]) SetShared(index int, value T) (*List[T], int) {
+	other := l.Set(index, value)
+	return other, countNewListNodes(l.root, other.root, other.origin+index)
+}
+
+// countNewListNodes returns the number of nodes along the path to index in n
+// which do not exist in prev. Both trees must use the same origin.
+func countNewListNodes[T any](prev, n listNode[T], index int) int {
+	var count int
+	for n != nil {
+		if prev != n {
+			count++
+		}
+
+		branch, ok := n.(*listBranchNode[T])
+		if !ok {
+			break
+		}
+		idx := (index >> (branch.d * listNodeBits)) & listNodeMask
+		n = branch.children[idx]
+
+		// Only descend the previous tree once the new tree reaches the same depth.
+		if prev != nil && prev.depth() == branch.d {
+			if prevBranch, ok := prev.(*listBranchNode[T]); ok {
+				prev = prevBranch.children[idx]
+			} else {
+				prev = nil
+			}
+		}
+	}
+	return count
+}
+
 // Prepend returns a new list with value(s) added to the beginning of the list.
 func (l *List[T]) Prepend(value T) *List[T] {
 	return l.prepend(value, false)
@@ -166,6 +315,221 @@ func (l *List[T]) prepend(value T, mutable bool) *List[T] {
 	return other
 }
 
+// ConcatSlice returns a new list with values added to the end of the list.
+// Returns the original list if values is empty.
+func (l *List[T]) ConcatSlice(values []T) *List[T] {
+	if len(values) == 0 {
+		return l
+	}
+
+	// The first append copies the path to the end of the list. Every later
+	// append only touches nodes on that path or newly created nodes so they
+	// can be updated in-place.
+	other := l.append(values[0], false)
+	other.appendSlice(values[1:])
+	return other
+}
+
+// NewListRepeat returns a new instance of List containing n copies of v.
+// Returns an empty list if n is zero. This method will panic if n is negative.
+func NewListRepeat[T any](v T, n int) *List[T] {
+	if n < 0 {
+		panic(fmt.Sprintf("immutable.NewListRepeat: negative count %d", n))
+	}
+
+	// Fill a single leaf's worth of values and bulk append it repeatedly.
+	chunk := make([]T, listNodeSize)
+	if n < len(chunk) {
+		chunk = chunk[:n]
+	}
+	for i := range chunk {
+		chunk[i] = v
+	}
+
+	l := NewList[T]()
+	for ; n > 0; n -= len(chunk) {
+		if n < len(chunk) {
+			chunk = chunk[:n]
+		}
+		l.appendSlice(chunk)
+	}
+	return l
+}
+
+// AppendAll returns a new list with values added to the end of the list.
+// See ConcatSlice() for more details.
+func (l *List[T]) AppendAll(values ...T) *List[T] {
+	return l.ConcatSlice(values)
+}
+
+// appendSlice adds values to the end of the list in-place. Values are copied
+// directly into leaf nodes rather than being set one at a time.
+func (l *List[T]) appendSlice(values []T) {
+	for len(values) > 0 {
+		// Expand list to the right if no slots remain. A leaf root is always
+		// expanded so that leaves can be reached through a branch.
+		if l.size+l.origin >= l.cap() || l.root.depth() == 0 {
+			newRoot := &listBranchNode[T]{d: l.root.depth() + 1}
+			newRoot.children[0] = l.root
+			l.root = newRoot
+		}
+
+		// Fill the remainder of the leaf containing the next index.
+		index := l.origin + l.size
+		off := index & listNodeMask
+		n := listNodeSize - off
+		if n > len(values) {
+			n = len(values)
+		}
+
+		leaf := l.root.(*listBranchNode[T]).leaf(index)
+		copy(leaf.children[off:], values[:n])
+		leaf.occupied |= uint32((uint64(1)<<n - 1) << off)
+
+		l.size += n
+		values = values[n:]
+	}
+}
+
+// prependSlice adds values to the beginning of the list in-place. Values are
+// copied directly into leaf nodes rather than being set one at a time.
+func (l *List[T]) prependSlice(values []T) {
+	for len(values) > 0 {
+		// Expand list to the left if no slots remain. A leaf root is always
+		// expanded so that leaves can be reached through a branch.
+		if l.origin == 0 || l.root.depth() == 0 {
+			newRoot := &listBranchNode[T]{d: l.root.depth() + 1}
+			newRoot.children[listNodeSize-1] = l.root
+			l.root = newRoot
+			l.origin += (listNodeSize - 1) << (l.root.depth() * listNodeBits)
+		}
+
+		// Fill the leaf containing the previous index from right to left.
+		index := l.origin - 1
+		off := index & listNodeMask
+		n := off + 1
+		if n > len(values) {
+			n = len(values)
+		}
+
+		leaf := l.root.(*listBranchNode[T]).leaf(index)
+		copy(leaf.children[off-n+1:], values[len(values)-n:])
+		leaf.occupied |= uint32((uint64(1)<<n - 1) << (off - n + 1))
+
+		l.origin -= n
+		l.size += n
+		values = values[:len(values)-n]
+	}
+}
+
+// InsertAt returns a new list with value inserted at index. Elements at and
+// after index are shifted up by one. Inserting at an index equal to the list
+// size is the same as appending. This method will panic if index is below zero
+// or greater than the list size.
+func (l *List[T]) InsertAt(index int, value T) *List[T] {
+	return l.insertAt(index, value, false)
+}
+
+func (l *List[T]) insertAt(index int, value T, mutable bool) *List[T] {
+	if index < 0 || index > l.size {
+		panic(fmt.Sprintf("immutable.List.InsertAt: index %d out of bounds", index))
+	}
+
+	// Rebuild whichever side of the index contains fewer elements. Adding the
+	// value copies the path to the edge of the list so the remaining elements
+	// can be bulk loaded in-place. See ConcatSlice() for more details.
+	if index < l.size/2 {
+		head := l.values(0, index)
+		other := l.slice(index, l.size, mutable).prepend(value, mutable)
+		other.prependSlice(head)
+		return other
+	}
+
+	tail := l.values(index, l.size)
+	other := l.slice(0, index, mutable).append(value, mutable)
+	other.appendSlice(tail)
+	return other
+}
+
+// InsertSortedBy returns a new list with value inserted at the position which
+// keeps the list sorted by key along with the index of the inserted value.
+// Values with equal keys are kept in insertion order. The list must already be
+// sorted by key.
+func (l *List[T]) InsertSortedBy(value T, key func(T) int) (*List[T], int) {
+	k := key(value)
+	index := sort.Search(l.size, func(i int) bool { return key(l.Get(i)) > k })
+	return l.InsertAt(index, value), index
+}
+
+// RemoveAt returns a new list with the element at index removed. Elements
+// after index are shifted down by one. Similar to slices, this method will
+// panic if index is below zero or greater than or equal to the list size.
+func (l *List[T]) RemoveAt(index int) *List[T] {
+	return l.removeAt(index, false)
+}
+
+func (l *List[T]) removeAt(index int, mutable bool) *List[T] {
+	if index < 0 || index >= l.size {
+		panic(fmt.Sprintf("immutable.List.RemoveAt: index %d out of bounds", index))
+	}
+
+	// Rebuild whichever side of the index contains fewer elements. The element
+	// nearest the index is added first to copy the path to the edge of the list
+	// and the rest are bulk loaded in-place. See ConcatSlice() for more details.
+	if index < l.size/2 {
+		head := l.values(0, index)
+		other := l.slice(index+1, l.size, mutable)
+		if len(head) == 0 {
+			return other
+		}
+		other = other.prepend(head[len(head)-1], mutable)
+		other.prependSlice(head[:len(head)-1])
+		return other
+	}
+
+	tail := l.values(index+1, l.size)
+	other := l.slice(0, index, mutable)
+	if len(tail) == 0 {
+		return other
+	}
+	other = other.append(tail[0], mutable)
+	other.appendSlice(tail[1:])
+	return other
+}
+
+// values returns a slice of the elements between start and end index.
+func (l *List[T]) values(start, end int) []T {
+	a := make([]T, 0, end-start)
+	if start == end {
+		return a
+	}
+
+	itr := l.Iterator()
+	for itr.Seek(start); len(a) < cap(a); {
+		_, v := itr.Next()
+		a = append(a, v)
+	}
+	return a
+}
+
+// Concat returns a new list with the elements of other added to the end of
+// the list. The larger of the two lists is used as the base of the new list so
+// that its nodes are shared and only the elements of the smaller list are copied.
+func (l *List[T]) Concat(other *List[T]) *List[T] {
+	if other.Len() <= l.Len() {
+		return l.ConcatSlice(other.ToSlice())
+	} else if l.Len() == 0 {
+		return other
+	}
+
+	// The first prepend copies the path to the start of the list so the
+	// remaining values can be added in-place. See ConcatSlice().
+	values := l.ToSlice()
+	result := other.prepend(values[len(values)-1], false)
+	result.prependSlice(values[:len(values)-1])
+	return result
+}
+
 // Slice returns a new list of elements between start index and end index.
 // Similar to slices, this method will panic if start or end are below zero or
 // greater than the list size. A panic will also occur if start is greater than
@@ -177,6 +541,18 @@ func (l *List[T]) Slice(start, end int) *List[T] {
 	return l.slice(start, end, false)
 }
 
+// SliceFrom returns a new list of elements from start index to the end of the
+// list. This is equivalent to l.Slice(start, l.Len()).
+func (l *List[T]) SliceFrom(start int) *List[T] {
+	return l.Slice(start, l.size)
+}
+
+// SliceTo returns a new list of elements from the beginning of the list up to
+// the end index. This is equivalent to l.Slice(0, end).
+func (l *List[T]) SliceTo(end int) *List[T] {
+	return l.Slice(0, end)
+}
+
 func (l *List[T]) slice(start, end int, mutable bool) *List[T] {
 	// Panics similar to Go slices.
 	if start < 0 || start > l.size {
@@ -222,1491 +598,3437 @@ func (l *List[T]) slice(start, end int, mutable bool) *List[T] {
 	return other
 }
 
-// Iterator returns a new iterator for this list positioned at the first index.
-func (l *List[T]) Iterator() *ListIterator[T] {
-	itr := &ListIterator[T]{list: l}
-	itr.First()
-	return itr
+// Equal returns true if other has the same length as l and every element is
+// equal to the element at the same index in l as determined by the eq function.
+func (l *List[T]) Equal(other *List[T], eq func(a, b T) bool) bool {
+	if l.size != other.size {
+		return false
+	} else if l.root == other.root && l.origin == other.origin {
+		return true // shared structure
+	}
+
+	for itr0, itr1 := l.Iterator(), other.Iterator(); !itr0.Done(); {
+		_, v0 := itr0.Next()
+		_, v1 := itr1.Next()
+		if !eq(v0, v1) {
+			return false
+		}
+	}
+	return true
 }
 
-// ListBuilder represents an efficient builder for creating new Lists.
-type ListBuilder[T any] struct {
-	list *List[T] // current state
+// IndexOf returns the index of the first element equal to v as determined by
+// the equal function. Returns -1 if no element matches.
+func (l *List[T]) IndexOf(v T, equal func(a, b T) bool) int {
+	for itr := l.Iterator(); !itr.Done(); {
+		if i, elem := itr.Next(); equal(elem, v) {
+			return i
+		}
+	}
+	return -1
 }
 
-// NewListBuilder returns a new instance of ListBuilder.
-func NewListBuilder[T any]() *ListBuilder[T] {
-	return &ListBuilder[T]{list: NewList[T]()}
+// LastIndexOf returns the index of the last element equal to v as determined
+// by the equal function. Returns -1 if no element matches.
+func (l *List[T]) LastIndexOf(v T, equal func(a, b T) bool) int {
+	itr := l.Iterator()
+	for itr.Last(); !itr.Done(); {
+		if i, elem := itr.Prev(); equal(elem, v) {
+			return i
+		}
+	}
+	return -1
 }
 
-// List returns the current copy of the list.
-// The builder should not be used again after the list after this call.
-func (b *ListBuilder[T]) List() *List[T] {
-	assert(b.list != nil, "immutable.ListBuilder.List(): duplicate call to fetch list")
-	list := b.list
-	b.list = nil
-	return list
+// IndexFrom returns the index of the first element at or after start for which
+// pred returns true. Returns -1 if no element matches. A start index beyond
+// the end of the list returns -1 and a negative start index searches from the
+// beginning of the list.
+func (l *List[T]) IndexFrom(start int, pred func(T) bool) int {
+	if start >= l.size {
+		return -1
+	} else if start < 0 {
+		start = 0
+	}
+
+	itr := l.Iterator()
+	for itr.Seek(start); !itr.Done(); {
+		if i, v := itr.Next(); pred(v) {
+			return i
+		}
+	}
+	return -1
 }
 
-// Len returns the number of elements in the underlying list.
-func (b *ListBuilder[T]) Len() int {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	return b.list.Len()
+// Find returns the first element for which pred returns true. Iteration stops
+// at the first match. Returns false if no element matches.
+func (l *List[T]) Find(pred func(T) bool) (value T, ok bool) {
+	for itr := l.Iterator(); !itr.Done(); {
+		if _, v := itr.Next(); pred(v) {
+			return v, true
+		}
+	}
+	return value, false
 }
 
-// Get returns the value at the given index. Similar to slices, this method will
-// panic if index is below zero or is greater than or equal to the list size.
-func (b *ListBuilder[T]) Get(index int) T {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	return b.list.Get(index)
+// FindLast returns the last element for which pred returns true. The list is
+// searched from the end. Returns false if no element matches.
+func (l *List[T]) FindLast(pred func(T) bool) (value T, ok bool) {
+	itr := l.Iterator()
+	for itr.Last(); !itr.Done(); {
+		if _, v := itr.Prev(); pred(v) {
+			return v, true
+		}
+	}
+	return value, false
 }
 
-// Set updates the value at the given index. Similar to slices, this method will
-// panic if index is below zero or if the index is greater than or equal to the
-// list size.
-func (b *ListBuilder[T]) Set(index int, value T) {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	b.list = b.list.set(index, value, true)
+// Count returns the number of elements for which pred returns true.
+func (l *List[T]) Count(pred func(T) bool) int {
+	var n int
+	for itr := l.Iterator(); !itr.Done(); {
+		if _, v := itr.Next(); pred(v) {
+			n++
+		}
+	}
+	return n
 }
 
-// Append adds value to the end of the list.
-func (b *ListBuilder[T]) Append(value T) {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	b.list = b.list.append(value, true)
+// Any returns true if pred returns true for at least one element. Iteration
+// stops at the first match. Returns false for an empty list.
+func (l *List[T]) Any(pred func(T) bool) bool {
+	_, ok := l.Find(pred)
+	return ok
 }
 
-// Prepend adds value to the beginning of the list.
-func (b *ListBuilder[T]) Prepend(value T) {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	b.list = b.list.prepend(value, true)
+// Every returns true if pred returns true for all elements. Iteration stops
+// at the first element which does not match. Returns true for an empty list.
+func (l *List[T]) Every(pred func(T) bool) bool {
+	_, ok := l.Find(func(v T) bool { return !pred(v) })
+	return !ok
 }
 
-// Slice updates the list with a sublist of elements between start and end index.
-// See List.Slice() for more details.
-func (b *ListBuilder[T]) Slice(start, end int) {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	b.list = b.list.slice(start, end, true)
+// Contains returns true if the list contains an element equal to v as
+// determined by the equal function.
+func (l *List[T]) Contains(v T, equal func(a, b T) bool) bool {
+	return l.IndexOf(v, equal) != -1
 }
 
-// Iterator returns a new iterator for the underlying list.
-func (b *ListBuilder[T]) Iterator() *ListIterator[T] {
-	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
-	return b.list.Iterator()
+// ToSlice returns a new slice containing the elements of the list in order.
+func (l *List[T]) ToSlice() []T {
+	return l.values(0, l.size)
 }
 
-// Constants for bit shifts used for levels in the List trie.
-const (
-	listNodeBits = 5
-	listNodeSize = 1 << listNodeBits
-	listNodeMask = listNodeSize - 1
-)
+// Each invokes fn for each element in the list, in order.
+func (l *List[T]) Each(fn func(value T)) {
+	for itr := l.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		fn(v)
+	}
+}
 
-// listNode represents either a branch or leaf node in a List.
-type listNode[T any] interface {
-	depth() uint
-	get(index int) T
-	set(index int, v T, mutable bool) listNode[T]
+// EachUntil invokes fn for each element in the list, in order. Iteration
+// stops as soon as fn returns false.
+func (l *List[T]) EachUntil(fn func(value T) bool) {
+	for itr := l.Iterator(); !itr.Done(); {
+		if _, v := itr.Next(); !fn(v) {
+			return
+		}
+	}
+}
 
-	containsBefore(index int) bool
-	containsAfter(index int) bool
+// ForEachWindow invokes fn for each sliding window of size consecutive
+// elements in the list, in order. Iteration stops if fn returns false. The
+// window slice is reused between calls and is only valid during the callback.
+// This method will panic if size is less than one.
+func (l *List[T]) ForEachWindow(size int, fn func(window []T) bool) {
+	if size < 1 {
+		panic(fmt.Sprintf("immutable.List.ForEachWindow: invalid window size %d", size))
+	} else if size > l.size {
+		return
+	}
 
-	deleteBefore(index int, mutable bool) listNode[T]
-	deleteAfter(index int, mutable bool) listNode[T]
-}
+	window := make([]T, 0, size)
+	for itr := l.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		if len(window) == size {
+			copy(window, window[1:])
+			window[size-1] = v
+		} else {
+			window = append(window, v)
+		}
 
-// newListNode returns a leaf node for depth zero, otherwise returns a branch node.
-func newListNode[T any](depth uint) listNode[T] {
-	if depth == 0 {
-		return &listLeafNode[T]{}
+		if len(window) == size && !fn(window) {
+			return
+		}
 	}
-	return &listBranchNode[T]{d: depth}
 }
 
-// listBranchNode represents a branch of a List tree at a given depth.
-type listBranchNode[T any] struct {
-	d        uint // depth
-	children [listNodeSize]listNode[T]
+// MarshalJSON encodes the list as a JSON array.
+func (l *List[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.ToSlice())
 }
 
-// depth returns the depth of this branch node from the leaf.
-func (n *listBranchNode[T]) depth() uint { return n.d }
+// UnmarshalJSON decodes a JSON array into the list. A JSON null is decoded as
+// an empty list.
+func (l *List[T]) UnmarshalJSON(data []byte) error {
+	var a []T
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
 
-// get returns the child node at the segment of the index for this depth.
-func (n *listBranchNode[T]) get(index int) T {
-	idx := (index >> (n.d * listNodeBits)) & listNodeMask
-	return n.children[idx].get(index)
+	b := NewListBuilder[T]()
+	for _, v := range a {
+		b.Append(v)
+	}
+	*l = *b.List()
+	return nil
 }
 
-// set recursively updates the value at index for each lower depth from the node.
-func (n *listBranchNode[T]) set(index int, v T, mutable bool) listNode[T] {
-	idx := (index >> (n.d * listNodeBits)) & listNodeMask
+// trim removes any nodes after the last element of the list in-place.
+func (l *List[T]) trim() {
+	if l.size == 0 {
+		l.root, l.origin = &listLeafNode[T]{}, 0
+		return
+	}
+	l.root = l.root.deleteAfter(l.origin+l.size-1, true)
+}
 
-	// Find child for the given value in the branch. Create new if it doesn't exist.
-	child := n.children[idx]
-	if child == nil {
-		child = newListNode[T](n.depth() - 1)
-	}
-
-	// Return a copy of this branch with the new child.
-	var other *listBranchNode[T]
-	if mutable {
-		other = n
-	} else {
-		tmp := *n
-		other = &tmp
+// Reverse returns a new list with the elements in reverse order.
+func (l *List[T]) Reverse() *List[T] {
+	other := NewList[T]()
+	itr := l.Iterator()
+	itr.Last()
+	for !itr.Done() {
+		_, v := itr.Prev()
+		other.append(v, true)
 	}
-	other.children[idx] = child.set(index, v, mutable)
 	return other
 }
 
-// containsBefore returns true if non-nil values exists between [0,index).
-func (n *listBranchNode[T]) containsBefore(index int) bool {
-	idx := (index >> (n.d * listNodeBits)) & listNodeMask
+// Validate checks the internal consistency of the list and returns an error
+// describing the first problem found. It verifies that node depths decrease by
+// one at each level, that every index within the list is stored in an occupied
+// leaf slot, and that no slots outside the list are marked as occupied. This
+// is intended for testing and debugging.
+func (l *List[T]) Validate() error {
+	if l.root == nil {
+		return fmt.Errorf("immutable: list root is nil")
+	} else if l.origin < 0 || l.size < 0 {
+		return fmt.Errorf("immutable: invalid list origin/size: %d/%d", l.origin, l.size)
+	} else if max := 1 << ((l.root.depth() + 1) * listNodeBits); l.origin+l.size > max {
+		return fmt.Errorf("immutable: list range [%d,%d) exceeds root capacity %d", l.origin, l.origin+l.size, max)
+	}
+	return validateListNode(l.root, 0, l.origin, l.origin+l.size)
+}
+
+// validateListNode recursively validates node n which stores the indexes
+// starting at base. Indexes in the range [lo, hi) must be occupied.
+func validateListNode[T any](n listNode[T], base, lo, hi int) error {
+	switch n := n.(type) {
+	case *listBranchNode[T]:
+		span := 1 << (n.d * listNodeBits)
+		for i, child := range n.children {
+			start := base + i*span
+			if child == nil {
+				if start < hi && start+span > lo {
+					return fmt.Errorf("immutable: missing list node for indexes [%d,%d)", start, start+span)
+				}
+				continue
+			} else if child.depth() != n.d-1 {
+				return fmt.Errorf("immutable: list node at depth %d has child at depth %d", n.d, child.depth())
+			}
+			if err := validateListNode(child, start, lo, hi); err != nil {
+				return err
+			}
+		}
 
-	// Quickly check if any direct children exist before this segment of the index.
-	for i := 0; i < idx; i++ {
-		if n.children[i] != nil {
-			return true
+	case *listLeafNode[T]:
+		for i := 0; i < listNodeSize; i++ {
+			index, occupied := base+i, n.occupied&(1<<i) != 0
+			if inRange := index >= lo && index < hi; inRange && !occupied {
+				return fmt.Errorf("immutable: list index %d is not occupied", index-lo)
+			} else if !inRange && occupied {
+				return fmt.Errorf("immutable: list slot %d outside of list is occupied", index)
+			}
 		}
 	}
+	return nil
+}
 
-	// Recursively check for children directly at the given index at this segment.
-	if n.children[idx] != nil && n.children[idx].containsBefore(index) {
-		return true
+// Iterator returns a new iterator for this list positioned at the first index.
+func (l *List[T]) Iterator() *ListIterator[T] {
+	itr := &ListIterator[T]{list: l}
+	itr.First()
+	return itr
+}
+
+// CountDistinct returns the number of distinct elements in l. Elements are
+// compared using h. If h is nil, a default hasher is chosen based on the first
+// element in the list.
+//
+// The count is computed in a single pass using a transient set which is
+// discarded before returning.
+func CountDistinct[T comparable](l *List[T], h Hasher[T]) int {
+	return CountDistinctBy(l, h, func(v T) T { return v })
+}
+
+// CountDistinctBy returns the number of elements in l with distinct keys as
+// returned by fn. This can be used to count elements which are not comparable
+// themselves. Keys are compared using h. If h is nil, a default hasher is
+// chosen based on the first key.
+func CountDistinctBy[T any, K comparable](l *List[T], h Hasher[K], fn func(T) K) int {
+	b := NewMapBuilder[K, struct{}](h)
+	for itr := l.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		b.Set(fn(v), struct{}{})
 	}
-	return false
+	return b.Len()
 }
 
-// containsAfter returns true if non-nil values exists between (index,listNodeSize).
-func (n *listBranchNode[T]) containsAfter(index int) bool {
-	idx := (index >> (n.d * listNodeBits)) & listNodeMask
+// MultisetEqual returns true if a and b contain the same elements with the
+// same multiplicities, regardless of order. Elements are compared using h. If
+// h is nil, a default hasher is chosen based on the first element.
+func MultisetEqual[T comparable](a, b *List[T], h Hasher[T]) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
 
-	// Quickly check if any direct children exist after this segment of the index.
-	for i := idx + 1; i < len(n.children); i++ {
-		if n.children[i] != nil {
-			return true
+	fa, fb := listFrequencies(a, h), listFrequencies(b, h)
+	if fa.Len() != fb.Len() {
+		return false
+	}
+	for itr := fa.Iterator(); !itr.Done(); {
+		k, n, _ := itr.Next()
+		if other, _ := fb.Get(k); other != n {
+			return false
 		}
 	}
+	return true
+}
 
-	// Recursively check for children directly at the given index at this segment.
-	if n.children[idx] != nil && n.children[idx].containsAfter(index) {
-		return true
+// listFrequencies returns a map of each distinct element in l to the number of
+// times it occurs in the list.
+func listFrequencies[T any](l *List[T], h Hasher[T]) *Map[T, int] {
+	b := NewMapBuilder[T, int](h)
+	for itr := l.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		n, _ := b.Get(v)
+		b.Set(v, n+1)
 	}
-	return false
+	return b.Map()
 }
 
-// deleteBefore returns a new node with all elements before index removed.
-func (n *listBranchNode[T]) deleteBefore(index int, mutable bool) listNode[T] {
-	// Ignore if no nodes exist before the given index.
-	if !n.containsBefore(index) {
-		return n
+// CommonPrefixLen returns the length of the longest common prefix of a and b
+// as determined by the equal function.
+func CommonPrefixLen[T any](a, b *List[T], equal func(a, b T) bool) int {
+	var n int
+	for itrA, itrB := a.Iterator(), b.Iterator(); !itrA.Done() && !itrB.Done(); n++ {
+		_, va := itrA.Next()
+		_, vb := itrB.Next()
+		if !equal(va, vb) {
+			break
+		}
 	}
+	return n
+}
 
-	// Return a copy with any nodes prior to the index removed.
-	idx := (index >> (n.d * listNodeBits)) & listNodeMask
-
-	var other *listBranchNode[T]
-	if mutable {
-		other = n
-		for i := 0; i < idx; i++ {
-			n.children[i] = nil
+// MapListE returns a new list containing the result of fn applied to every
+// element in l. Iteration stops at the first error returned by fn and the
+// error is returned along with a nil list.
+func MapListE[T, U any](l *List[T], fn func(T) (U, error)) (*List[U], error) {
+	b := NewListBuilder[U]()
+	for itr := l.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		u, err := fn(v)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		other = &listBranchNode[T]{d: n.d}
-		copy(other.children[idx:][:], n.children[idx:][:])
+		b.Append(u)
 	}
+	return b.List(), nil
+}
 
-	if other.children[idx] != nil {
-		other.children[idx] = other.children[idx].deleteBefore(index, mutable)
+// FlatMap returns a new list containing the concatenation of the lists
+// returned by fn for every element in l.
+func FlatMap[T, U any](l *List[T], fn func(T) *List[U]) *List[U] {
+	b := NewListBuilder[U]()
+	for itr := l.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		b.AppendList(fn(v))
 	}
-	return other
+	return b.List()
 }
 
-// deleteBefore returns a new node with all elements before index removed.
-func (n *listBranchNode[T]) deleteAfter(index int, mutable bool) listNode[T] {
-	// Ignore if no nodes exist after the given index.
-	if !n.containsAfter(index) {
-		return n
+// DedupBy returns a new list with consecutive runs of elements with equal keys,
+// as returned by fn, collapsed to the first element of each run.
+func DedupBy[T any, K comparable](l *List[T], fn func(T) K) *List[T] {
+	b := NewListBuilder[T]()
+	var prev K
+	for itr := l.Iterator(); !itr.Done(); {
+		i, v := itr.Next()
+		if k := fn(v); i == 0 || k != prev {
+			b.Append(v)
+			prev = k
+		}
 	}
+	return b.List()
+}
 
-	// Return a copy with any nodes after the index removed.
-	idx := (index >> (n.d * listNodeBits)) & listNodeMask
+// ListBuilder represents an efficient builder for creating new Lists.
+type ListBuilder[T any] struct {
+	list     *List[T] // current state
+	shared   bool     // true if list nodes are shared with another list
+	prealloc bool     // true if list may contain preallocated empty nodes
 
-	var other *listBranchNode[T]
-	if mutable {
-		other = n
-		for i := idx + 1; i < len(n.children); i++ {
-			n.children[i] = nil
-		}
-	} else {
-		other = &listBranchNode[T]{d: n.d}
-		copy(other.children[:idx+1], n.children[:idx+1])
-	}
+	owned map[listNode[T]]struct{} // nodes copied since the list became shared
+}
 
-	if other.children[idx] != nil {
-		other.children[idx] = other.children[idx].deleteAfter(index, mutable)
-	}
-	return other
+// NewListBuilder returns a new instance of ListBuilder.
+func NewListBuilder[T any]() *ListBuilder[T] {
+	return &ListBuilder[T]{list: NewList[T]()}
 }
 
-// listLeafNode represents a leaf node in a List.
-type listLeafNode[T any] struct {
-	children [listNodeSize]T
-	// bitset with ones at occupied positions, position 0 is the LSB
-	occupied uint32
+// NewListBuilderWithCapacity returns a new instance of ListBuilder with tree
+// nodes preallocated for n elements. The leaf nodes are allocated in a single
+// block which reduces allocations when appending up to n elements. Exceeding
+// the capacity hint is allowed and grows the list as usual.
+func NewListBuilderWithCapacity[T any](n int) *ListBuilder[T] {
+	l := NewList[T]()
+	if n > 1 {
+		l.root = newListTree[T](n)
+	}
+	return &ListBuilder[T]{list: l, prealloc: true}
 }
 
-// depth always returns 0 for leaf nodes.
-func (n *listLeafNode[T]) depth() uint { return 0 }
+// newListTree returns an empty tree with enough nodes to append n elements
+// without growing the root.
+func newListTree[T any](n int) listNode[T] {
+	// Determine the root depth required so that appends never expand the root.
+	var depth uint
+	for 1<<(depth*listNodeBits) < n {
+		depth++
+	}
 
-// get returns the value at the given index.
-func (n *listLeafNode[T]) get(index int) T {
-	return n.children[index&listNodeMask]
-}
+	leaves := make([]listLeafNode[T], (n+listNodeSize-1)/listNodeSize)
+	nodes := make([]listNode[T], len(leaves))
+	for i := range leaves {
+		nodes[i] = &leaves[i]
+	}
 
-// set returns a copy of the node with the value at the index updated to v.
-func (n *listLeafNode[T]) set(index int, v T, mutable bool) listNode[T] {
-	idx := index & listNodeMask
-	var other *listLeafNode[T]
-	if mutable {
-		other = n
-	} else {
-		tmp := *n
-		other = &tmp
+	// Group each level of nodes into parent branches until the root is reached.
+	for d := uint(1); d <= depth; d++ {
+		parents := make([]listNode[T], (len(nodes)+listNodeSize-1)/listNodeSize)
+		for i := range parents {
+			branch := &listBranchNode[T]{d: d}
+			copy(branch.children[:], nodes[i*listNodeSize:])
+			parents[i] = branch
+		}
+		nodes = parents
 	}
-	other.children[idx] = v
-	other.occupied |= 1 << idx
-	return other
+	return nodes[0]
 }
 
-// containsBefore returns true if non-nil values exists between [0,index).
-func (n *listLeafNode[T]) containsBefore(index int) bool {
-	idx := index & listNodeMask
-	return bits.TrailingZeros32(n.occupied) < idx
+// NewListBuilderFrom returns a new instance of ListBuilder initialized with
+// the elements of l. The builder shares the nodes of l and copies them as they
+// are mutated. Only the nodes along the paths touched by a mutation are copied,
+// except for Sort() and Reverse() which rebuild the entire list. Mutating the
+// builder never affects l.
+func NewListBuilderFrom[T any](l *List[T]) *ListBuilder[T] {
+	return &ListBuilder[T]{list: l, shared: true}
 }
 
-// containsAfter returns true if non-nil values exists between (index,listNodeSize).
-func (n *listLeafNode[T]) containsAfter(index int) bool {
-	idx := index & listNodeMask
-	lastSetPos := 31 - bits.LeadingZeros32(n.occupied)
-	return lastSetPos > idx
+// Build returns the current state of the builder as an immutable list.
+// Unlike List(), the builder remains valid after the call and later changes
+// to the builder do not affect the returned list.
+//
+// The returned list shares its nodes with the builder so later mutations copy
+// nodes as described in NewListBuilderFrom(). Use List() instead if the
+// builder is no longer needed.
+func (b *ListBuilder[T]) Build() *List[T] {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+
+	// Remove unused preallocated nodes so they are not shared by the copy.
+	if b.prealloc {
+		b.list.trim()
+		b.prealloc = false
+	}
+	b.shared, b.owned = true, nil
+	return b.list.clone()
 }
 
-// deleteBefore returns a new node with all elements before index removed.
-func (n *listLeafNode[T]) deleteBefore(index int, mutable bool) listNode[T] {
-	if !n.containsBefore(index) {
-		return n
+// ownPath copies the nodes along the path to each index if they are shared
+// with another list so that the path can be safely mutated in-place. Copied
+// nodes are tracked so they are only copied once. Missing nodes at the end of
+// a path are ignored as they are created by the mutation itself. Indexes may
+// be one past either end of the list; an index before the start of the tree is
+// ignored as prepending to it grows a new root.
+func (b *ListBuilder[T]) ownPath(indexes ...int) {
+	if !b.shared {
+		return
+	} else if b.owned == nil {
+		b.list = b.list.clone()
+		b.owned = make(map[listNode[T]]struct{})
 	}
 
-	idx := index & listNodeMask
-	var other *listLeafNode[T]
-	if mutable {
-		other = n
-		var empty T
-		for i := 0; i < idx; i++ {
-			other.children[i] = empty
+	for _, index := range indexes {
+		if index += b.list.origin; index < 0 {
+			continue
+		}
+		b.list.root = ownListNode(b.list.root, b.owned)
+		for node := b.list.root; ; {
+			n, ok := node.(*listBranchNode[T])
+			if !ok {
+				break
+			}
+			idx := (index >> (n.d * listNodeBits)) & listNodeMask
+			if n.children[idx] == nil {
+				break
+			}
+			n.children[idx] = ownListNode(n.children[idx], b.owned)
+			node = n.children[idx]
 		}
-	} else {
-		other = &listLeafNode[T]{occupied: n.occupied}
-		copy(other.children[idx:][:], n.children[idx:][:])
 	}
-	// Set the first idx bits to 0.
-	other.occupied &= ^((1 << idx) - 1)
-	return other
 }
 
-// deleteAfter returns a new node with all elements after index removed.
-func (n *listLeafNode[T]) deleteAfter(index int, mutable bool) listNode[T] {
-	if !n.containsAfter(index) {
+// ownListNode returns n if it is in owned. Otherwise returns a copy of n which
+// is added to owned.
+func ownListNode[T any](n listNode[T], owned map[listNode[T]]struct{}) listNode[T] {
+	if _, ok := owned[n]; ok {
 		return n
 	}
 
-	idx := index & listNodeMask
-	var other *listLeafNode[T]
-	if mutable {
-		other = n
-		var empty T
-		for i := idx + 1; i < len(n.children); i++ {
-			other.children[i] = empty
-		}
-	} else {
-		other = &listLeafNode[T]{occupied: n.occupied}
-		copy(other.children[:idx+1][:], n.children[:idx+1][:])
+	var other listNode[T]
+	switch n := n.(type) {
+	case *listBranchNode[T]:
+		tmp := *n
+		other = &tmp
+	case *listLeafNode[T]:
+		tmp := *n
+		other = &tmp
 	}
-	// Set bits after idx to 0. idx < 31 because n.containsAfter(index) == true.
-	other.occupied &= (1 << (idx + 1)) - 1
+	owned[other] = struct{}{}
 	return other
 }
 
-// ListIterator represents an ordered iterator over a list.
-type ListIterator[T any] struct {
-	list  *List[T] // source list
-	index int      // current index position
+// List returns the current copy of the list.
+// The builder should not be used again after the list after this call.
+func (b *ListBuilder[T]) List() *List[T] {
+	assert(b.list != nil, "immutable.ListBuilder.List(): duplicate call to fetch list")
+	list := b.list
+	b.list = nil
 
-	stack [32]listIteratorElem[T] // search stack
-	depth int                     // stack depth
+	// Remove unused preallocated nodes so they are not shared by later copies.
+	if b.prealloc {
+		list.trim()
+	}
+	return list
 }
 
-// Done returns true if no more elements remain in the iterator.
-func (itr *ListIterator[T]) Done() bool {
-	return itr.index < 0 || itr.index >= itr.list.Len()
+// Valid returns true if the builder can still be used. A builder becomes
+// invalid once List() has been called.
+func (b *ListBuilder[T]) Valid() bool {
+	return b.list != nil
 }
 
-// First positions the iterator on the first index.
-// If source list is empty then no change is made.
-func (itr *ListIterator[T]) First() {
-	if itr.list.Len() != 0 {
-		itr.Seek(0)
-	}
+// Len returns the number of elements in the underlying list.
+func (b *ListBuilder[T]) Len() int {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Len()
 }
 
-// Last positions the iterator on the last index.
-// If source list is empty then no change is made.
-func (itr *ListIterator[T]) Last() {
-	if n := itr.list.Len(); n != 0 {
-		itr.Seek(n - 1)
-	}
+// IsEmpty returns true if the underlying list contains no elements.
+func (b *ListBuilder[T]) IsEmpty() bool {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.IsEmpty()
 }
 
-// Seek moves the iterator position to the given index in the list.
-// Similar to Go slices, this method will panic if index is below zero or if
-// the index is greater than or equal to the list size.
-func (itr *ListIterator[T]) Seek(index int) {
-	// Panic similar to Go slices.
-	if index < 0 || index >= itr.list.Len() {
-		panic(fmt.Sprintf("immutable.ListIterator.Seek: index %d out of bounds", index))
-	}
-	itr.index = index
+// Clear removes all elements from the underlying list. The builder can
+// continue to be used afterward.
+func (b *ListBuilder[T]) Clear() {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.list, b.shared, b.prealloc, b.owned = NewList[T](), false, false, nil
+}
 
-	// Reset to the bottom of the stack at seek to the correct position.
-	itr.stack[0] = listIteratorElem[T]{node: itr.list.root}
-	itr.depth = 0
-	itr.seek(index)
+// Get returns the value at the given index. Similar to slices, this method will
+// panic if index is below zero or is greater than or equal to the list size.
+func (b *ListBuilder[T]) Get(index int) T {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Get(index)
 }
 
-// Next returns the current index and its value & moves the iterator forward.
-// Returns an index of -1 if the there are no more elements to return.
-func (itr *ListIterator[T]) Next() (index int, value T) {
-	// Exit immediately if there are no elements remaining.
-	var empty T
-	if itr.Done() {
-		return -1, empty
-	}
+// First returns the first value in the list. Returns false if the list is empty.
+func (b *ListBuilder[T]) First() (value T, ok bool) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.First()
+}
 
-	// Retrieve current index & value.
-	elem := &itr.stack[itr.depth]
-	index, value = itr.index, elem.node.(*listLeafNode[T]).children[elem.index]
+// Last returns the last value in the list. Returns false if the list is empty.
+func (b *ListBuilder[T]) Last() (value T, ok bool) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Last()
+}
 
-	// Increase index. If index is at the end then return immediately.
-	itr.index++
-	if itr.Done() {
-		return index, value
-	}
+// Count returns the number of elements for which pred returns true.
+func (b *ListBuilder[T]) Count(pred func(T) bool) int {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Count(pred)
+}
 
-	// Move up stack until we find a node that has remaining position ahead.
-	for ; itr.depth > 0 && itr.stack[itr.depth].index >= listNodeSize-1; itr.depth-- {
-	}
+// Any returns true if pred returns true for at least one element.
+// See List.Any() for additional details.
+func (b *ListBuilder[T]) Any(pred func(T) bool) bool {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Any(pred)
+}
 
-	// Seek to correct position from current depth.
-	itr.seek(itr.index)
+// Every returns true if pred returns true for all elements.
+// See List.Every() for additional details.
+func (b *ListBuilder[T]) Every(pred func(T) bool) bool {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Every(pred)
+}
 
-	return index, value
+// Set updates the value at the given index. Similar to slices, this method will
+// panic if index is below zero or if the index is greater than or equal to the
+// list size.
+func (b *ListBuilder[T]) Set(index int, value T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(index)
+	b.list = b.list.set(index, value, true)
 }
 
-// Prev returns the current index and value and moves the iterator backward.
-// Returns an index of -1 if the there are no more elements to return.
-func (itr *ListIterator[T]) Prev() (index int, value T) {
-	// Exit immediately if there are no elements remaining.
-	var empty T
-	if itr.Done() {
-		return -1, empty
-	}
+// Swap exchanges the values at indexes i and j. Similar to slices, this method
+// will panic if either index is below zero or greater than or equal to the
+// list size.
+func (b *ListBuilder[T]) Swap(i, j int) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(i, j)
+	b.list = b.list.swap(i, j, true)
+}
 
-	// Retrieve current index & value.
-	elem := &itr.stack[itr.depth]
-	index, value = itr.index, elem.node.(*listLeafNode[T]).children[elem.index]
+// Sort sorts the elements in place using less. The sort is stable so equal
+// elements keep their original order. Elements are copied to a slice, sorted,
+// and bulk loaded into a new tree so sorting takes O(n log n) time.
+func (b *ListBuilder[T]) Sort(less func(x, y T) bool) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	values := b.list.ToSlice()
+	sort.SliceStable(values, func(i, j int) bool { return less(values[i], values[j]) })
+	b.list, b.shared, b.prealloc, b.owned = NewListFromSlice(values), false, false, nil
+}
 
-	// Decrease index. If index is past the beginning then return immediately.
-	itr.index--
-	if itr.Done() {
-		return index, value
+// SetOrAppend updates the value at the given index or appends the value if
+// index is equal to the list size. See List.SetOrAppend() for additional
+// details.
+func (b *ListBuilder[T]) SetOrAppend(index int, value T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	if index == b.list.size {
+		b.Append(value)
+		return
+	} else if index < 0 || index > b.list.size {
+		panic(fmt.Sprintf("immutable.ListBuilder.SetOrAppend: index %d out of bounds", index))
 	}
+	b.Set(index, value)
+}
 
-	// Move up stack until we find a node that has remaining position behind.
-	for ; itr.depth > 0 && itr.stack[itr.depth].index == 0; itr.depth-- {
-	}
+// Append adds value to the end of the list.
+func (b *ListBuilder[T]) Append(value T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(b.list.size)
+	b.list = b.list.append(value, true)
+}
 
-	// Seek to correct position from current depth.
-	itr.seek(itr.index)
+// AppendSlice adds values to the end of the list.
+func (b *ListBuilder[T]) AppendSlice(values ...T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(b.list.size)
+	b.list.appendSlice(values)
+}
 
-	return index, value
+// PrependSlice adds values to the beginning of the list. The values are kept
+// in the order given so the first value becomes the first element of the list.
+func (b *ListBuilder[T]) PrependSlice(values ...T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(-1)
+	b.list.prependSlice(values)
 }
 
-// seek positions the stack to the given index from the current depth.
-// Elements and indexes below the current depth are assumed to be correct.
-func (itr *ListIterator[T]) seek(index int) {
-	// Iterate over each level until we reach a leaf node.
-	for {
-		elem := &itr.stack[itr.depth]
-		elem.index = ((itr.list.origin + index) >> (elem.node.depth() * listNodeBits)) & listNodeMask
+// Prepend adds value to the beginning of the list.
+func (b *ListBuilder[T]) Prepend(value T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(-1)
+	b.list = b.list.prepend(value, true)
+}
 
-		switch node := elem.node.(type) {
-		case *listBranchNode[T]:
-			child := node.children[elem.index]
-			itr.stack[itr.depth+1] = listIteratorElem[T]{node: child}
-			itr.depth++
-		case *listLeafNode[T]:
-			return
-		}
+// InsertAt inserts value at the given index. See List.InsertAt() for more details.
+func (b *ListBuilder[T]) InsertAt(index int, value T) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(index-1, index)
+	b.list = b.list.insertAt(index, value, true)
+}
+
+// RemoveAt removes the element at the given index. See List.RemoveAt() for more details.
+func (b *ListBuilder[T]) RemoveAt(index int) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(index-1, index, index+1)
+	b.list = b.list.removeAt(index, true)
+}
+
+// AppendList adds the elements of other to the end of the list.
+func (b *ListBuilder[T]) AppendList(other *List[T]) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(b.list.size)
+	for itr := other.Iterator(); !itr.Done(); {
+		_, v := itr.Next()
+		b.list = b.list.append(v, true)
 	}
 }
 
-// listIteratorElem represents the node and it's child index within the stack.
-type listIteratorElem[T any] struct {
-	node  listNode[T]
-	index int
+// Slice updates the list with a sublist of elements between start and end index.
+// See List.Slice() for more details.
+func (b *ListBuilder[T]) Slice(start, end int) {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.ownPath(start, end-1)
+	b.list = b.list.slice(start, end, true)
 }
 
-// Size thresholds for each type of branch node.
-const (
-	maxArrayMapSize      = 8
-	maxBitmapIndexedSize = 16
-)
+// Reverse reverses the order of the elements in the list.
+func (b *ListBuilder[T]) Reverse() {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	b.list, b.shared, b.owned = b.list.Reverse(), false, nil
+}
 
-// Segment bit shifts within the map tree.
+// ToSlice returns a new slice containing the elements of the list in order.
+func (b *ListBuilder[T]) ToSlice() []T {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.ToSlice()
+}
+
+// Iterator returns a new iterator for the underlying list.
+func (b *ListBuilder[T]) Iterator() *ListIterator[T] {
+	assert(b.list != nil, "immutable.ListBuilder: builder invalid after List() invocation")
+	return b.list.Iterator()
+}
+
+// Constants for bit shifts used for levels in the List trie.
 const (
-	mapNodeBits = 5
-	mapNodeSize = 1 << mapNodeBits
-	mapNodeMask = mapNodeSize - 1
+	listNodeBits = 5
+	listNodeSize = 1 << listNodeBits
+	listNodeMask = listNodeSize - 1
 )
 
-// Map represents an immutable hash map implementation. The map uses a Hasher
-// to generate hashes and check for equality of key values.
-//
-// It is implemented as an Hash Array Mapped Trie.
-type Map[K, V any] struct {
-	size   int           // total number of key/value pairs
-	root   mapNode[K, V] // root node of trie
-	hasher Hasher[K]     // hasher implementation
-}
+// listNode represents either a branch or leaf node in a List.
+type listNode[T any] interface {
+	depth() uint
+	get(index int) T
+	set(index int, v T, mutable bool) listNode[T]
+	setPair(i int, vi T, j int, vj T, mutable bool) listNode[T]
 
-// NewMap returns a new instance of Map. If hasher is nil, a default hasher
-// implementation will automatically be chosen based on the first key added.
-// Default hasher implementations only exist for int, string, and byte slice types.
-func NewMap[K, V any](hasher Hasher[K]) *Map[K, V] {
-	return &Map[K, V]{
-		hasher: hasher,
-	}
+	containsBefore(index int) bool
+	containsAfter(index int) bool
+
+	deleteBefore(index int, mutable bool) listNode[T]
+	deleteAfter(index int, mutable bool) listNode[T]
 }
 
-// NewMapOf returns a new instance of Map, containing a map of provided entries.
-//
-// If hasher is nil, a default hasher implementation will automatically be chosen based on the first key added.
-// Default hasher implementations only exist for int, string, and byte slice types.
-func NewMapOf[K comparable, V any](hasher Hasher[K], entries map[K]V) *Map[K, V] {
-	m := &Map[K, V]{
-		hasher: hasher,
-	}
-	for k, v := range entries {
-		m.set(k, v, true)
+// newListNode returns a leaf node for depth zero, otherwise returns a branch node.
+func newListNode[T any](depth uint) listNode[T] {
+	if depth == 0 {
+		return &listLeafNode[T]{}
 	}
-	return m
+	return &listBranchNode[T]{d: depth}
 }
 
-// Len returns the number of elements in the map.
-func (m *Map[K, V]) Len() int {
-	return m.size
+// listBranchNode represents a branch of a List tree at a given depth.
+type listBranchNode[T any] struct {
+	d        uint // depth
+	children [listNodeSize]listNode[T]
 }
 
-// clone returns a shallow copy of m.
-func (m *Map[K, V]) clone() *Map[K, V] {
-	other := *m
-	return &other
-}
+// depth returns the depth of this branch node from the leaf.
+func (n *listBranchNode[T]) depth() uint { return n.d }
 
-// Get returns the value for a given key and a flag indicating whether the
-// key exists. This flag distinguishes a nil value set on a key versus a
-// non-existent key in the map.
-func (m *Map[K, V]) Get(key K) (value V, ok bool) {
-	var empty V
-	if m.root == nil {
-		return empty, false
-	}
-	keyHash := m.hasher.Hash(key)
-	return m.root.get(key, 0, keyHash, m.hasher)
+// get returns the child node at the segment of the index for this depth.
+func (n *listBranchNode[T]) get(index int) T {
+	idx := (index >> (n.d * listNodeBits)) & listNodeMask
+	return n.children[idx].get(index)
 }
 
-// Set returns a map with the key set to the new value. A nil value is allowed.
-//
-// This function will return a new map even if the updated value is the same as
-// the existing value because Map does not track value equality.
-func (m *Map[K, V]) Set(key K, value V) *Map[K, V] {
-	return m.set(key, value, false)
-}
+// set recursively updates the value at index for each lower depth from the node.
+func (n *listBranchNode[T]) set(index int, v T, mutable bool) listNode[T] {
+	idx := (index >> (n.d * listNodeBits)) & listNodeMask
 
-func (m *Map[K, V]) set(key K, value V, mutable bool) *Map[K, V] {
-	// Set a hasher on the first value if one does not already exist.
-	hasher := m.hasher
-	if hasher == nil {
-		hasher = NewHasher(key)
+	// Find child for the given value in the branch. Create new if it doesn't exist.
+	child := n.children[idx]
+	if child == nil {
+		child = newListNode[T](n.depth() - 1)
 	}
 
-	// Generate copy if necessary.
-	other := m
-	if !mutable {
-		other = m.clone()
+	// Return a copy of this branch with the new child.
+	var other *listBranchNode[T]
+	if mutable {
+		other = n
+	} else {
+		tmp := *n
+		other = &tmp
 	}
-	other.hasher = hasher
+	other.children[idx] = child.set(index, v, mutable)
+	return other
+}
 
-	// If the map is empty, initialize with a simple array node.
-	if m.root == nil {
-		other.size = 1
-		other.root = &mapArrayNode[K, V]{entries: []mapEntry[K, V]{{key: key, value: value}}}
-		return other
+// setPair updates the values at two indexes. Nodes on the path shared by both
+// indexes are only copied once. Both indexes must exist in the node.
+func (n *listBranchNode[T]) setPair(i int, vi T, j int, vj T, mutable bool) listNode[T] {
+	idxI := (i >> (n.d * listNodeBits)) & listNodeMask
+	idxJ := (j >> (n.d * listNodeBits)) & listNodeMask
+
+	var other *listBranchNode[T]
+	if mutable {
+		other = n
+	} else {
+		tmp := *n
+		other = &tmp
 	}
 
-	// Otherwise copy the map and delegate insertion to the root.
-	// Resized will return true if the key does not currently exist.
-	var resized bool
-	other.root = m.root.set(key, value, 0, hasher.Hash(key), hasher, mutable, &resized)
-	if resized {
-		other.size++
+	// Continue down a single path until the indexes are in different children.
+	if idxI == idxJ {
+		other.children[idxI] = n.children[idxI].setPair(i, vi, j, vj, mutable)
+		return other
 	}
+	other.children[idxI] = n.children[idxI].set(i, vi, mutable)
+	other.children[idxJ] = n.children[idxJ].set(j, vj, mutable)
 	return other
 }
 
-// Delete returns a map with the given key removed.
-// Removing a non-existent key will cause this method to return the same map.
-func (m *Map[K, V]) Delete(key K) *Map[K, V] {
-	return m.delete(key, false)
-}
+// leaf returns the leaf node containing index. Missing nodes along the path
+// are created. The node must be mutable.
+func (n *listBranchNode[T]) leaf(index int) *listLeafNode[T] {
+	for {
+		idx := (index >> (n.d * listNodeBits)) & listNodeMask
+		if n.children[idx] == nil {
+			n.children[idx] = newListNode[T](n.d - 1)
+		}
 
-func (m *Map[K, V]) delete(key K, mutable bool) *Map[K, V] {
-	// Return original map if no keys exist.
-	if m.root == nil {
-		return m
+		switch child := n.children[idx].(type) {
+		case *listBranchNode[T]:
+			n = child
+		case *listLeafNode[T]:
+			return child
+		}
 	}
+}
 
-	// If the delete did not change the node then return the original map.
-	var resized bool
-	newRoot := m.root.delete(key, 0, m.hasher.Hash(key), m.hasher, mutable, &resized)
-	if !resized {
-		return m
-	}
+// containsBefore returns true if non-nil values exists between [0,index).
+func (n *listBranchNode[T]) containsBefore(index int) bool {
+	idx := (index >> (n.d * listNodeBits)) & listNodeMask
 
-	// Generate copy if necessary.
-	other := m
-	if !mutable {
-		other = m.clone()
+	// Quickly check if any direct children exist before this segment of the index.
+	for i := 0; i < idx; i++ {
+		if n.children[i] != nil {
+			return true
+		}
 	}
 
-	// Return copy of map with new root and decreased size.
-	other.size = m.size - 1
-	other.root = newRoot
-	return other
+	// Recursively check for children directly at the given index at this segment.
+	if n.children[idx] != nil && n.children[idx].containsBefore(index) {
+		return true
+	}
+	return false
 }
 
-// Iterator returns a new iterator for the map.
-func (m *Map[K, V]) Iterator() *MapIterator[K, V] {
-	itr := &MapIterator[K, V]{m: m}
-	itr.First()
-	return itr
-}
+// containsAfter returns true if non-nil values exists between (index,listNodeSize).
+func (n *listBranchNode[T]) containsAfter(index int) bool {
+	idx := (index >> (n.d * listNodeBits)) & listNodeMask
 
-// MapBuilder represents an efficient builder for creating Maps.
-type MapBuilder[K, V any] struct {
-	m *Map[K, V] // current state
-}
+	// Quickly check if any direct children exist after this segment of the index.
+	for i := idx + 1; i < len(n.children); i++ {
+		if n.children[i] != nil {
+			return true
+		}
+	}
 
-// NewMapBuilder returns a new instance of MapBuilder.
-func NewMapBuilder[K, V any](hasher Hasher[K]) *MapBuilder[K, V] {
-	return &MapBuilder[K, V]{m: NewMap[K, V](hasher)}
+	// Recursively check for children directly at the given index at this segment.
+	if n.children[idx] != nil && n.children[idx].containsAfter(index) {
+		return true
+	}
+	return false
 }
 
-// Map returns the underlying map. Only call once.
-// Builder is invalid after call. Will panic on second invocation.
-func (b *MapBuilder[K, V]) Map() *Map[K, V] {
-	assert(b.m != nil, "immutable.SortedMapBuilder.Map(): duplicate call to fetch map")
-	m := b.m
-	b.m = nil
-	return m
-}
+// deleteBefore returns a new node with all elements before index removed.
+func (n *listBranchNode[T]) deleteBefore(index int, mutable bool) listNode[T] {
+	// Ignore if no nodes exist before the given index.
+	if !n.containsBefore(index) {
+		return n
+	}
 
-// Len returns the number of elements in the underlying map.
-func (b *MapBuilder[K, V]) Len() int {
-	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
-	return b.m.Len()
-}
+	// Return a copy with any nodes prior to the index removed.
+	idx := (index >> (n.d * listNodeBits)) & listNodeMask
 
-// Get returns the value for the given key.
-func (b *MapBuilder[K, V]) Get(key K) (value V, ok bool) {
-	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
-	return b.m.Get(key)
-}
+	var other *listBranchNode[T]
+	if mutable {
+		other = n
+		for i := 0; i < idx; i++ {
+			n.children[i] = nil
+		}
+	} else {
+		other = &listBranchNode[T]{d: n.d}
+		copy(other.children[idx:][:], n.children[idx:][:])
+	}
 
-// Set sets the value of the given key. See Map.Set() for additional details.
-func (b *MapBuilder[K, V]) Set(key K, value V) {
-	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
-	b.m = b.m.set(key, value, true)
+	if other.children[idx] != nil {
+		other.children[idx] = other.children[idx].deleteBefore(index, mutable)
+	}
+	return other
 }
 
-// Delete removes the given key. See Map.Delete() for additional details.
-func (b *MapBuilder[K, V]) Delete(key K) {
-	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
-	b.m = b.m.delete(key, true)
-}
+// deleteBefore returns a new node with all elements before index removed.
+func (n *listBranchNode[T]) deleteAfter(index int, mutable bool) listNode[T] {
+	// Ignore if no nodes exist after the given index.
+	if !n.containsAfter(index) {
+		return n
+	}
 
-// Iterator returns a new iterator for the underlying map.
-func (b *MapBuilder[K, V]) Iterator() *MapIterator[K, V] {
-	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
-	return b.m.Iterator()
-}
+	// Return a copy with any nodes after the index removed.
+	idx := (index >> (n.d * listNodeBits)) & listNodeMask
 
-// mapNode represents any node in the map tree.
-type mapNode[K, V any] interface {
-	get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool)
-	set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V]
-	delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V]
-}
+	var other *listBranchNode[T]
+	if mutable {
+		other = n
+		for i := idx + 1; i < len(n.children); i++ {
+			n.children[i] = nil
+		}
+	} else {
+		other = &listBranchNode[T]{d: n.d}
+		copy(other.children[:idx+1], n.children[:idx+1])
+	}
 
-var _ mapNode[string, any] = (*mapArrayNode[string, any])(nil)
-var _ mapNode[string, any] = (*mapBitmapIndexedNode[string, any])(nil)
-var _ mapNode[string, any] = (*mapHashArrayNode[string, any])(nil)
-var _ mapNode[string, any] = (*mapValueNode[string, any])(nil)
-var _ mapNode[string, any] = (*mapHashCollisionNode[string, any])(nil)
+	if other.children[idx] != nil {
+		other.children[idx] = other.children[idx].deleteAfter(index, mutable)
+	}
+	return other
+}
 
-// mapLeafNode represents a node that stores a single key hash at the leaf of the map tree.
-type mapLeafNode[K, V any] interface {
-	mapNode[K, V]
-	keyHashValue() uint32
+// listLeafNode represents a leaf node in a List.
+type listLeafNode[T any] struct {
+	children [listNodeSize]T
+	// bitset with ones at occupied positions, position 0 is the LSB
+	occupied uint32
 }
 
-var _ mapLeafNode[string, any] = (*mapValueNode[string, any])(nil)
-var _ mapLeafNode[string, any] = (*mapHashCollisionNode[string, any])(nil)
+// depth always returns 0 for leaf nodes.
+func (n *listLeafNode[T]) depth() uint { return 0 }
 
-// mapArrayNode is a map node that stores key/value pairs in a slice.
-// Entries are stored in insertion order. An array node expands into a bitmap
-// indexed node once a given threshold size is crossed.
-type mapArrayNode[K, V any] struct {
-	entries []mapEntry[K, V]
+// get returns the value at the given index.
+func (n *listLeafNode[T]) get(index int) T {
+	return n.children[index&listNodeMask]
 }
 
-// indexOf returns the entry index of the given key. Returns -1 if key not found.
-func (n *mapArrayNode[K, V]) indexOf(key K, h Hasher[K]) int {
-	for i := range n.entries {
-		if h.Equal(n.entries[i].key, key) {
-			return i
-		}
+// set returns a copy of the node with the value at the index updated to v.
+func (n *listLeafNode[T]) set(index int, v T, mutable bool) listNode[T] {
+	idx := index & listNodeMask
+	var other *listLeafNode[T]
+	if mutable {
+		other = n
+	} else {
+		tmp := *n
+		other = &tmp
 	}
-	return -1
+	other.children[idx] = v
+	other.occupied |= 1 << idx
+	return other
 }
 
-// get returns the value for the given key.
-func (n *mapArrayNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
-	i := n.indexOf(key, h)
-	if i == -1 {
-		return value, false
-	}
-	return n.entries[i].value, true
+// setPair returns a copy of the node with the values at both indexes updated.
+func (n *listLeafNode[T]) setPair(i int, vi T, j int, vj T, mutable bool) listNode[T] {
+	other := n.set(i, vi, mutable).(*listLeafNode[T])
+	other.children[j&listNodeMask] = vj
+	other.occupied |= 1 << (j & listNodeMask)
+	return other
 }
 
-// set inserts or updates the value for a given key. If the key is inserted and
-// the new size crosses the max size threshold, a bitmap indexed node is returned.
-func (n *mapArrayNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	idx := n.indexOf(key, h)
+// containsBefore returns true if non-nil values exists between [0,index).
+func (n *listLeafNode[T]) containsBefore(index int) bool {
+	idx := index & listNodeMask
+	return bits.TrailingZeros32(n.occupied) < idx
+}
 
-	// Mark as resized if the key doesn't exist.
-	if idx == -1 {
-		*resized = true
-	}
+// containsAfter returns true if non-nil values exists between (index,listNodeSize).
+func (n *listLeafNode[T]) containsAfter(index int) bool {
+	idx := index & listNodeMask
+	lastSetPos := 31 - bits.LeadingZeros32(n.occupied)
+	return lastSetPos > idx
+}
 
-	// If we are adding and it crosses the max size threshold, expand the node.
-	// We do this by continually setting the entries to a value node and expanding.
-	if idx == -1 && len(n.entries) >= maxArrayMapSize {
-		var node mapNode[K, V] = newMapValueNode(h.Hash(key), key, value)
-		for _, entry := range n.entries {
-			node = node.set(entry.key, entry.value, 0, h.Hash(entry.key), h, false, resized)
-		}
-		return node
+// deleteBefore returns a new node with all elements before index removed.
+func (n *listLeafNode[T]) deleteBefore(index int, mutable bool) listNode[T] {
+	if !n.containsBefore(index) {
+		return n
 	}
 
-	// Update in-place if mutable.
+	idx := index & listNodeMask
+	var other *listLeafNode[T]
 	if mutable {
-		if idx != -1 {
-			n.entries[idx] = mapEntry[K, V]{key, value}
-		} else {
-			n.entries = append(n.entries, mapEntry[K, V]{key, value})
+		other = n
+		var empty T
+		for i := 0; i < idx; i++ {
+			other.children[i] = empty
 		}
-		return n
-	}
-
-	// Update existing entry if a match is found.
-	// Otherwise append to the end of the element list if it doesn't exist.
-	var other mapArrayNode[K, V]
-	if idx != -1 {
-		other.entries = make([]mapEntry[K, V], len(n.entries))
-		copy(other.entries, n.entries)
-		other.entries[idx] = mapEntry[K, V]{key, value}
 	} else {
-		other.entries = make([]mapEntry[K, V], len(n.entries)+1)
-		copy(other.entries, n.entries)
-		other.entries[len(other.entries)-1] = mapEntry[K, V]{key, value}
+		other = &listLeafNode[T]{occupied: n.occupied}
+		copy(other.children[idx:][:], n.children[idx:][:])
 	}
-	return &other
+	// Set the first idx bits to 0.
+	other.occupied &= ^((1 << idx) - 1)
+	return other
 }
 
-// delete removes the given key from the node. Returns the same node if key does
-// not exist. Returns a nil node when removing the last entry.
-func (n *mapArrayNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	idx := n.indexOf(key, h)
-
-	// Return original node if key does not exist.
-	if idx == -1 {
+// deleteAfter returns a new node with all elements after index removed.
+func (n *listLeafNode[T]) deleteAfter(index int, mutable bool) listNode[T] {
+	if !n.containsAfter(index) {
 		return n
 	}
-	*resized = true
 
-	// Return nil if this node will contain no nodes.
-	if len(n.entries) == 1 {
-		return nil
-	}
-
-	// Update in-place, if mutable.
+	idx := index & listNodeMask
+	var other *listLeafNode[T]
 	if mutable {
-		copy(n.entries[idx:], n.entries[idx+1:])
-		n.entries[len(n.entries)-1] = mapEntry[K, V]{}
-		n.entries = n.entries[:len(n.entries)-1]
-		return n
+		other = n
+		var empty T
+		for i := idx + 1; i < len(n.children); i++ {
+			other.children[i] = empty
+		}
+	} else {
+		other = &listLeafNode[T]{occupied: n.occupied}
+		copy(other.children[:idx+1][:], n.children[:idx+1][:])
 	}
-
-	// Otherwise create a copy with the given entry removed.
-	other := &mapArrayNode[K, V]{entries: make([]mapEntry[K, V], len(n.entries)-1)}
-	copy(other.entries[:idx], n.entries[:idx])
-	copy(other.entries[idx:], n.entries[idx+1:])
+	// Set bits after idx to 0. idx < 31 because n.containsAfter(index) == true.
+	other.occupied &= (1 << (idx + 1)) - 1
 	return other
 }
 
-// mapBitmapIndexedNode represents a map branch node with a variable number of
-// node slots and indexed using a bitmap. Indexes for the node slots are
-// calculated by counting the number of set bits before the target bit using popcount.
-type mapBitmapIndexedNode[K, V any] struct {
-	bitmap uint32
-	nodes  []mapNode[K, V]
-}
+// ListIterator represents an ordered iterator over a list.
+type ListIterator[T any] struct {
+	list  *List[T] // source list
+	index int      // current index position
 
-// get returns the value for the given key.
-func (n *mapBitmapIndexedNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
-	bit := uint32(1) << ((keyHash >> shift) & mapNodeMask)
-	if (n.bitmap & bit) == 0 {
-		return value, false
-	}
-	child := n.nodes[bits.OnesCount32(n.bitmap&(bit-1))]
-	return child.get(key, shift+mapNodeBits, keyHash, h)
+	stack [32]listIteratorElem[T] // search stack
+	depth int                     // stack depth
 }
 
-// set inserts or updates the value for the given key. If a new key is inserted
-// and the size crosses the max size threshold then a hash array node is returned.
-func (n *mapBitmapIndexedNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	// Extract the index for the bit segment of the key hash.
-	keyHashFrag := (keyHash >> shift) & mapNodeMask
-
-	// Determine the bit based on the hash index.
-	bit := uint32(1) << keyHashFrag
-	exists := (n.bitmap & bit) != 0
+// Done returns true if no more elements remain in the iterator.
+func (itr *ListIterator[T]) Done() bool {
+	return itr.index < 0 || itr.index >= itr.list.Len()
+}
 
-	// Mark as resized if the key doesn't exist.
-	if !exists {
-		*resized = true
+// Remaining returns the number of elements that will be returned by calls to
+// Next() before the iterator is done.
+func (itr *ListIterator[T]) Remaining() int {
+	if itr.Done() {
+		return 0
 	}
+	return itr.list.Len() - itr.index
+}
 
-	// Find index of node based on popcount of bits before it.
-	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+// First positions the iterator on the first index.
+// If source list is empty then no change is made.
+func (itr *ListIterator[T]) First() {
+	if itr.list.Len() != 0 {
+		itr.Seek(0)
+	}
+}
 
-	// If the node already exists, delegate set operation to it.
-	// If the node doesn't exist then create a simple value leaf node.
-	var newNode mapNode[K, V]
-	if exists {
-		newNode = n.nodes[idx].set(key, value, shift+mapNodeBits, keyHash, h, mutable, resized)
-	} else {
-		newNode = newMapValueNode(keyHash, key, value)
+// Last positions the iterator on the last index.
+// If source list is empty then no change is made.
+func (itr *ListIterator[T]) Last() {
+	if n := itr.list.Len(); n != 0 {
+		itr.Seek(n - 1)
 	}
+}
 
-	// Convert to a hash-array node once we exceed the max bitmap size.
-	// Copy each node based on their bit position within the bitmap.
-	if !exists && len(n.nodes) > maxBitmapIndexedSize {
-		var other mapHashArrayNode[K, V]
-		for i := uint(0); i < uint(len(other.nodes)); i++ {
-			if n.bitmap&(uint32(1)<<i) != 0 {
-				other.nodes[i] = n.nodes[other.count]
-				other.count++
-			}
-		}
-		other.nodes[keyHashFrag] = newNode
-		other.count++
-		return &other
+// Seek moves the iterator position to the given index in the list.
+// Similar to Go slices, this method will panic if index is below zero or if
+// the index is greater than or equal to the list size.
+func (itr *ListIterator[T]) Seek(index int) {
+	// Panic similar to Go slices.
+	if index < 0 || index >= itr.list.Len() {
+		panic(fmt.Sprintf("immutable.ListIterator.Seek: index %d out of bounds", index))
 	}
+	itr.index = index
 
-	// Update in-place if mutable.
-	if mutable {
-		if exists {
-			n.nodes[idx] = newNode
-		} else {
-			n.bitmap |= bit
-			n.nodes = append(n.nodes, nil)
-			copy(n.nodes[idx+1:], n.nodes[idx:])
-			n.nodes[idx] = newNode
+	// Reset to the bottom of the stack at seek to the correct position.
+	itr.stack[0] = listIteratorElem[T]{node: itr.list.root}
+	itr.depth = 0
+	itr.seek(index)
+}
+
+// SeekFunc moves the iterator forward from its current position to the first
+// element for which pred returns true. The matching element is returned by
+// the next call to Next(). If no elements match then the iterator is marked as
+// done.
+func (itr *ListIterator[T]) SeekFunc(pred func(T) bool) {
+	for !itr.Done() {
+		elem := &itr.stack[itr.depth]
+		if pred(elem.node.(*listLeafNode[T]).children[elem.index]) {
+			return
 		}
-		return n
+		itr.Next()
 	}
+}
 
-	// If node exists at given slot then overwrite it with new node.
-	// Otherwise expand the node list and insert new node into appropriate position.
-	other := &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap | bit}
-	if exists {
-		other.nodes = make([]mapNode[K, V], len(n.nodes))
-		copy(other.nodes, n.nodes)
-		other.nodes[idx] = newNode
-	} else {
-		other.nodes = make([]mapNode[K, V], len(n.nodes)+1)
-		copy(other.nodes, n.nodes[:idx])
-		other.nodes[idx] = newNode
-		copy(other.nodes[idx+1:], n.nodes[idx:])
+// Next returns the current index and its value & moves the iterator forward.
+// Returns an index of -1 if the there are no more elements to return.
+func (itr *ListIterator[T]) Next() (index int, value T) {
+	// Exit immediately if there are no elements remaining.
+	var empty T
+	if itr.Done() {
+		return -1, empty
 	}
-	return other
-}
 
-// delete removes the key from the tree. If the key does not exist then the
-// original node is returned. If removing the last child node then a nil is
-// returned. Note that shrinking the node will not convert it to an array node.
-func (n *mapBitmapIndexedNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	bit := uint32(1) << ((keyHash >> shift) & mapNodeMask)
+	// Retrieve current index & value.
+	elem := &itr.stack[itr.depth]
+	index, value = itr.index, elem.node.(*listLeafNode[T]).children[elem.index]
 
-	// Return original node if key does not exist.
-	if (n.bitmap & bit) == 0 {
-		return n
+	// Increase index. If index is at the end then return immediately.
+	itr.index++
+	if itr.Done() {
+		return index, value
 	}
 
-	// Find index of node based on popcount of bits before it.
-	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+	// Move up stack until we find a node that has remaining position ahead.
+	for ; itr.depth > 0 && itr.stack[itr.depth].index >= listNodeSize-1; itr.depth-- {
+	}
 
-	// Delegate delete to child node.
-	child := n.nodes[idx]
-	newChild := child.delete(key, shift+mapNodeBits, keyHash, h, mutable, resized)
+	// Seek to correct position from current depth.
+	itr.seek(itr.index)
 
-	// Return original node if key doesn't exist in child.
-	if !*resized {
-		return n
-	}
+	return index, value
+}
 
-	// Remove if returned child has been deleted.
-	if newChild == nil {
-		// If we won't have any children then return nil.
-		if len(n.nodes) == 1 {
-			return nil
-		}
+// Prev returns the current index and value and moves the iterator backward.
+// Returns an index of -1 if the there are no more elements to return.
+func (itr *ListIterator[T]) Prev() (index int, value T) {
+	// Exit immediately if there are no elements remaining.
+	var empty T
+	if itr.Done() {
+		return -1, empty
+	}
 
-		// Update in-place if mutable.
-		if mutable {
-			n.bitmap ^= bit
-			copy(n.nodes[idx:], n.nodes[idx+1:])
-			n.nodes[len(n.nodes)-1] = nil
-			n.nodes = n.nodes[:len(n.nodes)-1]
-			return n
-		}
+	// Retrieve current index & value.
+	elem := &itr.stack[itr.depth]
+	index, value = itr.index, elem.node.(*listLeafNode[T]).children[elem.index]
 
-		// Return copy with bit removed from bitmap and node removed from node list.
-		other := &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap ^ bit, nodes: make([]mapNode[K, V], len(n.nodes)-1)}
-		copy(other.nodes[:idx], n.nodes[:idx])
-		copy(other.nodes[idx:], n.nodes[idx+1:])
-		return other
+	// Decrease index. If index is past the beginning then return immediately.
+	itr.index--
+	if itr.Done() {
+		return index, value
 	}
 
-	// Generate copy, if necessary.
-	other := n
-	if !mutable {
-		other = &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap, nodes: make([]mapNode[K, V], len(n.nodes))}
-		copy(other.nodes, n.nodes)
+	// Move up stack until we find a node that has remaining position behind.
+	for ; itr.depth > 0 && itr.stack[itr.depth].index == 0; itr.depth-- {
 	}
 
-	// Update child.
-	other.nodes[idx] = newChild
-	return other
-}
+	// Seek to correct position from current depth.
+	itr.seek(itr.index)
 
-// mapHashArrayNode is a map branch node that stores nodes in a fixed length
-// array. Child nodes are indexed by their index bit segment for the current depth.
-type mapHashArrayNode[K, V any] struct {
-	count uint                       // number of set nodes
-	nodes [mapNodeSize]mapNode[K, V] // child node slots, may contain empties
+	return index, value
 }
 
-// clone returns a shallow copy of n.
-func (n *mapHashArrayNode[K, V]) clone() *mapHashArrayNode[K, V] {
-	other := *n
-	return &other
+// seek positions the stack to the given index from the current depth.
+// Elements and indexes below the current depth are assumed to be correct.
+func (itr *ListIterator[T]) seek(index int) {
+	// Iterate over each level until we reach a leaf node.
+	for {
+		elem := &itr.stack[itr.depth]
+		elem.index = ((itr.list.origin + index) >> (elem.node.depth() * listNodeBits)) & listNodeMask
+
+		switch node := elem.node.(type) {
+		case *listBranchNode[T]:
+			child := node.children[elem.index]
+			itr.stack[itr.depth+1] = listIteratorElem[T]{node: child}
+			itr.depth++
+		case *listLeafNode[T]:
+			return
+		}
+	}
 }
 
-// get returns the value for the given key.
-func (n *mapHashArrayNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
-	node := n.nodes[(keyHash>>shift)&mapNodeMask]
-	if node == nil {
-		return value, false
+// listIteratorElem represents the node and it's child index within the stack.
+type listIteratorElem[T any] struct {
+	node  listNode[T]
+	index int
+}
+
+// Size thresholds for each type of branch node.
+const (
+	maxArrayMapSize      = 8
+	maxBitmapIndexedSize = 16
+)
+
+// Segment bit shifts within the map tree.
+const (
+	mapNodeBits = 5
+	mapNodeSize = 1 << mapNodeBits
+	mapNodeMask = mapNodeSize - 1
+)
+
+// Map represents an immutable hash map implementation. The map uses a Hasher
+// to generate hashes and check for equality of key values.
+//
+// It is implemented as an Hash Array Mapped Trie.
+type Map[K, V any] struct {
+	size   int           // total number of key/value pairs
+	root   mapNode[K, V] // root node of trie
+	hasher Hasher[K]     // hasher implementation
+}
+
+// NewMap returns a new instance of Map. If hasher is nil, a default hasher
+// implementation is chosen based on the key type. Default hasher
+// implementations only exist for int, string, and byte slice types.
+//
+// The default hasher is resolved when the map is created so that empty maps
+// shared between goroutines never change. For interface key types, it is
+// instead chosen based on the first key added.
+func NewMap[K, V any](hasher Hasher[K]) *Map[K, V] {
+	if hasher == nil {
+		var key K
+		if hasDefaultHasher(key) {
+			hasher = NewHasher(key)
+		}
+	}
+	return &Map[K, V]{
+		hasher: hasher,
 	}
-	return node.get(key, shift+mapNodeBits, keyHash, h)
 }
 
-// set returns a node with the value set for the given key.
-func (n *mapHashArrayNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	idx := (keyHash >> shift) & mapNodeMask
-	node := n.nodes[idx]
+// NewMapE returns a new instance of Map. If hasher is nil, a default hasher is
+// chosen based on the key type. Unlike NewMap(), an UnsupportedKeyTypeError is
+// returned if no default hasher exists for the key type instead of panicking
+// on the first insert.
+func NewMapE[K, V any](hasher Hasher[K]) (*Map[K, V], error) {
+	if hasher == nil {
+		var key K
+		if !hasDefaultHasher(key) {
+			return nil, &UnsupportedKeyTypeError{Type: keyType[K](), Op: "hasher"}
+		}
+		hasher = NewHasher(key)
+	}
+	return NewMap[K, V](hasher), nil
+}
 
-	// If node at index doesn't exist, create a simple value leaf node.
-	// Otherwise delegate set to child node.
-	var newNode mapNode[K, V]
-	if node == nil {
-		*resized = true
-		newNode = newMapValueNode(keyHash, key, value)
-	} else {
-		newNode = node.set(key, value, shift+mapNodeBits, keyHash, h, mutable, resized)
+// NewMapOf returns a new instance of Map containing the provided entries.
+// Entries are inserted in-place into a single new map so no intermediate maps
+// are allocated.
+//
+// If hasher is nil, a default hasher is chosen as described by NewMap().
+func NewMapOf[K comparable, V any](hasher Hasher[K], entries map[K]V) *Map[K, V] {
+	m := NewMap[K, V](hasher)
+	for k, v := range entries {
+		m.set(k, v, true)
+	}
+	return m
+}
+
+// Len returns the number of elements in the map.
+func (m *Map[K, V]) Len() int {
+	return m.size
+}
+
+// IsEmpty returns true if the map contains no elements.
+func (m *Map[K, V]) IsEmpty() bool {
+	return m.size == 0
+}
+
+// clone returns a shallow copy of m.
+func (m *Map[K, V]) clone() *Map[K, V] {
+	other := *m
+	return &other
+}
+
+// Clone returns an independent copy of the map in O(1) time.
+// See List.Clone() for more details.
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	return m.clone()
+}
+
+// WithHasher returns a new map containing the same key/value pairs as m but
+// using hasher to hash keys. Since the layout of the map depends on the key
+// hashes, every pair is reinserted which takes O(n) time. If hasher is nil
+// then a default hasher is used.
+func (m *Map[K, V]) WithHasher(hasher Hasher[K]) *Map[K, V] {
+	other := NewMap[K, V](hasher)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		other.set(k, v, true)
 	}
+	return other
+}
 
-	// Generate copy, if necessary.
-	other := n
+// Get returns the value for a given key and a flag indicating whether the
+// key exists. This flag distinguishes a nil value set on a key versus a
+// non-existent key in the map.
+func (m *Map[K, V]) Get(key K) (value V, ok bool) {
+	var empty V
+	if m.root == nil {
+		return empty, false
+	}
+	keyHash := m.hasher.Hash(key)
+	return m.root.get(key, 0, keyHash, m.hasher)
+}
+
+// GetOrDefault returns the value for the given key or def if the key does not exist.
+func (m *Map[K, V]) GetOrDefault(key K, def V) V {
+	if v, ok := m.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+// GetOrElse returns the value for the given key. If the key does not exist
+// then the result of fn is returned.
+func (m *Map[K, V]) GetOrElse(key K, fn func() V) V {
+	if v, ok := m.Get(key); ok {
+		return v
+	}
+	return fn()
+}
+
+// Set returns a map with the key set to the new value. A nil value is allowed.
+//
+// This function will return a new map even if the updated value is the same as
+// the existing value because Map does not track value equality.
+func (m *Map[K, V]) Set(key K, value V) *Map[K, V] {
+	return m.set(key, value, false)
+}
+
+func (m *Map[K, V]) set(key K, value V, mutable bool) *Map[K, V] {
+	// Set a hasher on the first value if one does not already exist.
+	hasher := m.hasher
+	if hasher == nil {
+		hasher = NewHasher(key)
+	}
+
+	// Generate copy if necessary.
+	other := m
 	if !mutable {
+		other = m.clone()
+	}
+	other.hasher = hasher
+
+	// If the map is empty, initialize with a simple array node.
+	if m.root == nil {
+		other.size = 1
+		other.root = &mapArrayNode[K, V]{entries: []mapEntry[K, V]{{key: key, value: value}}}
+		return other
+	}
+
+	// Otherwise copy the map and delegate insertion to the root.
+	// Resized will return true if the key does not currently exist.
+	var resized bool
+	other.root = m.root.set(key, value, 0, hasher.Hash(key), hasher, mutable, &resized)
+	if resized {
+		other.size++
+	}
+	return other
+}
+
+// MapSetMany returns a map with all key/value pairs in entries set.
+// Returns the original map if entries is empty.
+//
+// The map is copied once and all entries are inserted in-place. Nodes shared
+// with m are copied the first time they are updated and are then owned by the
+// new map so that later entries do not copy them again. m is unaffected. If m
+// is empty then no nodes are shared so they are not tracked.
+func MapSetMany[K comparable, V any](m *Map[K, V], entries map[K]V) *Map[K, V] {
+	if len(entries) == 0 {
+		return m
+	}
+
+	other := m.clone()
+	if other.root == nil {
+		for k, v := range entries {
+			other.set(k, v, true)
+		}
+		return other
+	}
+
+	owned := make(map[mapNode[K, V]]struct{})
+	for k, v := range entries {
+		other.setOwned(k, v, owned)
+	}
+	return other
+}
+
+// setOwned sets the value for key in-place. Nodes along the path to key which
+// are not in owned are copied and added to owned before the update so that
+// nodes shared with other maps are never modified. The map itself must not be
+// shared.
+func (m *Map[K, V]) setOwned(key K, value V, owned map[mapNode[K, V]]struct{}) {
+	if m.hasher == nil {
+		m.hasher = NewHasher(key)
+	}
+
+	// If the map is empty, initialize with a simple array node.
+	if m.root == nil {
+		m.size = 1
+		m.root = &mapArrayNode[K, V]{entries: []mapEntry[K, V]{{key: key, value: value}}}
+		owned[m.root] = struct{}{}
+		return
+	}
+
+	keyHash := m.hasher.Hash(key)
+	m.ownPath(keyHash, owned)
+
+	var resized bool
+	m.root = m.root.set(key, value, 0, keyHash, m.hasher, true, &resized)
+	if resized {
+		m.size++
+	}
+}
+
+// deleteOwned removes key in-place. Nodes are copied as described in
+// setOwned(). No nodes are copied if the key does not exist.
+func (m *Map[K, V]) deleteOwned(key K, owned map[mapNode[K, V]]struct{}) {
+	if m.root == nil {
+		return
+	}
+
+	keyHash := m.hasher.Hash(key)
+	if _, ok := m.root.get(key, 0, keyHash, m.hasher); !ok {
+		return
+	}
+	m.ownPath(keyHash, owned)
+
+	var resized bool
+	m.root = m.root.delete(key, 0, keyHash, m.hasher, true, &resized)
+	if resized {
+		m.size--
+	}
+}
+
+// ownPath copies each node along the path to keyHash which is not in owned.
+// The copies replace the originals in the map and are added to owned.
+func (m *Map[K, V]) ownPath(keyHash uint32, owned map[mapNode[K, V]]struct{}) {
+	m.root = ownMapNode(m.root, owned)
+	for node, shift := m.root, uint(0); ; shift += mapNodeBits {
+		var child *mapNode[K, V]
+		switch n := node.(type) {
+		case *mapBitmapIndexedNode[K, V]:
+			if bit := uint32(1) << ((keyHash >> shift) & mapNodeMask); n.bitmap&bit != 0 {
+				child = &n.nodes[bits.OnesCount32(n.bitmap&(bit-1))]
+			}
+		case *mapHashArrayNode[K, V]:
+			if n.nodes[(keyHash>>shift)&mapNodeMask] != nil {
+				child = &n.nodes[(keyHash>>shift)&mapNodeMask]
+			}
+		}
+		if child == nil {
+			break
+		}
+		*child = ownMapNode(*child, owned)
+		node = *child
+	}
+}
+
+// ownMapNode returns n if it is in owned. Otherwise returns a shallow copy of
+// n which is added to owned.
+func ownMapNode[K, V any](n mapNode[K, V], owned map[mapNode[K, V]]struct{}) mapNode[K, V] {
+	if _, ok := owned[n]; ok {
+		return n
+	}
+
+	var other mapNode[K, V]
+	switch n := n.(type) {
+	case *mapArrayNode[K, V]:
+		other = &mapArrayNode[K, V]{entries: append([]mapEntry[K, V](nil), n.entries...)}
+	case *mapBitmapIndexedNode[K, V]:
+		other = &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap, nodes: append([]mapNode[K, V](nil), n.nodes...)}
+	case *mapHashArrayNode[K, V]:
 		other = n.clone()
+	case *mapValueNode[K, V]:
+		other = newMapValueNode(n.keyHash, n.key, n.value)
+	case *mapHashCollisionNode[K, V]:
+		other = &mapHashCollisionNode[K, V]{keyHash: n.keyHash, entries: append([]mapEntry[K, V](nil), n.entries...)}
+	}
+	owned[other] = struct{}{}
+	return other
+}
+
+// Delete returns a map with the given key removed.
+// Removing a non-existent key will cause this method to return the same map.
+func (m *Map[K, V]) Delete(key K) *Map[K, V] {
+	return m.delete(key, false)
+}
+
+// Pop returns the value for key along with a map with the key removed.
+// If the key does not exist then the original map is returned and ok is false.
+func (m *Map[K, V]) Pop(key K) (value V, other *Map[K, V], ok bool) {
+	if value, ok = m.Get(key); !ok {
+		return value, m, false
+	}
+	return value, m.delete(key, false), true
+}
+
+func (m *Map[K, V]) delete(key K, mutable bool) *Map[K, V] {
+	// Return original map if no keys exist.
+	if m.root == nil {
+		return m
+	}
+
+	// If the delete did not change the node then return the original map.
+	var resized bool
+	newRoot := m.root.delete(key, 0, m.hasher.Hash(key), m.hasher, mutable, &resized)
+	if !resized {
+		return m
+	}
+
+	// Generate copy if necessary.
+	other := m
+	if !mutable {
+		other = m.clone()
+	}
+
+	// Return copy of map with new root and decreased size.
+	other.size = m.size - 1
+	other.root = newRoot
+	return other
+}
+
+// Iterator returns a new iterator for the map.
+func (m *Map[K, V]) Iterator() *MapIterator[K, V] {
+	itr := &MapIterator[K, V]{m: m}
+	itr.First()
+	return itr
+}
+
+// Any returns an arbitrary key/value pair from the map. Returns false if the
+// map is empty. No guarantee is made about which pair is returned.
+func (m *Map[K, V]) Any() (key K, value V, ok bool) {
+	for node := m.root; node != nil; {
+		switch n := node.(type) {
+		case *mapArrayNode[K, V]:
+			return n.entries[0].key, n.entries[0].value, true
+		case *mapBitmapIndexedNode[K, V]:
+			node = n.nodes[0]
+		case *mapHashArrayNode[K, V]:
+			for _, child := range n.nodes {
+				if child != nil {
+					node = child
+					break
+				}
+			}
+		case *mapValueNode[K, V]:
+			return n.key, n.value, true
+		case *mapHashCollisionNode[K, V]:
+			return n.entries[0].key, n.entries[0].value, true
+		}
+	}
+	return key, value, false
+}
+
+// Keys returns a slice of the keys in the map in iteration order.
+func (m *Map[K, V]) Keys() []K {
+	keys := make([]K, 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, _, _ := itr.Next()
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values returns a slice of the values in the map in iteration order.
+func (m *Map[K, V]) Values() []V {
+	values := make([]V, 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		_, v, _ := itr.Next()
+		values = append(values, v)
+	}
+	return values
+}
+
+// KeysAndValues returns the keys and values of the map in a single traversal.
+// The returned slices are aligned such that values[i] is the value for keys[i].
+func (m *Map[K, V]) KeysAndValues() ([]K, []V) {
+	keys, values := make([]K, 0, m.size), make([]V, 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		keys, values = append(keys, k), append(values, v)
+	}
+	return keys, values
+}
+
+// SortedKeys returns the keys of the map ordered by comparer. This provides a
+// reproducible order regardless of how the map was built. If comparer is nil
+// then the default comparer for the key type is used.
+//
+// Since a Map is not ordered, the keys are collected and then sorted which
+// takes O(n log n) time. Use a SortedMap if sorted iteration is frequent.
+func (m *Map[K, V]) SortedKeys(comparer Comparer[K]) []K {
+	keys := m.Keys()
+	if len(keys) == 0 {
+		return keys
+	} else if comparer == nil {
+		comparer = NewComparer(keys[0])
+	}
+	sort.Slice(keys, func(i, j int) bool { return comparer.Compare(keys[i], keys[j]) < 0 })
+	return keys
+}
+
+// EachSorted invokes fn for each key/value pair in the map ordered by
+// comparer. If comparer is nil then the default comparer for the key type is
+// used. See SortedKeys() for additional details.
+func (m *Map[K, V]) EachSorted(comparer Comparer[K], fn func(key K, value V)) {
+	entries := make([]mapEntry[K, V], 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		entries = append(entries, mapEntry[K, V]{key: k, value: v})
+	}
+	if len(entries) == 0 {
+		return
+	} else if comparer == nil {
+		comparer = NewComparer(entries[0].key)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return comparer.Compare(entries[i].key, entries[j].key) < 0 })
+	for i := range entries {
+		fn(entries[i].key, entries[i].value)
+	}
+}
+
+// Each invokes fn for each key/value pair in the map in iteration order.
+func (m *Map[K, V]) Each(fn func(key K, value V)) {
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		fn(k, v)
+	}
+}
+
+// Filter returns a new map containing only the key/value pairs for which keep
+// returns true. The returned map uses the same hasher as the original map.
+func (m *Map[K, V]) Filter(keep func(key K, value V) bool) *Map[K, V] {
+	other := NewMap[K, V](m.hasher)
+	for itr := m.Iterator(); !itr.Done(); {
+		if k, v, _ := itr.Next(); keep(k, v) {
+			other.set(k, v, true)
+		}
+	}
+	return other
+}
+
+// Reject returns a new map containing only the key/value pairs for which
+// reject returns false. It is the inverse of Filter().
+func (m *Map[K, V]) Reject(reject func(key K, value V) bool) *Map[K, V] {
+	return m.Filter(func(key K, value V) bool { return !reject(key, value) })
+}
+
+// TransformMap returns a new map containing the key/value pairs returned by
+// fn for each pair in m. If fn returns the same key more than once then the
+// last pair returned is kept. If hasher is nil then a default hasher is used.
+func TransformMap[K, V, K2, V2 any](m *Map[K, V], hasher Hasher[K2], fn func(key K, value V) (K2, V2)) *Map[K2, V2] {
+	other := NewMap[K2, V2](hasher)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		k2, v2 := fn(k, v)
+		other.set(k2, v2, true)
+	}
+	return other
+}
+
+// MarshalJSON encodes the map as a JSON object. The key type must have an
+// underlying string type, otherwise an error is returned.
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	if err := jsonCheckKeyType[K](); err != nil {
+		return nil, err
+	}
+
+	obj := make(map[string]V, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		s, err := jsonKeyString(k)
+		if err != nil {
+			return nil, err
+		}
+		obj[s] = v
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON decodes a JSON object into the map. The key type must have an
+// underlying string type, otherwise an error is returned. The existing hasher
+// on the map, if any, is preserved.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	var obj map[string]V
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	b := NewMapBuilder[K, V](m.hasher)
+	for s, v := range obj {
+		k, err := jsonParseKey[K](s)
+		if err != nil {
+			return err
+		}
+		b.Set(k, v)
+	}
+	*m = *b.Map()
+	return nil
+}
+
+// GobEncode encodes the key/value pairs of the map using encoding/gob.
+// The hasher is not encoded.
+func (m *Map[K, V]) GobEncode() ([]byte, error) {
+	keys, values := m.KeysAndValues()
+	return gobEncodeEntries(keys, values)
+}
+
+// GobDecode decodes key/value pairs encoded by GobEncode() into the map.
+// Because hashers cannot be encoded, the existing hasher on the map is used
+// if set. Otherwise the default hasher for the key type is used.
+func (m *Map[K, V]) GobDecode(data []byte) error {
+	keys, values, err := gobDecodeEntries[K, V](data)
+	if err != nil {
+		return err
+	}
+
+	b := NewMapBuilder[K, V](m.hasher)
+	for i := range keys {
+		b.Set(keys[i], values[i])
+	}
+	*m = *b.Map()
+	return nil
+}
+
+// MapBuilder represents an efficient builder for creating Maps.
+type MapBuilder[K, V any] struct {
+	m      *Map[K, V] // current state
+	shared bool       // true if map nodes are shared with a snapshot
+
+	owned map[mapNode[K, V]]struct{} // nodes copied since the map became shared
+}
+
+// NewMapBuilder returns a new instance of MapBuilder.
+func NewMapBuilder[K, V any](hasher Hasher[K]) *MapBuilder[K, V] {
+	return &MapBuilder[K, V]{m: NewMap[K, V](hasher)}
+}
+
+// Map returns the underlying map. Only call once.
+// Builder is invalid after call. Will panic on second invocation.
+func (b *MapBuilder[K, V]) Map() *Map[K, V] {
+	assert(b.m != nil, "immutable.MapBuilder.Map(): duplicate call to fetch map")
+	m := b.m
+	b.m = nil
+	return m
+}
+
+// Snapshot returns the current state of the builder as an immutable map.
+// Unlike Map(), the builder remains valid after the call. The snapshot is not
+// affected by later changes to the builder.
+//
+// The snapshot shares its nodes with the builder so later mutations only copy
+// the nodes along the path to each key they touch.
+func (b *MapBuilder[K, V]) Snapshot() *Map[K, V] {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	b.shared, b.owned = true, nil
+	return b.m.clone()
+}
+
+// Build returns the current state of the builder as an immutable map while
+// leaving the builder valid. It is equivalent to Snapshot(). Use Map()
+// instead if the builder is no longer needed to avoid copying nodes on later
+// mutations.
+func (b *MapBuilder[K, V]) Build() *Map[K, V] {
+	return b.Snapshot()
+}
+
+// Restore resets the builder to the contents of m, which is typically a map
+// previously returned by Snapshot(). This allows changes made since the
+// snapshot to be rolled back. The builder shares the nodes of m and copies
+// them as they are mutated so m is never affected.
+func (b *MapBuilder[K, V]) Restore(m *Map[K, V]) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	b.m, b.shared, b.owned = m, true, nil
+}
+
+// own returns true if the map nodes are shared with a snapshot. The map is
+// copied on first use so that updates can be made in-place through setOwned()
+// and deleteOwned(), which copy shared nodes and track them in owned.
+func (b *MapBuilder[K, V]) own() bool {
+	if !b.shared {
+		return false
+	} else if b.owned == nil {
+		b.m = b.m.clone()
+		b.owned = make(map[mapNode[K, V]]struct{})
+	}
+	return true
+}
+
+// set sets the value of key in-place, copying shared nodes if needed.
+func (b *MapBuilder[K, V]) set(key K, value V) {
+	if b.own() {
+		b.m.setOwned(key, value, b.owned)
+		return
+	}
+	b.m = b.m.set(key, value, true)
+}
+
+// delete removes key in-place, copying shared nodes if needed.
+func (b *MapBuilder[K, V]) delete(key K) {
+	if b.own() {
+		b.m.deleteOwned(key, b.owned)
+		return
+	}
+	b.m = b.m.delete(key, true)
+}
+
+// Valid returns true if the builder can still be used. A builder becomes
+// invalid once Map() has been called.
+func (b *MapBuilder[K, V]) Valid() bool {
+	return b.m != nil
+}
+
+// Len returns the number of elements in the underlying map.
+func (b *MapBuilder[K, V]) Len() int {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	return b.m.Len()
+}
+
+// IsEmpty returns true if the underlying map contains no elements.
+func (b *MapBuilder[K, V]) IsEmpty() bool {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	return b.m.IsEmpty()
+}
+
+// Clear removes all keys from the underlying map while keeping its hasher.
+// The builder can continue to be used afterward.
+func (b *MapBuilder[K, V]) Clear() {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	b.m, b.shared, b.owned = NewMap[K, V](b.m.hasher), false, nil
+}
+
+// Get returns the value for the given key.
+func (b *MapBuilder[K, V]) Get(key K) (value V, ok bool) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	return b.m.Get(key)
+}
+
+// Set sets the value of the given key. See Map.Set() for additional details.
+func (b *MapBuilder[K, V]) Set(key K, value V) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	b.set(key, value)
+}
+
+// MapBuilderSetMany sets all key/value pairs in entries on the builder.
+// It is a function rather than a method because entries requires a comparable
+// key type while MapBuilder accepts any key type.
+func MapBuilderSetMany[K comparable, V any](b *MapBuilder[K, V], entries map[K]V) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	for k, v := range entries {
+		b.set(k, v)
+	}
+}
+
+// Merge sets every key/value pair from other on the builder. Keys which
+// already exist in the builder are overwritten by the value in other.
+func (b *MapBuilder[K, V]) Merge(other *Map[K, V]) {
+	b.MergeFunc(other, nil)
+}
+
+// MergeFunc sets every key/value pair from other on the builder. If a key
+// already exists in the builder then the value returned by resolve is stored
+// instead. If resolve is nil then the value from other is used.
+func (b *MapBuilder[K, V]) MergeFunc(other *Map[K, V], resolve func(key K, existing, value V) V) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	for itr := other.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		if resolve != nil {
+			if existing, ok := b.m.Get(k); ok {
+				v = resolve(k, existing, v)
+			}
+		}
+		b.set(k, v)
+	}
+}
+
+// SetNew sets the value of the given key only if the key does not already
+// exist. Returns false without overwriting the existing value if it does.
+func (b *MapBuilder[K, V]) SetNew(key K, value V) bool {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	if _, ok := b.m.Get(key); ok {
+		return false
+	}
+	b.set(key, value)
+	return true
+}
+
+// Delete removes the given key. See Map.Delete() for additional details.
+func (b *MapBuilder[K, V]) Delete(key K) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	b.delete(key)
+}
+
+// Pop removes the given key and returns its value. Returns false if the key
+// does not exist.
+func (b *MapBuilder[K, V]) Pop(key K) (value V, ok bool) {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	if value, ok = b.m.Get(key); ok {
+		b.Delete(key)
+	}
+	return value, ok
+}
+
+// Iterator returns a new iterator for the underlying map.
+func (b *MapBuilder[K, V]) Iterator() *MapIterator[K, V] {
+	assert(b.m != nil, "immutable.MapBuilder: builder invalid after Map() invocation")
+	return b.m.Iterator()
+}
+
+// mapNode represents any node in the map tree.
+type mapNode[K, V any] interface {
+	get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool)
+	set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V]
+	delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V]
+}
+
+var _ mapNode[string, any] = (*mapArrayNode[string, any])(nil)
+var _ mapNode[string, any] = (*mapBitmapIndexedNode[string, any])(nil)
+var _ mapNode[string, any] = (*mapHashArrayNode[string, any])(nil)
+var _ mapNode[string, any] = (*mapValueNode[string, any])(nil)
+var _ mapNode[string, any] = (*mapHashCollisionNode[string, any])(nil)
+
+// mapLeafNode represents a node that stores a single key hash at the leaf of the map tree.
+type mapLeafNode[K, V any] interface {
+	mapNode[K, V]
+	keyHashValue() uint32
+}
+
+var _ mapLeafNode[string, any] = (*mapValueNode[string, any])(nil)
+var _ mapLeafNode[string, any] = (*mapHashCollisionNode[string, any])(nil)
+
+// mapArrayNode is a map node that stores key/value pairs in a slice.
+// Entries are stored in insertion order. An array node expands into a bitmap
+// indexed node once a given threshold size is crossed.
+type mapArrayNode[K, V any] struct {
+	entries []mapEntry[K, V]
+}
+
+// indexOf returns the entry index of the given key. Returns -1 if key not found.
+func (n *mapArrayNode[K, V]) indexOf(key K, h Hasher[K]) int {
+	for i := range n.entries {
+		if h.Equal(n.entries[i].key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// get returns the value for the given key.
+func (n *mapArrayNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
+	i := n.indexOf(key, h)
+	if i == -1 {
+		return value, false
+	}
+	return n.entries[i].value, true
+}
+
+// set inserts or updates the value for a given key. If the key is inserted and
+// the new size crosses the max size threshold, a bitmap indexed node is returned.
+func (n *mapArrayNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	idx := n.indexOf(key, h)
+
+	// Mark as resized if the key doesn't exist.
+	if idx == -1 {
+		*resized = true
+	}
+
+	// If we are adding and it crosses the max size threshold, expand the node.
+	// We do this by continually setting the entries to a value node and expanding.
+	if idx == -1 && len(n.entries) >= maxArrayMapSize {
+		var node mapNode[K, V] = newMapValueNode(h.Hash(key), key, value)
+		for _, entry := range n.entries {
+			node = node.set(entry.key, entry.value, 0, h.Hash(entry.key), h, false, resized)
+		}
+		return node
+	}
+
+	// Update in-place if mutable.
+	if mutable {
+		if idx != -1 {
+			n.entries[idx] = mapEntry[K, V]{key, value}
+		} else {
+			n.entries = append(n.entries, mapEntry[K, V]{key, value})
+		}
+		return n
+	}
+
+	// Update existing entry if a match is found.
+	// Otherwise append to the end of the element list if it doesn't exist.
+	var other mapArrayNode[K, V]
+	if idx != -1 {
+		other.entries = make([]mapEntry[K, V], len(n.entries))
+		copy(other.entries, n.entries)
+		other.entries[idx] = mapEntry[K, V]{key, value}
+	} else {
+		other.entries = make([]mapEntry[K, V], len(n.entries)+1)
+		copy(other.entries, n.entries)
+		other.entries[len(other.entries)-1] = mapEntry[K, V]{key, value}
+	}
+	return &other
+}
+
+// delete removes the given key from the node. Returns the same node if key does
+// not exist. Returns a nil node when removing the last entry.
+func (n *mapArrayNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	idx := n.indexOf(key, h)
+
+	// Return original node if key does not exist.
+	if idx == -1 {
+		return n
+	}
+	*resized = true
+
+	// Return nil if this node will contain no nodes.
+	if len(n.entries) == 1 {
+		return nil
+	}
+
+	// Update in-place, if mutable.
+	if mutable {
+		copy(n.entries[idx:], n.entries[idx+1:])
+		n.entries[len(n.entries)-1] = mapEntry[K, V]{}
+		n.entries = n.entries[:len(n.entries)-1]
+		return n
+	}
+
+	// Otherwise create a copy with the given entry removed.
+	other := &mapArrayNode[K, V]{entries: make([]mapEntry[K, V], len(n.entries)-1)}
+	copy(other.entries[:idx], n.entries[:idx])
+	copy(other.entries[idx:], n.entries[idx+1:])
+	return other
+}
+
+// mapBitmapIndexedNode represents a map branch node with a variable number of
+// node slots and indexed using a bitmap. Indexes for the node slots are
+// calculated by counting the number of set bits before the target bit using popcount.
+type mapBitmapIndexedNode[K, V any] struct {
+	bitmap uint32
+	nodes  []mapNode[K, V]
+}
+
+// get returns the value for the given key.
+func (n *mapBitmapIndexedNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
+	bit := uint32(1) << ((keyHash >> shift) & mapNodeMask)
+	if (n.bitmap & bit) == 0 {
+		return value, false
+	}
+	child := n.nodes[bits.OnesCount32(n.bitmap&(bit-1))]
+	return child.get(key, shift+mapNodeBits, keyHash, h)
+}
+
+// set inserts or updates the value for the given key. If a new key is inserted
+// and the size crosses the max size threshold then a hash array node is returned.
+func (n *mapBitmapIndexedNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	// Extract the index for the bit segment of the key hash.
+	keyHashFrag := (keyHash >> shift) & mapNodeMask
+
+	// Determine the bit based on the hash index.
+	bit := uint32(1) << keyHashFrag
+	exists := (n.bitmap & bit) != 0
+
+	// Mark as resized if the key doesn't exist.
+	if !exists {
+		*resized = true
+	}
+
+	// Find index of node based on popcount of bits before it.
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+
+	// If the node already exists, delegate set operation to it.
+	// If the node doesn't exist then create a simple value leaf node.
+	var newNode mapNode[K, V]
+	if exists {
+		newNode = n.nodes[idx].set(key, value, shift+mapNodeBits, keyHash, h, mutable, resized)
+	} else {
+		newNode = newMapValueNode(keyHash, key, value)
+	}
+
+	// Convert to a hash-array node once we exceed the max bitmap size.
+	// Copy each node based on their bit position within the bitmap.
+	if !exists && len(n.nodes) > maxBitmapIndexedSize {
+		var other mapHashArrayNode[K, V]
+		for i := uint(0); i < uint(len(other.nodes)); i++ {
+			if n.bitmap&(uint32(1)<<i) != 0 {
+				other.nodes[i] = n.nodes[other.count]
+				other.count++
+			}
+		}
+		other.nodes[keyHashFrag] = newNode
+		other.count++
+		return &other
+	}
+
+	// Update in-place if mutable.
+	if mutable {
+		if exists {
+			n.nodes[idx] = newNode
+		} else {
+			n.bitmap |= bit
+			n.nodes = append(n.nodes, nil)
+			copy(n.nodes[idx+1:], n.nodes[idx:])
+			n.nodes[idx] = newNode
+		}
+		return n
+	}
+
+	// If node exists at given slot then overwrite it with new node.
+	// Otherwise expand the node list and insert new node into appropriate position.
+	other := &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap | bit}
+	if exists {
+		other.nodes = make([]mapNode[K, V], len(n.nodes))
+		copy(other.nodes, n.nodes)
+		other.nodes[idx] = newNode
+	} else {
+		other.nodes = make([]mapNode[K, V], len(n.nodes)+1)
+		copy(other.nodes, n.nodes[:idx])
+		other.nodes[idx] = newNode
+		copy(other.nodes[idx+1:], n.nodes[idx:])
+	}
+	return other
+}
+
+// delete removes the key from the tree. If the key does not exist then the
+// original node is returned. If removing the last child node then a nil is
+// returned. Note that shrinking the node will not convert it to an array node.
+func (n *mapBitmapIndexedNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	bit := uint32(1) << ((keyHash >> shift) & mapNodeMask)
+
+	// Return original node if key does not exist.
+	if (n.bitmap & bit) == 0 {
+		return n
+	}
+
+	// Find index of node based on popcount of bits before it.
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+
+	// Delegate delete to child node.
+	child := n.nodes[idx]
+	newChild := child.delete(key, shift+mapNodeBits, keyHash, h, mutable, resized)
+
+	// Return original node if key doesn't exist in child.
+	if !*resized {
+		return n
+	}
+
+	// Remove if returned child has been deleted.
+	if newChild == nil {
+		// If we won't have any children then return nil.
+		if len(n.nodes) == 1 {
+			return nil
+		}
+
+		// Update in-place if mutable.
+		if mutable {
+			n.bitmap ^= bit
+			copy(n.nodes[idx:], n.nodes[idx+1:])
+			n.nodes[len(n.nodes)-1] = nil
+			n.nodes = n.nodes[:len(n.nodes)-1]
+			return n
+		}
+
+		// Return copy with bit removed from bitmap and node removed from node list.
+		other := &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap ^ bit, nodes: make([]mapNode[K, V], len(n.nodes)-1)}
+		copy(other.nodes[:idx], n.nodes[:idx])
+		copy(other.nodes[idx:], n.nodes[idx+1:])
+		return other
+	}
+
+	// Generate copy, if necessary.
+	other := n
+	if !mutable {
+		other = &mapBitmapIndexedNode[K, V]{bitmap: n.bitmap, nodes: make([]mapNode[K, V], len(n.nodes))}
+		copy(other.nodes, n.nodes)
+	}
+
+	// Update child.
+	other.nodes[idx] = newChild
+	return other
+}
+
+// mapHashArrayNode is a map branch node that stores nodes in a fixed length
+// array. Child nodes are indexed by their index bit segment for the current depth.
+type mapHashArrayNode[K, V any] struct {
+	count uint                       // number of set nodes
+	nodes [mapNodeSize]mapNode[K, V] // child node slots, may contain empties
+}
+
+// clone returns a shallow copy of n.
+func (n *mapHashArrayNode[K, V]) clone() *mapHashArrayNode[K, V] {
+	other := *n
+	return &other
+}
+
+// get returns the value for the given key.
+func (n *mapHashArrayNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
+	node := n.nodes[(keyHash>>shift)&mapNodeMask]
+	if node == nil {
+		return value, false
+	}
+	return node.get(key, shift+mapNodeBits, keyHash, h)
+}
+
+// set returns a node with the value set for the given key.
+func (n *mapHashArrayNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	idx := (keyHash >> shift) & mapNodeMask
+	node := n.nodes[idx]
+
+	// If node at index doesn't exist, create a simple value leaf node.
+	// Otherwise delegate set to child node.
+	var newNode mapNode[K, V]
+	if node == nil {
+		*resized = true
+		newNode = newMapValueNode(keyHash, key, value)
+	} else {
+		newNode = node.set(key, value, shift+mapNodeBits, keyHash, h, mutable, resized)
+	}
+
+	// Generate copy, if necessary.
+	other := n
+	if !mutable {
+		other = n.clone()
+	}
+
+	// Update child node (and update size, if new).
+	if node == nil {
+		other.count++
+	}
+	other.nodes[idx] = newNode
+	return other
+}
+
+// delete returns a node with the given key removed. Returns the same node if
+// the key does not exist. If node shrinks to within bitmap-indexed size then
+// converts to a bitmap-indexed node.
+func (n *mapHashArrayNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	idx := (keyHash >> shift) & mapNodeMask
+	node := n.nodes[idx]
+
+	// Return original node if child is not found.
+	if node == nil {
+		return n
+	}
+
+	// Return original node if child is unchanged.
+	newNode := node.delete(key, shift+mapNodeBits, keyHash, h, mutable, resized)
+	if !*resized {
+		return n
+	}
+
+	// If we remove a node and drop below a threshold, convert back to bitmap indexed node.
+	if newNode == nil && n.count <= maxBitmapIndexedSize {
+		other := &mapBitmapIndexedNode[K, V]{nodes: make([]mapNode[K, V], 0, n.count-1)}
+		for i, child := range n.nodes {
+			if child != nil && uint32(i) != idx {
+				other.bitmap |= 1 << uint(i)
+				other.nodes = append(other.nodes, child)
+			}
+		}
+		return other
+	}
+
+	// Generate copy, if necessary.
+	other := n
+	if !mutable {
+		other = n.clone()
+	}
+
+	// Return copy of node with child updated.
+	other.nodes[idx] = newNode
+	if newNode == nil {
+		other.count--
+	}
+	return other
+}
+
+// mapValueNode represents a leaf node with a single key/value pair.
+// A value node can be converted to a hash collision leaf node if a different
+// key with the same keyHash is inserted.
+type mapValueNode[K, V any] struct {
+	keyHash uint32
+	key     K
+	value   V
+}
+
+// newMapValueNode returns a new instance of mapValueNode.
+func newMapValueNode[K, V any](keyHash uint32, key K, value V) *mapValueNode[K, V] {
+	return &mapValueNode[K, V]{
+		keyHash: keyHash,
+		key:     key,
+		value:   value,
+	}
+}
+
+// keyHashValue returns the key hash for this node.
+func (n *mapValueNode[K, V]) keyHashValue() uint32 {
+	return n.keyHash
+}
+
+// get returns the value for the given key.
+func (n *mapValueNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
+	if !h.Equal(n.key, key) {
+		return value, false
+	}
+	return n.value, true
+}
+
+// set returns a new node with the new value set for the key. If the key equals
+// the node's key then a new value node is returned. If key is not equal to the
+// node's key but has the same hash then a hash collision node is returned.
+// Otherwise the nodes are merged into a branch node.
+func (n *mapValueNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	// If the keys match then return a new value node overwriting the value.
+	if h.Equal(n.key, key) {
+		// Update in-place if mutable.
+		if mutable {
+			n.value = value
+			return n
+		}
+		// Otherwise return a new copy.
+		return newMapValueNode(n.keyHash, key, value)
+	}
+
+	*resized = true
+
+	// Recursively merge nodes together if key hashes are different.
+	if n.keyHash != keyHash {
+		return mergeIntoNode[K, V](n, shift, keyHash, key, value)
+	}
+
+	// Merge into collision node if hash matches.
+	return &mapHashCollisionNode[K, V]{keyHash: keyHash, entries: []mapEntry[K, V]{
+		{key: n.key, value: n.value},
+		{key: key, value: value},
+	}}
+}
+
+// delete returns nil if the key matches the node's key. Otherwise returns the original node.
+func (n *mapValueNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	// Return original node if the keys do not match.
+	if !h.Equal(n.key, key) {
+		return n
+	}
+
+	// Otherwise remove the node if keys do match.
+	*resized = true
+	return nil
+}
+
+// mapHashCollisionNode represents a leaf node that contains two or more key/value
+// pairs with the same key hash. Single pairs for a hash are stored as value nodes.
+type mapHashCollisionNode[K, V any] struct {
+	keyHash uint32 // key hash for all entries
+	entries []mapEntry[K, V]
+}
+
+// keyHashValue returns the key hash for all entries on the node.
+func (n *mapHashCollisionNode[K, V]) keyHashValue() uint32 {
+	return n.keyHash
+}
+
+// indexOf returns the index of the entry for the given key.
+// Returns -1 if the key does not exist in the node.
+func (n *mapHashCollisionNode[K, V]) indexOf(key K, h Hasher[K]) int {
+	for i := range n.entries {
+		if h.Equal(n.entries[i].key, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// get returns the value for the given key.
+func (n *mapHashCollisionNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
+	for i := range n.entries {
+		if h.Equal(n.entries[i].key, key) {
+			return n.entries[i].value, true
+		}
+	}
+	return value, false
+}
+
+// set returns a copy of the node with key set to the given value.
+func (n *mapHashCollisionNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	// Merge node with key/value pair if this is not a hash collision.
+	if n.keyHash != keyHash {
+		*resized = true
+		return mergeIntoNode[K, V](n, shift, keyHash, key, value)
+	}
+
+	// Update in-place if mutable.
+	if mutable {
+		if idx := n.indexOf(key, h); idx == -1 {
+			*resized = true
+			n.entries = append(n.entries, mapEntry[K, V]{key, value})
+		} else {
+			n.entries[idx] = mapEntry[K, V]{key, value}
+		}
+		return n
+	}
+
+	// Append to end of node if key doesn't exist & mark resized.
+	// Otherwise copy nodes and overwrite at matching key index.
+	other := &mapHashCollisionNode[K, V]{keyHash: n.keyHash}
+	if idx := n.indexOf(key, h); idx == -1 {
+		*resized = true
+		other.entries = make([]mapEntry[K, V], len(n.entries)+1)
+		copy(other.entries, n.entries)
+		other.entries[len(other.entries)-1] = mapEntry[K, V]{key, value}
+	} else {
+		other.entries = make([]mapEntry[K, V], len(n.entries))
+		copy(other.entries, n.entries)
+		other.entries[idx] = mapEntry[K, V]{key, value}
+	}
+	return other
+}
+
+// delete returns a node with the given key deleted. Returns the same node if
+// the key does not exist. If removing the key would shrink the node to a single
+// entry then a value node is returned.
+func (n *mapHashCollisionNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
+	idx := n.indexOf(key, h)
+
+	// Return original node if key is not found.
+	if idx == -1 {
+		return n
+	}
+
+	// Mark as resized if key exists.
+	*resized = true
+
+	// Convert to value node if we move to one entry.
+	if len(n.entries) == 2 {
+		return &mapValueNode[K, V]{
+			keyHash: n.keyHash,
+			key:     n.entries[idx^1].key,
+			value:   n.entries[idx^1].value,
+		}
+	}
+
+	// Remove entry in-place if mutable.
+	if mutable {
+		copy(n.entries[idx:], n.entries[idx+1:])
+		n.entries[len(n.entries)-1] = mapEntry[K, V]{}
+		n.entries = n.entries[:len(n.entries)-1]
+		return n
+	}
+
+	// Return copy without entry if immutable.
+	other := &mapHashCollisionNode[K, V]{keyHash: n.keyHash, entries: make([]mapEntry[K, V], len(n.entries)-1)}
+	copy(other.entries[:idx], n.entries[:idx])
+	copy(other.entries[idx:], n.entries[idx+1:])
+	return other
+}
+
+// mergeIntoNode merges a key/value pair into an existing node.
+// Caller must verify that node's keyHash is not equal to keyHash.
+func mergeIntoNode[K, V any](node mapLeafNode[K, V], shift uint, keyHash uint32, key K, value V) mapNode[K, V] {
+	idx1 := (node.keyHashValue() >> shift) & mapNodeMask
+	idx2 := (keyHash >> shift) & mapNodeMask
+
+	// Recursively build branch nodes to combine the node and its key.
+	other := &mapBitmapIndexedNode[K, V]{bitmap: (1 << idx1) | (1 << idx2)}
+	if idx1 == idx2 {
+		other.nodes = []mapNode[K, V]{mergeIntoNode(node, shift+mapNodeBits, keyHash, key, value)}
+	} else {
+		if newNode := newMapValueNode(keyHash, key, value); idx1 < idx2 {
+			other.nodes = []mapNode[K, V]{node, newNode}
+		} else {
+			other.nodes = []mapNode[K, V]{newNode, node}
+		}
+	}
+	return other
+}
+
+// mapEntry represents a single key/value pair.
+type mapEntry[K, V any] struct {
+	key   K
+	value V
+}
+
+// MapIterator represents an iterator over a map's key/value pairs. Although
+// map keys are not sorted, the iterator's order is deterministic. Iterating
+// over the same map, or by calling Reset(), always returns pairs in the same
+// order. Maps with equal contents built in a different order may differ.
+type MapIterator[K, V any] struct {
+	m *Map[K, V] // source map
+	n int        // number of pairs returned
+
+	stack [32]mapIteratorElem[K, V] // search stack
+	depth int                       // stack depth
+}
+
+// Done returns true if no more elements remain in the iterator.
+func (itr *MapIterator[K, V]) Done() bool {
+	return itr.depth == -1
+}
+
+// Remaining returns the number of key/value pairs that have not yet been
+// returned by Next().
+func (itr *MapIterator[K, V]) Remaining() int {
+	if itr.Done() {
+		return 0
+	}
+	return itr.m.Len() - itr.n
+}
+
+// First resets the iterator to the first key/value pair.
+func (itr *MapIterator[K, V]) First() {
+	itr.n = 0
+
+	// Exit immediately if the map is empty.
+	if itr.m.root == nil {
+		itr.depth = -1
+		return
+	}
+
+	// Initialize the stack to the left most element.
+	itr.stack[0] = mapIteratorElem[K, V]{node: itr.m.root}
+	itr.depth = 0
+	itr.first()
+}
+
+// Reset repositions the iterator at the first key/value pair so that the map
+// can be scanned again without allocating a new iterator. It is equivalent to
+// First().
+func (itr *MapIterator[K, V]) Reset() {
+	itr.First()
+}
+
+// Next returns the next key/value pair. Returns a nil key when no elements remain.
+func (itr *MapIterator[K, V]) Next() (key K, value V, ok bool) {
+	// Return nil key if iteration is done.
+	if itr.Done() {
+		return key, value, false
+	}
+
+	// Retrieve current index & value. Current node is always a leaf.
+	elem := &itr.stack[itr.depth]
+	switch node := elem.node.(type) {
+	case *mapArrayNode[K, V]:
+		entry := &node.entries[elem.index]
+		key, value = entry.key, entry.value
+	case *mapValueNode[K, V]:
+		key, value = node.key, node.value
+	case *mapHashCollisionNode[K, V]:
+		entry := &node.entries[elem.index]
+		key, value = entry.key, entry.value
+	}
+
+	// Move up stack until we find a node that has remaining position ahead
+	// and move that element forward by one.
+	itr.n++
+	itr.next()
+	return key, value, true
+}
+
+// next moves to the next available key.
+func (itr *MapIterator[K, V]) next() {
+	for ; itr.depth >= 0; itr.depth-- {
+		elem := &itr.stack[itr.depth]
+
+		switch node := elem.node.(type) {
+		case *mapArrayNode[K, V]:
+			if elem.index < len(node.entries)-1 {
+				elem.index++
+				return
+			}
+
+		case *mapBitmapIndexedNode[K, V]:
+			if elem.index < len(node.nodes)-1 {
+				elem.index++
+				itr.stack[itr.depth+1].node = node.nodes[elem.index]
+				itr.depth++
+				itr.first()
+				return
+			}
+
+		case *mapHashArrayNode[K, V]:
+			for i := elem.index + 1; i < len(node.nodes); i++ {
+				if node.nodes[i] != nil {
+					elem.index = i
+					itr.stack[itr.depth+1].node = node.nodes[elem.index]
+					itr.depth++
+					itr.first()
+					return
+				}
+			}
+
+		case *mapValueNode[K, V]:
+			continue // always the last value, traverse up
+
+		case *mapHashCollisionNode[K, V]:
+			if elem.index < len(node.entries)-1 {
+				elem.index++
+				return
+			}
+		}
+	}
+}
+
+// first positions the stack left most index.
+// Elements and indexes at and below the current depth are assumed to be correct.
+func (itr *MapIterator[K, V]) first() {
+	for ; ; itr.depth++ {
+		elem := &itr.stack[itr.depth]
+
+		switch node := elem.node.(type) {
+		case *mapBitmapIndexedNode[K, V]:
+			elem.index = 0
+			itr.stack[itr.depth+1].node = node.nodes[0]
+
+		case *mapHashArrayNode[K, V]:
+			for i := 0; i < len(node.nodes); i++ {
+				if node.nodes[i] != nil { // find first node
+					elem.index = i
+					itr.stack[itr.depth+1].node = node.nodes[i]
+					break
+				}
+			}
+
+		default: // *mapArrayNode, mapLeafNode
+			elem.index = 0
+			return
+		}
+	}
+}
+
+// mapIteratorElem represents a node/index pair in the MapIterator stack.
+type mapIteratorElem[K, V any] struct {
+	node  mapNode[K, V]
+	index int
+}
+
+// Sorted map child node limit size.
+const (
+	sortedMapNodeSize = 32
+)
+
+// SortedMap represents a map of key/value pairs sorted by key. The sort order
+// is determined by the Comparer used by the map.
+//
+// This map is implemented as a B+tree.
+type SortedMap[K, V any] struct {
+	size     int                 // total number of key/value pairs
+	root     sortedMapNode[K, V] // root of b+tree
+	comparer Comparer[K]
+}
+
+// NewSortedMap returns a new instance of SortedMap. If comparer is nil then
+// a default comparer is chosen based on the key type when the map is created.
+// For interface key types, it is instead chosen after the first key is
+// inserted. Default comparers exist for int, string, and byte slice keys.
+//
+// Keys of any type may be used, including structs, as long as a comparer is
+// provided. The tree only orders keys through the comparer.
+func NewSortedMap[K, V any](comparer Comparer[K]) *SortedMap[K, V] {
+	if comparer == nil {
+		var key K
+		if hasDefaultComparer(key) {
+			comparer = NewComparer(key)
+		}
+	}
+	return &SortedMap[K, V]{
+		comparer: comparer,
+	}
+}
+
+// NewSortedMapE returns a new instance of SortedMap. If comparer is nil, a
+// default comparer is chosen based on the key type. Unlike NewSortedMap(), an
+// UnsupportedKeyTypeError is returned if no default comparer exists for the
+// key type instead of panicking on the first insert.
+func NewSortedMapE[K, V any](comparer Comparer[K]) (*SortedMap[K, V], error) {
+	if comparer == nil {
+		var key K
+		if !hasDefaultComparer(key) {
+			return nil, &UnsupportedKeyTypeError{Type: keyType[K](), Op: "comparer"}
+		}
+		comparer = NewComparer(key)
+	}
+	return NewSortedMap[K, V](comparer), nil
+}
+
+// NewSortedMapOf returns a new instance of SortedMap containing the provided
+// entries. Keys are sorted and inserted in-place into a single new map so no
+// intermediate maps are allocated.
+//
+// If comparer is nil, a default comparer is chosen as described by
+// NewSortedMap().
+func NewSortedMapOf[K comparable, V any](comparer Comparer[K], entries map[K]V) *SortedMap[K, V] {
+	m := NewSortedMap[K, V](comparer)
+	if len(entries) == 0 {
+		return m
+	}
+	for _, k := range sortedMapKeys(m, entries) {
+		m.set(k, entries[k], true)
+	}
+	return m
+}
+
+// Len returns the number of elements in the sorted map.
+func (m *SortedMap[K, V]) Len() int {
+	return m.size
+}
+
+// IsEmpty returns true if the sorted map contains no elements.
+func (m *SortedMap[K, V]) IsEmpty() bool {
+	return m.size == 0
+}
+
+// Get returns the value for a given key and a flag indicating if the key is set.
+// The flag can be used to distinguish between a nil-set key versus an unset key.
+func (m *SortedMap[K, V]) Get(key K) (V, bool) {
+	if m.root == nil {
+		var v V
+		return v, false
+	}
+	return m.root.get(key, m.comparer)
+}
+
+// GetOrDefault returns the value for the given key or def if the key does not exist.
+func (m *SortedMap[K, V]) GetOrDefault(key K, def V) V {
+	if v, ok := m.Get(key); ok {
+		return v
+	}
+	return def
+}
+
+// GetOrElse returns the value for the given key. If the key does not exist
+// then the result of fn is returned.
+func (m *SortedMap[K, V]) GetOrElse(key K, fn func() V) V {
+	if v, ok := m.Get(key); ok {
+		return v
+	}
+	return fn()
+}
+
+// Set returns a copy of the map with the key set to the given value.
+func (m *SortedMap[K, V]) Set(key K, value V) *SortedMap[K, V] {
+	return m.set(key, value, false)
+}
+
+func (m *SortedMap[K, V]) set(key K, value V, mutable bool) *SortedMap[K, V] {
+	// Set a comparer on the first value if one does not already exist.
+	comparer := m.comparer
+	if comparer == nil {
+		comparer = NewComparer(key)
+	}
+
+	// Create copy, if necessary.
+	other := m
+	if !mutable {
+		other = m.clone()
+	}
+	other.comparer = comparer
+
+	// If no values are set then initialize with a leaf node.
+	if m.root == nil {
+		other.size = 1
+		other.root = &sortedMapLeafNode[K, V]{entries: []mapEntry[K, V]{{key: key, value: value}}}
+		return other
+	}
+
+	// Otherwise delegate to root node.
+	// If a split occurs then grow the tree from the root.
+	var resized bool
+	newRoot, splitNode := m.root.set(key, value, comparer, mutable, &resized)
+	if splitNode != nil {
+		newRoot = newSortedMapBranchNode(newRoot, splitNode)
 	}
 
-	// Update child node (and update size, if new).
-	if node == nil {
-		other.count++
+	// Update root and size (if resized).
+	other.size = m.size
+	other.root = newRoot
+	if resized {
+		other.size++
 	}
-	other.nodes[idx] = newNode
 	return other
 }
 
-// delete returns a node with the given key removed. Returns the same node if
-// the key does not exist. If node shrinks to within bitmap-indexed size then
-// converts to a bitmap-indexed node.
-func (n *mapHashArrayNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	idx := (keyHash >> shift) & mapNodeMask
-	node := n.nodes[idx]
-
-	// Return original node if child is not found.
-	if node == nil {
-		return n
-	}
-
-	// Return original node if child is unchanged.
-	newNode := node.delete(key, shift+mapNodeBits, keyHash, h, mutable, resized)
-	if !*resized {
-		return n
+// SortedMapSetMany returns a map with all key/value pairs in entries set.
+// Returns the original map if entries is empty.
+//
+// Keys are sorted before insertion so that leaf nodes are filled in order.
+// The map is copied once and all entries are inserted in-place. Nodes shared
+// with m are copied the first time they are updated and are then owned by the
+// new map so that later entries do not copy them again. m is unaffected. If m
+// is empty then no nodes are shared so they are not tracked.
+func SortedMapSetMany[K comparable, V any](m *SortedMap[K, V], entries map[K]V) *SortedMap[K, V] {
+	if len(entries) == 0 {
+		return m
 	}
 
-	// If we remove a node and drop below a threshold, convert back to bitmap indexed node.
-	if newNode == nil && n.count <= maxBitmapIndexedSize {
-		other := &mapBitmapIndexedNode[K, V]{nodes: make([]mapNode[K, V], 0, n.count-1)}
-		for i, child := range n.nodes {
-			if child != nil && uint32(i) != idx {
-				other.bitmap |= 1 << uint(i)
-				other.nodes = append(other.nodes, child)
-			}
+	other := m.clone()
+	if other.root == nil {
+		for _, k := range sortedMapKeys(other, entries) {
+			other.set(k, entries[k], true)
 		}
 		return other
 	}
 
-	// Generate copy, if necessary.
-	other := n
-	if !mutable {
-		other = n.clone()
-	}
-
-	// Return copy of node with child updated.
-	other.nodes[idx] = newNode
-	if newNode == nil {
-		other.count--
+	owned := make(map[sortedMapNode[K, V]]struct{})
+	for _, k := range sortedMapKeys(other, entries) {
+		other.setOwned(k, entries[k], owned)
 	}
 	return other
 }
 
-// mapValueNode represents a leaf node with a single key/value pair.
-// A value node can be converted to a hash collision leaf node if a different
-// key with the same keyHash is inserted.
-type mapValueNode[K, V any] struct {
-	keyHash uint32
-	key     K
-	value   V
-}
+// setOwned sets the value for key in-place. Nodes along the path to key which
+// are not in owned are copied and added to owned before the update so that
+// nodes shared with other maps are never modified. The map itself must not be
+// shared.
+func (m *SortedMap[K, V]) setOwned(key K, value V, owned map[sortedMapNode[K, V]]struct{}) {
+	if m.comparer == nil {
+		m.comparer = NewComparer(key)
+	}
 
-// newMapValueNode returns a new instance of mapValueNode.
-func newMapValueNode[K, V any](keyHash uint32, key K, value V) *mapValueNode[K, V] {
-	return &mapValueNode[K, V]{
-		keyHash: keyHash,
-		key:     key,
-		value:   value,
+	// If no values are set then initialize with a leaf node.
+	if m.root == nil {
+		m.size = 1
+		m.root = &sortedMapLeafNode[K, V]{entries: []mapEntry[K, V]{{key: key, value: value}}}
+		owned[m.root] = struct{}{}
+		return
 	}
-}
 
-// keyHashValue returns the key hash for this node.
-func (n *mapValueNode[K, V]) keyHashValue() uint32 {
-	return n.keyHash
+	// Take ownership of each node along the path to the key.
+	m.ownPath(key, owned)
+
+	// If a split occurs then grow the tree from the root.
+	var resized bool
+	newRoot, splitNode := m.root.set(key, value, m.comparer, true, &resized)
+	if splitNode != nil {
+		newRoot = newSortedMapBranchNode(newRoot, splitNode)
+	}
+	m.root = newRoot
+	if resized {
+		m.size++
+	}
 }
 
-// get returns the value for the given key.
-func (n *mapValueNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
-	if !h.Equal(n.key, key) {
-		return value, false
+// deleteOwned removes key in-place. Nodes are copied as described in
+// setOwned(). No nodes are copied if the key does not exist.
+func (m *SortedMap[K, V]) deleteOwned(key K, owned map[sortedMapNode[K, V]]struct{}) {
+	if _, ok := m.Get(key); !ok {
+		return
 	}
-	return n.value, true
+	m.ownPath(key, owned)
+
+	var resized bool
+	m.root = m.root.delete(key, m.comparer, true, &resized)
+	m.size--
 }
 
-// set returns a new node with the new value set for the key. If the key equals
-// the node's key then a new value node is returned. If key is not equal to the
-// node's key but has the same hash then a hash collision node is returned.
-// Otherwise the nodes are merged into a branch node.
-func (n *mapValueNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	// If the keys match then return a new value node overwriting the value.
-	if h.Equal(n.key, key) {
-		// Update in-place if mutable.
-		if mutable {
-			n.value = value
-			return n
-		}
-		// Otherwise return a new copy.
-		return newMapValueNode(n.keyHash, key, value)
+// deleteRangeOwned removes all keys in the range [lo, hi) in-place. Nodes
+// which may be modified are copied as described in setOwned().
+func (m *SortedMap[K, V]) deleteRangeOwned(lo, hi K, owned map[sortedMapNode[K, V]]struct{}) {
+	if m.root == nil || m.comparer.Compare(lo, hi) >= 0 {
+		return
 	}
+	m.root = ownSortedMapNode(m.root, owned)
+	ownSortedMapRange(m.root, lo, hi, m.comparer, owned)
+	m.deleteRange(lo, hi, true)
+}
 
-	*resized = true
+// ownSortedMapRange copies the children of node which are only partially
+// within the range [lo, hi) and are not in owned. These are the only nodes
+// modified by sortedMapDeleteRange(). Node itself must already be owned.
+func ownSortedMapRange[K, V any](node sortedMapNode[K, V], lo, hi K, c Comparer[K], owned map[sortedMapNode[K, V]]struct{}) {
+	n, ok := node.(*sortedMapBranchNode[K, V])
+	if !ok {
+		return
+	}
 
-	// Recursively merge nodes together if key hashes are different.
-	if n.keyHash != keyHash {
-		return mergeIntoNode[K, V](n, shift, keyHash, key, value)
+	start := n.indexOf(lo, c)
+	end := start + 1
+	for end < len(n.elems) && c.Compare(n.elems[end].key, hi) < 0 {
+		end++
+	}
+	for i := start; i < end; i++ {
+		if i == start || i == end-1 {
+			n.elems[i].node = ownSortedMapNode(n.elems[i].node, owned)
+			ownSortedMapRange(n.elems[i].node, lo, hi, c, owned)
+		}
 	}
+}
 
-	// Merge into collision node if hash matches.
-	return &mapHashCollisionNode[K, V]{keyHash: keyHash, entries: []mapEntry[K, V]{
-		{key: n.key, value: n.value},
-		{key: key, value: value},
-	}}
+// ownPath copies each node along the path to key which is not in owned.
+// The copies replace the originals in the map and are added to owned.
+func (m *SortedMap[K, V]) ownPath(key K, owned map[sortedMapNode[K, V]]struct{}) {
+	m.root = ownSortedMapNode(m.root, owned)
+	for node := m.root; ; {
+		n, ok := node.(*sortedMapBranchNode[K, V])
+		if !ok {
+			break
+		}
+		elem := &n.elems[n.indexOf(key, m.comparer)]
+		elem.node = ownSortedMapNode(elem.node, owned)
+		node = elem.node
+	}
 }
 
-// delete returns nil if the key matches the node's key. Otherwise returns the original node.
-func (n *mapValueNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	// Return original node if the keys do not match.
-	if !h.Equal(n.key, key) {
+// ownSortedMapNode returns n if it is in owned. Otherwise returns a shallow
+// copy of n which is added to owned.
+func ownSortedMapNode[K, V any](n sortedMapNode[K, V], owned map[sortedMapNode[K, V]]struct{}) sortedMapNode[K, V] {
+	if _, ok := owned[n]; ok {
 		return n
 	}
 
-	// Otherwise remove the node if keys do match.
-	*resized = true
-	return nil
+	var other sortedMapNode[K, V]
+	switch n := n.(type) {
+	case *sortedMapBranchNode[K, V]:
+		other = &sortedMapBranchNode[K, V]{elems: append([]sortedMapBranchElem[K, V](nil), n.elems...), size: n.size}
+	case *sortedMapLeafNode[K, V]:
+		other = &sortedMapLeafNode[K, V]{entries: append([]mapEntry[K, V](nil), n.entries...)}
+	}
+	owned[other] = struct{}{}
+	return other
 }
 
-// mapHashCollisionNode represents a leaf node that contains two or more key/value
-// pairs with the same key hash. Single pairs for a hash are stored as value nodes.
-type mapHashCollisionNode[K, V any] struct {
-	keyHash uint32 // key hash for all entries
-	entries []mapEntry[K, V]
+// sortedMapKeys returns the keys of entries sorted by the comparer of m.
+// If m does not yet have a comparer then the default comparer is used. The
+// map itself is not modified as it may be shared with other goroutines.
+func sortedMapKeys[K comparable, V any](m *SortedMap[K, V], entries map[K]V) []K {
+	keys := make([]K, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	comparer := m.comparer
+	if comparer == nil {
+		comparer = NewComparer(keys[0])
+	}
+	sort.Slice(keys, func(i, j int) bool { return comparer.Compare(keys[i], keys[j]) < 0 })
+	return keys
 }
 
-// keyHashValue returns the key hash for all entries on the node.
-func (n *mapHashCollisionNode[K, V]) keyHashValue() uint32 {
-	return n.keyHash
+// Delete returns a copy of the map with the key removed.
+// Returns the original map if key does not exist.
+func (m *SortedMap[K, V]) Delete(key K) *SortedMap[K, V] {
+	return m.delete(key, false)
 }
 
-// indexOf returns the index of the entry for the given key.
-// Returns -1 if the key does not exist in the node.
-func (n *mapHashCollisionNode[K, V]) indexOf(key K, h Hasher[K]) int {
-	for i := range n.entries {
-		if h.Equal(n.entries[i].key, key) {
-			return i
-		}
+// Pop returns the value for key along with a map with the key removed.
+// If the key does not exist then the original map is returned and ok is false.
+func (m *SortedMap[K, V]) Pop(key K) (value V, other *SortedMap[K, V], ok bool) {
+	if value, ok = m.Get(key); !ok {
+		return value, m, false
 	}
-	return -1
+	return value, m.delete(key, false), true
 }
 
-// get returns the value for the given key.
-func (n *mapHashCollisionNode[K, V]) get(key K, shift uint, keyHash uint32, h Hasher[K]) (value V, ok bool) {
-	for i := range n.entries {
-		if h.Equal(n.entries[i].key, key) {
-			return n.entries[i].value, true
-		}
+func (m *SortedMap[K, V]) delete(key K, mutable bool) *SortedMap[K, V] {
+	// Return original map if no keys exist.
+	if m.root == nil {
+		return m
 	}
-	return value, false
-}
 
-// set returns a copy of the node with key set to the given value.
-func (n *mapHashCollisionNode[K, V]) set(key K, value V, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	// Merge node with key/value pair if this is not a hash collision.
-	if n.keyHash != keyHash {
-		*resized = true
-		return mergeIntoNode[K, V](n, shift, keyHash, key, value)
+	// If the delete did not change the node then return the original map.
+	var resized bool
+	newRoot := m.root.delete(key, m.comparer, mutable, &resized)
+	if !resized {
+		return m
 	}
 
-	// Update in-place if mutable.
-	if mutable {
-		if idx := n.indexOf(key, h); idx == -1 {
-			*resized = true
-			n.entries = append(n.entries, mapEntry[K, V]{key, value})
-		} else {
-			n.entries[idx] = mapEntry[K, V]{key, value}
-		}
-		return n
+	// Create copy, if necessary.
+	other := m
+	if !mutable {
+		other = m.clone()
 	}
 
-	// Append to end of node if key doesn't exist & mark resized.
-	// Otherwise copy nodes and overwrite at matching key index.
-	other := &mapHashCollisionNode[K, V]{keyHash: n.keyHash}
-	if idx := n.indexOf(key, h); idx == -1 {
-		*resized = true
-		other.entries = make([]mapEntry[K, V], len(n.entries)+1)
-		copy(other.entries, n.entries)
-		other.entries[len(other.entries)-1] = mapEntry[K, V]{key, value}
-	} else {
-		other.entries = make([]mapEntry[K, V], len(n.entries))
-		copy(other.entries, n.entries)
-		other.entries[idx] = mapEntry[K, V]{key, value}
-	}
+	// Update root and size.
+	other.size = m.size - 1
+	other.root = newRoot
 	return other
 }
 
-// delete returns a node with the given key deleted. Returns the same node if
-// the key does not exist. If removing the key would shrink the node to a single
-// entry then a value node is returned.
-func (n *mapHashCollisionNode[K, V]) delete(key K, shift uint, keyHash uint32, h Hasher[K], mutable bool, resized *bool) mapNode[K, V] {
-	idx := n.indexOf(key, h)
+// DeleteRange returns a copy of the map with all keys in the range [lo, hi)
+// removed. Subtrees entirely within the range are dropped without visiting
+// their entries. Returns the original map if no keys are in the range.
+func (m *SortedMap[K, V]) DeleteRange(lo, hi K) *SortedMap[K, V] {
+	return m.deleteRange(lo, hi, false)
+}
 
-	// Return original node if key is not found.
-	if idx == -1 {
-		return n
+func (m *SortedMap[K, V]) deleteRange(lo, hi K, mutable bool) *SortedMap[K, V] {
+	if m.root == nil || m.comparer.Compare(lo, hi) >= 0 {
+		return m
 	}
 
-	// Mark as resized if key exists.
-	*resized = true
-
-	// Convert to value node if we move to one entry.
-	if len(n.entries) == 2 {
-		return &mapValueNode[K, V]{
-			keyHash: n.keyHash,
-			key:     n.entries[idx^1].key,
-			value:   n.entries[idx^1].value,
-		}
+	// If the delete did not change the tree then return the original map.
+	var deleted int
+	newRoot := sortedMapDeleteRange(m.root, lo, hi, m.comparer, mutable, &deleted)
+	if deleted == 0 {
+		return m
 	}
 
-	// Remove entry in-place if mutable.
-	if mutable {
-		copy(n.entries[idx:], n.entries[idx+1:])
-		n.entries[len(n.entries)-1] = mapEntry[K, V]{}
-		n.entries = n.entries[:len(n.entries)-1]
-		return n
+	// Create copy, if necessary.
+	other := m
+	if !mutable {
+		other = m.clone()
 	}
 
-	// Return copy without entry if immutable.
-	other := &mapHashCollisionNode[K, V]{keyHash: n.keyHash, entries: make([]mapEntry[K, V], len(n.entries)-1)}
-	copy(other.entries[:idx], n.entries[:idx])
-	copy(other.entries[idx:], n.entries[idx+1:])
+	// Update root and size.
+	other.size = m.size - deleted
+	other.root = newRoot
 	return other
 }
 
-// mergeIntoNode merges a key/value pair into an existing node.
-// Caller must verify that node's keyHash is not equal to keyHash.
-func mergeIntoNode[K, V any](node mapLeafNode[K, V], shift uint, keyHash uint32, key K, value V) mapNode[K, V] {
-	idx1 := (node.keyHashValue() >> shift) & mapNodeMask
-	idx2 := (keyHash >> shift) & mapNodeMask
+// sortedMapDeleteRange returns node with all keys in the range [lo, hi)
+// removed. Returns nil if the node becomes empty. The number of removed keys
+// is added to deleted.
+func sortedMapDeleteRange[K, V any](node sortedMapNode[K, V], lo, hi K, c Comparer[K], mutable bool, deleted *int) sortedMapNode[K, V] {
+	switch n := node.(type) {
+	case *sortedMapBranchNode[K, V]:
+		// Determine the children which may contain keys in the range.
+		start := n.indexOf(lo, c)
+		end := start + 1
+		for end < len(n.elems) && c.Compare(n.elems[end].key, hi) < 0 {
+			end++
+		}
+
+		// Only the first & last children can be partially within the range.
+		// Children between them are dropped entirely.
+		prev := *deleted
+		var mid []sortedMapBranchElem[K, V]
+		for i := start; i < end; i++ {
+			if i != start && i != end-1 {
+				*deleted += n.elems[i].node.len()
+				continue
+			}
+			if child := sortedMapDeleteRange(n.elems[i].node, lo, hi, c, mutable, deleted); child != nil {
+				mid = append(mid, sortedMapBranchElem[K, V]{key: child.minKey(), node: child})
+			}
+		}
+
+		// Return original node if no children have changed.
+		if *deleted == prev {
+			return n
+		} else if len(n.elems)-(end-start)+len(mid) == 0 {
+			return nil
+		}
+
+		// If mutable, update in-place.
+		if mutable {
+			tail := len(n.elems) - end
+			copy(n.elems[start:], mid)
+			copy(n.elems[start+len(mid):], n.elems[end:])
+			for i := start + len(mid) + tail; i < len(n.elems); i++ {
+				n.elems[i] = sortedMapBranchElem[K, V]{}
+			}
+			n.elems = n.elems[:start+len(mid)+tail]
+			n.size -= *deleted - prev
+			return n
+		}
+
+		// Return a copy with the updated children.
+		other := &sortedMapBranchNode[K, V]{
+			elems: make([]sortedMapBranchElem[K, V], 0, len(n.elems)-(end-start)+len(mid)),
+			size:  n.size - (*deleted - prev),
+		}
+		other.elems = append(other.elems, n.elems[:start]...)
+		other.elems = append(other.elems, mid...)
+		other.elems = append(other.elems, n.elems[end:]...)
+		return other
 
-	// Recursively build branch nodes to combine the node and its key.
-	other := &mapBitmapIndexedNode[K, V]{bitmap: (1 << idx1) | (1 << idx2)}
-	if idx1 == idx2 {
-		other.nodes = []mapNode[K, V]{mergeIntoNode(node, shift+mapNodeBits, keyHash, key, value)}
-	} else {
-		if newNode := newMapValueNode(keyHash, key, value); idx1 < idx2 {
-			other.nodes = []mapNode[K, V]{node, newNode}
-		} else {
-			other.nodes = []mapNode[K, V]{newNode, node}
+	case *sortedMapLeafNode[K, V]:
+		i, j := n.indexOf(lo, c), n.indexOf(hi, c)
+		if i >= j {
+			return n
+		}
+		*deleted += j - i
+
+		// If every entry is removed then return nil.
+		if j-i == len(n.entries) {
+			return nil
+		}
+
+		// Update in-place, if mutable.
+		if mutable {
+			copy(n.entries[i:], n.entries[j:])
+			for k := len(n.entries) - (j - i); k < len(n.entries); k++ {
+				n.entries[k] = mapEntry[K, V]{}
+			}
+			n.entries = n.entries[:len(n.entries)-(j-i)]
+			return n
 		}
+
+		// Return copy of node with entries removed.
+		other := &sortedMapLeafNode[K, V]{entries: make([]mapEntry[K, V], 0, len(n.entries)-(j-i))}
+		other.entries = append(other.entries, n.entries[:i]...)
+		other.entries = append(other.entries, n.entries[j:]...)
+		return other
 	}
-	return other
+	return node
 }
 
-// mapEntry represents a single key/value pair.
-type mapEntry[K, V any] struct {
-	key   K
-	value V
+// clone returns a shallow copy of m.
+func (m *SortedMap[K, V]) clone() *SortedMap[K, V] {
+	other := *m
+	return &other
 }
 
-// MapIterator represents an iterator over a map's key/value pairs. Although
-// map keys are not sorted, the iterator's order is deterministic.
-type MapIterator[K, V any] struct {
-	m *Map[K, V] // source map
+// Clone returns an independent copy of the map in O(1) time.
+// See List.Clone() for more details.
+func (m *SortedMap[K, V]) Clone() *SortedMap[K, V] {
+	return m.clone()
+}
 
-	stack [32]mapIteratorElem[K, V] // search stack
-	depth int                       // stack depth
+// Iterator returns a new iterator for this map positioned at the first key.
+func (m *SortedMap[K, V]) Iterator() *SortedMapIterator[K, V] {
+	itr := &SortedMapIterator[K, V]{m: m}
+	itr.First()
+	return itr
 }
 
-// Done returns true if no more elements remain in the iterator.
-func (itr *MapIterator[K, V]) Done() bool {
-	return itr.depth == -1
+// RangeIterator returns a new iterator over the entries with keys between lo
+// and hi, positioned at the first key in the range. The loInclusive and
+// hiInclusive flags determine whether keys equal to lo and hi are included.
+func (m *SortedMap[K, V]) RangeIterator(lo K, loInclusive bool, hi K, hiInclusive bool) *SortedMapIterator[K, V] {
+	itr := &SortedMapIterator[K, V]{
+		m: m,
+		bounds: &sortedMapBounds[K]{
+			lo:          lo,
+			hi:          hi,
+			loInclusive: loInclusive,
+			hiInclusive: hiInclusive,
+		},
+	}
+	itr.First()
+	return itr
 }
 
-// First resets the iterator to the first key/value pair.
-func (itr *MapIterator[K, V]) First() {
-	// Exit immediately if the map is empty.
-	if itr.m.root == nil {
-		itr.depth = -1
-		return
+// Keys returns a slice of the keys in the map in sorted order.
+func (m *SortedMap[K, V]) Keys() []K {
+	keys := make([]K, 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, _, _ := itr.Next()
+		keys = append(keys, k)
 	}
+	return keys
+}
 
-	// Initialize the stack to the left most element.
-	itr.stack[0] = mapIteratorElem[K, V]{node: itr.m.root}
-	itr.depth = 0
-	itr.first()
+// Values returns a slice of the values in the map in key order.
+func (m *SortedMap[K, V]) Values() []V {
+	values := make([]V, 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		_, v, _ := itr.Next()
+		values = append(values, v)
+	}
+	return values
 }
 
-// Next returns the next key/value pair. Returns a nil key when no elements remain.
-func (itr *MapIterator[K, V]) Next() (key K, value V, ok bool) {
-	// Return nil key if iteration is done.
-	if itr.Done() {
+// Min returns the entry with the smallest key in the map.
+// Returns false if the map is empty.
+func (m *SortedMap[K, V]) Min() (key K, value V, ok bool) {
+	if m.root == nil {
 		return key, value, false
 	}
 
-	// Retrieve current index & value. Current node is always a leaf.
-	elem := &itr.stack[itr.depth]
-	switch node := elem.node.(type) {
-	case *mapArrayNode[K, V]:
-		entry := &node.entries[elem.index]
-		key, value = entry.key, entry.value
-	case *mapValueNode[K, V]:
-		key, value = node.key, node.value
-	case *mapHashCollisionNode[K, V]:
-		entry := &node.entries[elem.index]
-		key, value = entry.key, entry.value
+	node := m.root
+	for {
+		switch n := node.(type) {
+		case *sortedMapBranchNode[K, V]:
+			node = n.elems[0].node
+		case *sortedMapLeafNode[K, V]:
+			entry := &n.entries[0]
+			return entry.key, entry.value, true
+		}
 	}
-
-	// Move up stack until we find a node that has remaining position ahead
-	// and move that element forward by one.
-	itr.next()
-	return key, value, true
 }
 
-// next moves to the next available key.
-func (itr *MapIterator[K, V]) next() {
-	for ; itr.depth >= 0; itr.depth-- {
-		elem := &itr.stack[itr.depth]
-
-		switch node := elem.node.(type) {
-		case *mapArrayNode[K, V]:
-			if elem.index < len(node.entries)-1 {
-				elem.index++
-				return
-			}
-
-		case *mapBitmapIndexedNode[K, V]:
-			if elem.index < len(node.nodes)-1 {
-				elem.index++
-				itr.stack[itr.depth+1].node = node.nodes[elem.index]
-				itr.depth++
-				itr.first()
-				return
-			}
+// Max returns the entry with the largest key in the map.
+// Returns false if the map is empty.
+func (m *SortedMap[K, V]) Max() (key K, value V, ok bool) {
+	if m.root == nil {
+		return key, value, false
+	}
 
-		case *mapHashArrayNode[K, V]:
-			for i := elem.index + 1; i < len(node.nodes); i++ {
-				if node.nodes[i] != nil {
-					elem.index = i
-					itr.stack[itr.depth+1].node = node.nodes[elem.index]
-					itr.depth++
-					itr.first()
-					return
-				}
-			}
+	node := m.root
+	for {
+		switch n := node.(type) {
+		case *sortedMapBranchNode[K, V]:
+			node = n.elems[len(n.elems)-1].node
+		case *sortedMapLeafNode[K, V]:
+			entry := &n.entries[len(n.entries)-1]
+			return entry.key, entry.value, true
+		}
+	}
+}
 
-		case *mapValueNode[K, V]:
-			continue // always the last value, traverse up
+// Rank returns the number of keys in the map which are less than key. If key
+// exists in the map then this is its 0-based position in sorted order. This
+// method runs in O(log n) time.
+func (m *SortedMap[K, V]) Rank(key K) int {
+	if m.root == nil {
+		return 0
+	}
 
-		case *mapHashCollisionNode[K, V]:
-			if elem.index < len(node.entries)-1 {
-				elem.index++
-				return
+	var n int
+	for node := m.root; ; {
+		switch x := node.(type) {
+		case *sortedMapBranchNode[K, V]:
+			idx := x.indexOf(key, m.comparer)
+			for i := 0; i < idx; i++ {
+				n += x.elems[i].node.len()
 			}
+			node = x.elems[idx].node
+		case *sortedMapLeafNode[K, V]:
+			return n + x.indexOf(key, m.comparer)
 		}
 	}
 }
 
-// first positions the stack left most index.
-// Elements and indexes at and below the current depth are assumed to be correct.
-func (itr *MapIterator[K, V]) first() {
-	for ; ; itr.depth++ {
-		elem := &itr.stack[itr.depth]
-
-		switch node := elem.node.(type) {
-		case *mapBitmapIndexedNode[K, V]:
-			elem.index = 0
-			itr.stack[itr.depth+1].node = node.nodes[0]
+// Select returns the entry at the given 0-based position in sorted order.
+// Returns false if index is below zero or greater than or equal to the map
+// size. This method runs in O(log n) time.
+func (m *SortedMap[K, V]) Select(index int) (key K, value V, ok bool) {
+	if index < 0 || index >= m.size {
+		return key, value, false
+	}
 
-		case *mapHashArrayNode[K, V]:
-			for i := 0; i < len(node.nodes); i++ {
-				if node.nodes[i] != nil { // find first node
-					elem.index = i
-					itr.stack[itr.depth+1].node = node.nodes[i]
-					break
+	for node := m.root; ; {
+		switch x := node.(type) {
+		case *sortedMapBranchNode[K, V]:
+			for i := range x.elems {
+				if n := x.elems[i].node.len(); index >= n {
+					index -= n
+					continue
 				}
+				node = x.elems[i].node
+				break
 			}
-
-		default: // *mapArrayNode, mapLeafNode
-			elem.index = 0
-			return
+		case *sortedMapLeafNode[K, V]:
+			entry := &x.entries[index]
+			return entry.key, entry.value, true
 		}
 	}
 }
 
-// mapIteratorElem represents a node/index pair in the MapIterator stack.
-type mapIteratorElem[K, V any] struct {
-	node  mapNode[K, V]
-	index int
+// Floor returns the entry with the largest key less than or equal to key.
+// If key exists in the map then its entry is returned. Returns false if no
+// such entry exists.
+func (m *SortedMap[K, V]) Floor(key K) (K, V, bool) {
+	itr := m.Iterator()
+	itr.SeekReverse(key)
+	return itr.Prev()
 }
 
-// Sorted map child node limit size.
-const (
-	sortedMapNodeSize = 32
-)
-
-// SortedMap represents a map of key/value pairs sorted by key. The sort order
-// is determined by the Comparer used by the map.
-//
-// This map is implemented as a B+tree.
-type SortedMap[K, V any] struct {
-	size     int                 // total number of key/value pairs
-	root     sortedMapNode[K, V] // root of b+tree
-	comparer Comparer[K]
+// Ceil returns the entry with the smallest key greater than or equal to key.
+// If key exists in the map then its entry is returned. Returns false if no
+// such entry exists.
+func (m *SortedMap[K, V]) Ceil(key K) (K, V, bool) {
+	itr := m.Iterator()
+	itr.Seek(key)
+	return itr.Next()
 }
 
-// NewSortedMap returns a new instance of SortedMap. If comparer is nil then
-// a default comparer is set after the first key is inserted. Default comparers
-// exist for int, string, and byte slice keys.
-func NewSortedMap[K, V any](comparer Comparer[K]) *SortedMap[K, V] {
-	return &SortedMap[K, V]{
-		comparer: comparer,
+// Each invokes fn for each key/value pair in the map in key order.
+func (m *SortedMap[K, V]) Each(fn func(key K, value V)) {
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		fn(k, v)
 	}
 }
 
-// NewSortedMapOf returns a new instance of SortedMap, containing a map of provided entries.
-//
-// If comparer is nil then a default comparer is set after the first key is inserted. Default comparers
-// exist for int, string, and byte slice keys.
-func NewSortedMapOf[K comparable, V any](comparer Comparer[K], entries map[K]V) *SortedMap[K, V] {
-	m := &SortedMap[K, V]{
-		comparer: comparer,
-	}
-	for k, v := range entries {
-		m.set(k, v, true)
+// Filter returns a new map containing only the key/value pairs for which keep
+// returns true. The returned map uses the same comparer as the original map.
+func (m *SortedMap[K, V]) Filter(keep func(key K, value V) bool) *SortedMap[K, V] {
+	other := NewSortedMap[K, V](m.comparer)
+	for itr := m.Iterator(); !itr.Done(); {
+		if k, v, _ := itr.Next(); keep(k, v) {
+			other.set(k, v, true)
+		}
 	}
-	return m
+	return other
 }
 
-// Len returns the number of elements in the sorted map.
-func (m *SortedMap[K, V]) Len() int {
-	return m.size
+// Reject returns a new map containing only the key/value pairs for which
+// reject returns false. It is the inverse of Filter().
+func (m *SortedMap[K, V]) Reject(reject func(key K, value V) bool) *SortedMap[K, V] {
+	return m.Filter(func(key K, value V) bool { return !reject(key, value) })
 }
 
-// Get returns the value for a given key and a flag indicating if the key is set.
-// The flag can be used to distinguish between a nil-set key versus an unset key.
-func (m *SortedMap[K, V]) Get(key K) (V, bool) {
-	if m.root == nil {
-		var v V
-		return v, false
+// TransformSortedMap returns a new map containing the key/value pairs returned
+// by fn for each pair in m. If fn returns the same key more than once then the
+// last pair returned is kept. If comparer is nil then a default comparer is
+// used.
+func TransformSortedMap[K, V, K2, V2 any](m *SortedMap[K, V], comparer Comparer[K2], fn func(key K, value V) (K2, V2)) *SortedMap[K2, V2] {
+	other := NewSortedMap[K2, V2](comparer)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		k2, v2 := fn(k, v)
+		other.set(k2, v2, true)
 	}
-	return m.root.get(key, m.comparer)
-}
-
-// Set returns a copy of the map with the key set to the given value.
-func (m *SortedMap[K, V]) Set(key K, value V) *SortedMap[K, V] {
-	return m.set(key, value, false)
+	return other
 }
 
-func (m *SortedMap[K, V]) set(key K, value V, mutable bool) *SortedMap[K, V] {
-	// Set a comparer on the first value if one does not already exist.
-	comparer := m.comparer
-	if comparer == nil {
-		comparer = NewComparer(key)
+// MarshalJSON encodes the map as a JSON object with keys written in sorted
+// order. The key type must have an underlying string type, otherwise an error
+// is returned.
+func (m *SortedMap[K, V]) MarshalJSON() ([]byte, error) {
+	if err := jsonCheckKeyType[K](); err != nil {
+		return nil, err
 	}
 
-	// Create copy, if necessary.
-	other := m
-	if !mutable {
-		other = m.clone()
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		s, err := jsonKeyString(k)
+		if err != nil {
+			return nil, err
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		if err := writeJSONEntry(&buf, s, v); err != nil {
+			return nil, err
+		}
 	}
-	other.comparer = comparer
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
 
-	// If no values are set then initialize with a leaf node.
-	if m.root == nil {
-		other.size = 1
-		other.root = &sortedMapLeafNode[K, V]{entries: []mapEntry[K, V]{{key: key, value: value}}}
-		return other
+// UnmarshalJSON decodes a JSON object into the map. The key type must have an
+// underlying string type, otherwise an error is returned. The existing
+// comparer on the map, if any, is preserved.
+func (m *SortedMap[K, V]) UnmarshalJSON(data []byte) error {
+	var obj map[string]V
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
 	}
 
-	// Otherwise delegate to root node.
-	// If a split occurs then grow the tree from the root.
-	var resized bool
-	newRoot, splitNode := m.root.set(key, value, comparer, mutable, &resized)
-	if splitNode != nil {
-		newRoot = newSortedMapBranchNode(newRoot, splitNode)
+	b := NewSortedMapBuilder[K, V](m.comparer)
+	for s, v := range obj {
+		k, err := jsonParseKey[K](s)
+		if err != nil {
+			return err
+		}
+		b.Set(k, v)
 	}
+	*m = *b.Map()
+	return nil
+}
 
-	// Update root and size (if resized).
-	other.size = m.size
-	other.root = newRoot
-	if resized {
-		other.size++
+// GobEncode encodes the key/value pairs of the map using encoding/gob.
+// The comparer is not encoded.
+func (m *SortedMap[K, V]) GobEncode() ([]byte, error) {
+	keys, values := make([]K, 0, m.size), make([]V, 0, m.size)
+	for itr := m.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		keys, values = append(keys, k), append(values, v)
 	}
-	return other
+	return gobEncodeEntries(keys, values)
 }
 
-// Delete returns a copy of the map with the key removed.
-// Returns the original map if key does not exist.
-func (m *SortedMap[K, V]) Delete(key K) *SortedMap[K, V] {
-	return m.delete(key, false)
+// GobDecode decodes key/value pairs encoded by GobEncode() into the map.
+// Because comparers cannot be encoded, the existing comparer on the map is
+// used if set. Otherwise the default comparer for the key type is used.
+func (m *SortedMap[K, V]) GobDecode(data []byte) error {
+	keys, values, err := gobDecodeEntries[K, V](data)
+	if err != nil {
+		return err
+	}
+
+	b := NewSortedMapBuilder[K, V](m.comparer)
+	for i := range keys {
+		b.Set(keys[i], values[i])
+	}
+	*m = *b.Map()
+	return nil
 }
 
-func (m *SortedMap[K, V]) delete(key K, mutable bool) *SortedMap[K, V] {
-	// Return original map if no keys exist.
+// SumRange returns the sum of fn applied to the value of every entry in m with
+// a key in the range [lo, hi). Only entries within the range are visited.
+func SumRange[K, V any, N constraints.Integer | constraints.Float](m *SortedMap[K, V], lo, hi K, fn func(V) N) N {
+	var sum N
 	if m.root == nil {
-		return m
+		return sum
 	}
 
-	// If the delete did not change the node then return the original map.
-	var resized bool
-	newRoot := m.root.delete(key, m.comparer, mutable, &resized)
-	if !resized {
-		return m
+	itr := m.Iterator()
+	for itr.Seek(lo); !itr.Done(); {
+		k, v, _ := itr.Next()
+		if m.comparer.Compare(k, hi) >= 0 {
+			break
+		}
+		sum += fn(v)
 	}
+	return sum
+}
 
-	// Create copy, if necessary.
-	other := m
-	if !mutable {
-		other = m.clone()
+// SortedMapKeysSymmetricDifference returns the keys which exist in exactly one
+// of a or b, in ascending order. Both maps are walked once in O(n+m) time.
+// Both maps must be sorted by the same comparer.
+func SortedMapKeysSymmetricDifference[K, V any](a, b *SortedMap[K, V]) []K {
+	comparer := a.comparer
+	if comparer == nil {
+		comparer = b.comparer
+	}
+
+	var keys []K
+	itrA, itrB := a.Iterator(), b.Iterator()
+	ka, _, okA := itrA.Next()
+	kb, _, okB := itrB.Next()
+	for okA || okB {
+		switch {
+		case !okB || (okA && comparer.Compare(ka, kb) < 0):
+			keys = append(keys, ka)
+			ka, _, okA = itrA.Next()
+		case !okA || comparer.Compare(ka, kb) > 0:
+			keys = append(keys, kb)
+			kb, _, okB = itrB.Next()
+		default:
+			ka, _, okA = itrA.Next()
+			kb, _, okB = itrB.Next()
+		}
 	}
-
-	// Update root and size.
-	other.size = m.size - 1
-	other.root = newRoot
-	return other
+	return keys
 }
 
-// clone returns a shallow copy of m.
-func (m *SortedMap[K, V]) clone() *SortedMap[K, V] {
-	other := *m
-	return &other
+// ascendRange invokes fn for each key/value pair with a key in the range
+// [lo, hi) in ascending order. Iteration stops if fn returns false.
+func (m *SortedMap[K, V]) ascendRange(lo, hi K, fn func(key K, value V) bool) {
+	if m.root == nil || m.comparer.Compare(lo, hi) >= 0 {
+		return
+	}
+	sortedMapAscendRange(m.root, lo, hi, m.comparer, fn)
 }
 
-// Iterator returns a new iterator for this map positioned at the first key.
-func (m *SortedMap[K, V]) Iterator() *SortedMapIterator[K, V] {
-	itr := &SortedMapIterator[K, V]{m: m}
-	itr.First()
-	return itr
+// sortedMapAscendRange recursively invokes fn for each key/value pair in node
+// with a key in the range [lo, hi). Returns false once iteration should stop.
+func sortedMapAscendRange[K, V any](node sortedMapNode[K, V], lo, hi K, c Comparer[K], fn func(key K, value V) bool) bool {
+	switch node := node.(type) {
+	case *sortedMapBranchNode[K, V]:
+		start := node.indexOf(lo, c)
+		for i := start; i < len(node.elems); i++ {
+			if i > start && c.Compare(node.elems[i].key, hi) >= 0 {
+				return false
+			} else if !sortedMapAscendRange(node.elems[i].node, lo, hi, c, fn) {
+				return false
+			}
+		}
+	case *sortedMapLeafNode[K, V]:
+		for i := node.indexOf(lo, c); i < len(node.entries); i++ {
+			entry := &node.entries[i]
+			if c.Compare(entry.key, hi) >= 0 || !fn(entry.key, entry.value) {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // SortedMapBuilder represents an efficient builder for creating sorted maps.
 type SortedMapBuilder[K, V any] struct {
-	m *SortedMap[K, V] // current state
+	m      *SortedMap[K, V] // current state
+	shared bool             // true if map nodes are shared with a built map
+
+	owned map[sortedMapNode[K, V]]struct{} // nodes copied since the map became shared
 }
 
 // NewSortedMapBuilder returns a new instance of SortedMapBuilder.
@@ -1714,6 +4036,62 @@ func NewSortedMapBuilder[K, V any](comparer Comparer[K]) *SortedMapBuilder[K, V]
 	return &SortedMapBuilder[K, V]{m: NewSortedMap[K, V](comparer)}
 }
 
+// NewSortedMapBuilderFromSorted returns a new instance of SortedMapBuilder
+// containing keys mapped to the values at the same index. Keys must be in
+// strictly increasing order according to comparer.
+//
+// Since the keys are already sorted, the tree is built bottom-up from full
+// nodes in O(n) time instead of inserting each key individually. This method
+// will panic if keys and values have different lengths or if keys are not
+// strictly increasing.
+func NewSortedMapBuilderFromSorted[K, V any](comparer Comparer[K], keys []K, values []V) *SortedMapBuilder[K, V] {
+	if len(keys) != len(values) {
+		panic(fmt.Sprintf("immutable.NewSortedMapBuilderFromSorted: key count %d does not match value count %d", len(keys), len(values)))
+	}
+
+	m := NewSortedMap[K, V](comparer)
+	if len(keys) == 0 {
+		return &SortedMapBuilder[K, V]{m: m}
+	} else if m.comparer == nil {
+		m.comparer = NewComparer(keys[0])
+	}
+	for i := 1; i < len(keys); i++ {
+		if m.comparer.Compare(keys[i-1], keys[i]) >= 0 {
+			panic(fmt.Sprintf("immutable.NewSortedMapBuilderFromSorted: key at index %d not in increasing order", i))
+		}
+	}
+
+	// Split entries into full leaves.
+	nodes := make([]sortedMapNode[K, V], 0, (len(keys)+sortedMapNodeSize-1)/sortedMapNodeSize)
+	for i := 0; i < len(keys); i += sortedMapNodeSize {
+		n := sortedMapNodeSize
+		if n > len(keys)-i {
+			n = len(keys) - i
+		}
+		leaf := &sortedMapLeafNode[K, V]{entries: make([]mapEntry[K, V], n)}
+		for j := range leaf.entries {
+			leaf.entries[j] = mapEntry[K, V]{key: keys[i+j], value: values[i+j]}
+		}
+		nodes = append(nodes, leaf)
+	}
+
+	// Group each level of nodes into parent branches until the root is reached.
+	for len(nodes) > 1 {
+		parents := make([]sortedMapNode[K, V], 0, (len(nodes)+sortedMapNodeSize-1)/sortedMapNodeSize)
+		for i := 0; i < len(nodes); i += sortedMapNodeSize {
+			end := i + sortedMapNodeSize
+			if end > len(nodes) {
+				end = len(nodes)
+			}
+			parents = append(parents, newSortedMapBranchNode(nodes[i:end]...))
+		}
+		nodes = parents
+	}
+
+	m.root, m.size = nodes[0], len(keys)
+	return &SortedMapBuilder[K, V]{m: m}
+}
+
 // SortedMap returns the current copy of the map.
 // The returned map is safe to use even if after the builder continues to be used.
 func (b *SortedMapBuilder[K, V]) Map() *SortedMap[K, V] {
@@ -1723,12 +4101,72 @@ func (b *SortedMapBuilder[K, V]) Map() *SortedMap[K, V] {
 	return m
 }
 
+// Build returns the current state of the builder as an immutable map.
+// Unlike Map(), the builder remains valid after the call and later changes
+// to the builder do not affect the returned map.
+//
+// The returned map shares its nodes with the builder so later mutations only
+// copy the nodes along the path to each key they touch. Use Map() instead if
+// the builder is no longer needed.
+func (b *SortedMapBuilder[K, V]) Build() *SortedMap[K, V] {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	b.shared, b.owned = true, nil
+	return b.m.clone()
+}
+
+// Restore resets the builder to the contents of m, which is typically a map
+// previously returned by Build(). See MapBuilder.Restore() for more details.
+func (b *SortedMapBuilder[K, V]) Restore(m *SortedMap[K, V]) {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	b.m, b.shared, b.owned = m, true, nil
+}
+
+// own returns true if the map nodes are shared with a built map. See
+// MapBuilder.own() for more details.
+func (b *SortedMapBuilder[K, V]) own() bool {
+	if !b.shared {
+		return false
+	} else if b.owned == nil {
+		b.m = b.m.clone()
+		b.owned = make(map[sortedMapNode[K, V]]struct{})
+	}
+	return true
+}
+
+// set sets the value of key in-place, copying shared nodes if needed.
+func (b *SortedMapBuilder[K, V]) set(key K, value V) {
+	if b.own() {
+		b.m.setOwned(key, value, b.owned)
+		return
+	}
+	b.m = b.m.set(key, value, true)
+}
+
+// Valid returns true if the builder can still be used. A builder becomes
+// invalid once Map() has been called.
+func (b *SortedMapBuilder[K, V]) Valid() bool {
+	return b.m != nil
+}
+
 // Len returns the number of elements in the underlying map.
 func (b *SortedMapBuilder[K, V]) Len() int {
 	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
 	return b.m.Len()
 }
 
+// IsEmpty returns true if the underlying map contains no elements.
+func (b *SortedMapBuilder[K, V]) IsEmpty() bool {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	return b.m.IsEmpty()
+}
+
+// Clear removes all keys from the underlying map while keeping its comparer.
+// The builder can continue to be used afterward.
+func (b *SortedMapBuilder[K, V]) Clear() {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	b.m, b.shared, b.owned = NewSortedMap[K, V](b.m.comparer), false, nil
+}
+
 // Get returns the value for the given key.
 func (b *SortedMapBuilder[K, V]) Get(key K) (value V, ok bool) {
 	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
@@ -1738,15 +4176,52 @@ func (b *SortedMapBuilder[K, V]) Get(key K) (value V, ok bool) {
 // Set sets the value of the given key. See SortedMap.Set() for additional details.
 func (b *SortedMapBuilder[K, V]) Set(key K, value V) {
 	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
-	b.m = b.m.set(key, value, true)
+	b.set(key, value)
+}
+
+// SortedMapBuilderSetMany sets all key/value pairs in entries on the builder.
+// See SortedMapSetMany() for additional details.
+func SortedMapBuilderSetMany[K comparable, V any](b *SortedMapBuilder[K, V], entries map[K]V) {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	if len(entries) == 0 {
+		return
+	}
+	for _, k := range sortedMapKeys(b.m, entries) {
+		b.set(k, entries[k])
+	}
 }
 
 // Delete removes the given key. See SortedMap.Delete() for additional details.
 func (b *SortedMapBuilder[K, V]) Delete(key K) {
 	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	if b.own() {
+		b.m.deleteOwned(key, b.owned)
+		return
+	}
 	b.m = b.m.delete(key, true)
 }
 
+// Pop removes the given key and returns its value. Returns false if the key
+// does not exist.
+func (b *SortedMapBuilder[K, V]) Pop(key K) (value V, ok bool) {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	if value, ok = b.m.Get(key); ok {
+		b.Delete(key)
+	}
+	return value, ok
+}
+
+// DeleteRange removes all keys in the range [lo, hi).
+// See SortedMap.DeleteRange() for additional details.
+func (b *SortedMapBuilder[K, V]) DeleteRange(lo, hi K) {
+	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
+	if b.own() {
+		b.m.deleteRangeOwned(lo, hi, b.owned)
+		return
+	}
+	b.m = b.m.deleteRange(lo, hi, true)
+}
+
 // Iterator returns a new iterator for the underlying map positioned at the first key.
 func (b *SortedMapBuilder[K, V]) Iterator() *SortedMapIterator[K, V] {
 	assert(b.m != nil, "immutable.SortedMapBuilder: builder invalid after Map() invocation")
@@ -1756,6 +4231,7 @@ func (b *SortedMapBuilder[K, V]) Iterator() *SortedMapIterator[K, V] {
 // sortedMapNode represents a branch or leaf node in the sorted map.
 type sortedMapNode[K, V any] interface {
 	minKey() K
+	len() int
 	indexOf(key K, c Comparer[K]) int
 	get(key K, c Comparer[K]) (value V, ok bool)
 	set(key K, value V, c Comparer[K], mutable bool, resized *bool) (sortedMapNode[K, V], sortedMapNode[K, V])
@@ -1765,9 +4241,11 @@ type sortedMapNode[K, V any] interface {
 var _ sortedMapNode[string, any] = (*sortedMapBranchNode[string, any])(nil)
 var _ sortedMapNode[string, any] = (*sortedMapLeafNode[string, any])(nil)
 
-// sortedMapBranchNode represents a branch in the sorted map.
+// sortedMapBranchNode represents a branch in the sorted map. The number of
+// keys in the subtree is cached so positions can be located in O(log n).
 type sortedMapBranchNode[K, V any] struct {
 	elems []sortedMapBranchElem[K, V]
+	size  int // total number of keys in all child nodes
 }
 
 // newSortedMapBranchNode returns a new branch node with the given child nodes.
@@ -1781,7 +4259,17 @@ func newSortedMapBranchNode[K, V any](children ...sortedMapNode[K, V]) *sortedMa
 		}
 	}
 
-	return &sortedMapBranchNode[K, V]{elems: elems}
+	return newSortedMapBranchNodeFromElems(elems)
+}
+
+// newSortedMapBranchNodeFromElems returns a new branch node containing elems
+// with its size computed from the child nodes.
+func newSortedMapBranchNodeFromElems[K, V any](elems []sortedMapBranchElem[K, V]) *sortedMapBranchNode[K, V] {
+	n := &sortedMapBranchNode[K, V]{elems: elems}
+	for i := range elems {
+		n.size += elems[i].node.len()
+	}
+	return n
 }
 
 // minKey returns the lowest key stored in this node's tree.
@@ -1789,6 +4277,19 @@ func (n *sortedMapBranchNode[K, V]) minKey() K {
 	return n.elems[0].node.minKey()
 }
 
+// len returns the number of keys stored in this node's tree.
+func (n *sortedMapBranchNode[K, V]) len() int {
+	return n.size
+}
+
+// split divides the child nodes evenly between two new branch nodes.
+func (n *sortedMapBranchNode[K, V]) split() (*sortedMapBranchNode[K, V], *sortedMapBranchNode[K, V]) {
+	splitIdx := len(n.elems) / 2
+	newNode := newSortedMapBranchNodeFromElems(n.elems[:splitIdx:splitIdx])
+	splitNode := &sortedMapBranchNode[K, V]{elems: n.elems[splitIdx:], size: n.size - newNode.size}
+	return newNode, splitNode
+}
+
 // indexOf returns the index of the key within the child nodes.
 func (n *sortedMapBranchNode[K, V]) indexOf(key K, c Comparer[K]) int {
 	if idx := sort.Search(len(n.elems), func(i int) bool { return c.Compare(n.elems[i].key, key) == 1 }); idx > 0 {
@@ -1818,20 +4319,23 @@ func (n *sortedMapBranchNode[K, V]) set(key K, value V, c Comparer[K], mutable b
 			copy(n.elems[idx+1:], n.elems[idx:])
 			n.elems[idx+1] = sortedMapBranchElem[K, V]{key: splitNode.minKey(), node: splitNode}
 		}
+		if *resized {
+			n.size++
+		}
 
 		// If the child splits and we have no more room then we split too.
 		if len(n.elems) > sortedMapNodeSize {
-			splitIdx := len(n.elems) / 2
-			newNode := &sortedMapBranchNode[K, V]{elems: n.elems[:splitIdx:splitIdx]}
-			splitNode := &sortedMapBranchNode[K, V]{elems: n.elems[splitIdx:]}
-			return newNode, splitNode
+			return n.split()
 		}
 		return n, nil
 	}
 
 	// If no split occurs, copy branch and update keys.
 	// If the child splits, insert new key/child into copy of branch.
-	var other sortedMapBranchNode[K, V]
+	other := sortedMapBranchNode[K, V]{size: n.size}
+	if *resized {
+		other.size++
+	}
 	if splitNode == nil {
 		other.elems = make([]sortedMapBranchElem[K, V], len(n.elems))
 		copy(other.elems, n.elems)
@@ -1855,10 +4359,7 @@ func (n *sortedMapBranchNode[K, V]) set(key K, value V, c Comparer[K], mutable b
 
 	// If the child splits and we have no more room then we split too.
 	if len(other.elems) > sortedMapNodeSize {
-		splitIdx := len(other.elems) / 2
-		newNode := &sortedMapBranchNode[K, V]{elems: other.elems[:splitIdx:splitIdx]}
-		splitNode := &sortedMapBranchNode[K, V]{elems: other.elems[splitIdx:]}
-		return newNode, splitNode
+		return other.split()
 	}
 
 	// Otherwise return the new branch node with the updated entry.
@@ -1888,11 +4389,12 @@ func (n *sortedMapBranchNode[K, V]) delete(key K, c Comparer[K], mutable bool, r
 			copy(n.elems[idx:], n.elems[idx+1:])
 			n.elems[len(n.elems)-1] = sortedMapBranchElem[K, V]{}
 			n.elems = n.elems[:len(n.elems)-1]
+			n.size--
 			return n
 		}
 
 		// Return a copy without the given node.
-		other := &sortedMapBranchNode[K, V]{elems: make([]sortedMapBranchElem[K, V], len(n.elems)-1)}
+		other := &sortedMapBranchNode[K, V]{elems: make([]sortedMapBranchElem[K, V], len(n.elems)-1), size: n.size - 1}
 		copy(other.elems[:idx], n.elems[:idx])
 		copy(other.elems[idx:], n.elems[idx+1:])
 		return other
@@ -1901,11 +4403,12 @@ func (n *sortedMapBranchNode[K, V]) delete(key K, c Comparer[K], mutable bool, r
 	// If mutable, update in-place.
 	if mutable {
 		n.elems[idx] = sortedMapBranchElem[K, V]{key: newNode.minKey(), node: newNode}
+		n.size--
 		return n
 	}
 
 	// Return a copy with the updated node.
-	other := &sortedMapBranchNode[K, V]{elems: make([]sortedMapBranchElem[K, V], len(n.elems))}
+	other := &sortedMapBranchNode[K, V]{elems: make([]sortedMapBranchElem[K, V], len(n.elems)), size: n.size - 1}
 	copy(other.elems, n.elems)
 	other.elems[idx] = sortedMapBranchElem[K, V]{
 		key:  newNode.minKey(),
@@ -1929,6 +4432,11 @@ func (n *sortedMapLeafNode[K, V]) minKey() K {
 	return n.entries[0].key
 }
 
+// len returns the number of keys stored in this node.
+func (n *sortedMapLeafNode[K, V]) len() int {
+	return len(n.entries)
+}
+
 // indexOf returns the index of the given key.
 func (n *sortedMapLeafNode[K, V]) indexOf(key K, c Comparer[K]) int {
 	return sort.Search(len(n.entries), func(i int) bool {
@@ -2040,6 +4548,17 @@ type SortedMapIterator[K, V any] struct {
 
 	stack [32]sortedMapIteratorElem[K, V] // search stack
 	depth int                             // stack depth
+
+	index int // rank of the current position
+	pos   int // rank of the last returned entry, or -1
+
+	bounds *sortedMapBounds[K] // key range, if bounded
+}
+
+// sortedMapBounds represents the key range of a bounded SortedMapIterator.
+type sortedMapBounds[K any] struct {
+	lo, hi                   K
+	loInclusive, hiInclusive bool
 }
 
 // Done returns true if no more key/value pairs remain in the iterator.
@@ -2049,37 +4568,152 @@ func (itr *SortedMapIterator[K, V]) Done() bool {
 
 // First moves the iterator to the first key/value pair.
 func (itr *SortedMapIterator[K, V]) First() {
+	itr.index, itr.pos = 0, -1
 	if itr.m.root == nil {
 		itr.depth = -1
 		return
 	}
 	itr.stack[0] = sortedMapIteratorElem[K, V]{node: itr.m.root}
 	itr.depth = 0
+
+	// Move to the lower bound of a range iterator.
+	if b := itr.bounds; b != nil {
+		itr.seek(b.lo)
+		if !b.loInclusive && !itr.Done() && itr.m.comparer.Compare(itr.key(), b.lo) == 0 {
+			itr.next()
+		}
+		itr.checkHi()
+		itr.index = itr.rank()
+		return
+	}
 	itr.first()
 }
 
 // Last moves the iterator to the last key/value pair.
 func (itr *SortedMapIterator[K, V]) Last() {
+	itr.index, itr.pos = itr.m.Len()-1, -1
 	if itr.m.root == nil {
 		itr.depth = -1
 		return
 	}
 	itr.stack[0] = sortedMapIteratorElem[K, V]{node: itr.m.root}
 	itr.depth = 0
+
+	// Move to the upper bound of a range iterator.
+	if b := itr.bounds; b != nil {
+		itr.seek(b.hi)
+		if itr.Done() {
+			itr.stack[0] = sortedMapIteratorElem[K, V]{node: itr.m.root}
+			itr.depth = 0
+			itr.last()
+		} else if cmp := itr.m.comparer.Compare(itr.key(), b.hi); cmp > 0 || (cmp == 0 && !b.hiInclusive) {
+			itr.prev()
+		}
+		itr.checkLo()
+		itr.index = itr.rank()
+		return
+	}
 	itr.last()
 }
 
 // Seek moves the iterator position to the given key in the map.
 // If the key does not exist then the next key is used. If no more keys exist
 // then the iteartor is marked as done.
+//
+// For range iterators, seeking before the lower bound moves the iterator to
+// the first key in the range.
 func (itr *SortedMapIterator[K, V]) Seek(key K) {
+	itr.pos = -1
 	if itr.m.root == nil {
-		itr.depth = -1
+		itr.index, itr.depth = 0, -1
+		return
+	}
+
+	if b := itr.bounds; b != nil {
+		if cmp := itr.m.comparer.Compare(key, b.lo); cmp < 0 || (cmp == 0 && !b.loInclusive) {
+			itr.First()
+			return
+		}
+	}
+
+	itr.stack[0] = sortedMapIteratorElem[K, V]{node: itr.m.root}
+	itr.depth = 0
+	itr.seek(key)
+	itr.checkHi()
+	itr.index = itr.rank()
+}
+
+// SeekReverse moves the iterator position to the given key in the map for
+// iteration with Prev(). If the key does not exist then the previous key is
+// used. If no keys exist at or before key then the iterator is marked as done.
+//
+// For range iterators, seeking after the upper bound moves the iterator to
+// the last key in the range.
+func (itr *SortedMapIterator[K, V]) SeekReverse(key K) {
+	itr.pos = -1
+	if itr.m.root == nil {
+		itr.index, itr.depth = -1, -1
 		return
 	}
+
+	if b := itr.bounds; b != nil {
+		if cmp := itr.m.comparer.Compare(key, b.hi); cmp > 0 || (cmp == 0 && !b.hiInclusive) {
+			itr.Last()
+			return
+		}
+	}
+
+	// Seek to the smallest key greater than or equal to key and then step
+	// back if it is not an exact match.
 	itr.stack[0] = sortedMapIteratorElem[K, V]{node: itr.m.root}
 	itr.depth = 0
 	itr.seek(key)
+	if itr.Done() {
+		itr.stack[0] = sortedMapIteratorElem[K, V]{node: itr.m.root}
+		itr.depth = 0
+		itr.last()
+	} else if itr.m.comparer.Compare(itr.key(), key) > 0 {
+		itr.prev()
+	}
+	itr.checkLo()
+	itr.index = itr.rank()
+}
+
+// SeekRange restricts the iterator to keys between lo and hi, inclusive, and
+// moves it to the first key in that range. Any previous range is replaced.
+// Iteration with Next() is done once keys exceed hi. Use RangeIterator() on
+// the map for exclusive bounds.
+func (itr *SortedMapIterator[K, V]) SeekRange(lo, hi K) {
+	itr.bounds = &sortedMapBounds[K]{
+		lo:          lo,
+		hi:          hi,
+		loInclusive: true,
+		hiInclusive: true,
+	}
+	itr.First()
+}
+
+// Remaining returns the number of key/value pairs that will be returned by
+// calls to Next() before the iterator is done. For range iterators, only
+// pairs up to the upper bound are counted.
+func (itr *SortedMapIterator[K, V]) Remaining() int {
+	if itr.Done() {
+		return 0
+	} else if itr.bounds == nil {
+		return itr.m.Len() - itr.index
+	}
+
+	// Find the rank of the last key within the range.
+	end := *itr
+	end.Last()
+	return end.index - itr.index + 1
+}
+
+// Position returns the 0-based rank of the entry most recently returned by
+// Next() or Prev(). Returns -1 if no entry has been returned since the
+// iterator was last positioned by First(), Last(), or Seek().
+func (itr *SortedMapIterator[K, V]) Position() int {
+	return itr.pos
 }
 
 // Next returns the current key/value pair and moves the iterator forward.
@@ -2095,9 +4729,12 @@ func (itr *SortedMapIterator[K, V]) Next() (key K, value V, ok bool) {
 	leafNode := leafElem.node.(*sortedMapLeafNode[K, V])
 	leafEntry := &leafNode.entries[leafElem.index]
 	key, value = leafEntry.key, leafEntry.value
+	itr.pos = itr.index
+	itr.index++
 
 	// Move to the next available key/value pair.
 	itr.next()
+	itr.checkHi()
 
 	// Only occurs when iterator is done.
 	return key, value, true
@@ -2139,8 +4776,11 @@ func (itr *SortedMapIterator[K, V]) Prev() (key K, value V, ok bool) {
 	leafNode := leafElem.node.(*sortedMapLeafNode[K, V])
 	leafEntry := &leafNode.entries[leafElem.index]
 	key, value = leafEntry.key, leafEntry.value
+	itr.pos = itr.index
+	itr.index--
 
 	itr.prev()
+	itr.checkLo()
 	return key, value, true
 }
 
@@ -2222,6 +4862,52 @@ func (itr *SortedMapIterator[K, V]) seek(key K) {
 	}
 }
 
+// key returns the key at the current position. Iterator must not be done.
+func (itr *SortedMapIterator[K, V]) key() K {
+	elem := &itr.stack[itr.depth]
+	return elem.node.(*sortedMapLeafNode[K, V]).entries[elem.index].key
+}
+
+// checkHi marks the iterator as done if it is positioned above the upper bound.
+func (itr *SortedMapIterator[K, V]) checkHi() {
+	if b := itr.bounds; b != nil && !itr.Done() {
+		if cmp := itr.m.comparer.Compare(itr.key(), b.hi); cmp > 0 || (cmp == 0 && !b.hiInclusive) {
+			itr.depth = -1
+		}
+	}
+}
+
+// checkLo marks the iterator as done if it is positioned below the lower bound.
+func (itr *SortedMapIterator[K, V]) checkLo() {
+	if b := itr.bounds; b != nil && !itr.Done() {
+		if cmp := itr.m.comparer.Compare(itr.key(), b.lo); cmp < 0 || (cmp == 0 && !b.loInclusive) {
+			itr.depth = -1
+		}
+	}
+}
+
+// rank returns the number of keys before the current position of the stack.
+// Returns the size of the map if the iterator is done.
+func (itr *SortedMapIterator[K, V]) rank() int {
+	if itr.Done() {
+		return itr.m.Len()
+	}
+
+	var n int
+	for i := 0; i <= itr.depth; i++ {
+		elem := &itr.stack[i]
+		switch node := elem.node.(type) {
+		case *sortedMapBranchNode[K, V]:
+			for j := 0; j < elem.index; j++ {
+				n += node.elems[j].node.len()
+			}
+		case *sortedMapLeafNode[K, V]:
+			n += elem.index
+		}
+	}
+	return n
+}
+
 // sortedMapIteratorElem represents node/index pair in the SortedMapIterator stack.
 type sortedMapIteratorElem[K, V any] struct {
 	node  sortedMapNode[K, V]
@@ -2243,6 +4929,8 @@ func NewHasher[K any](key K) Hasher[K] {
 	switch (any(key)).(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, string:
 		return &defaultHasher[K]{}
+	case []byte:
+		return any(&bytesHasher{}).(Hasher[K])
 	}
 
 	// Fallback to reflection-based hasher otherwise.
@@ -2257,6 +4945,64 @@ func NewHasher[K any](key K) Hasher[K] {
 	panic(fmt.Sprintf("immutable.NewHasher: must set hasher for %T type", key))
 }
 
+// HasherFunc returns a hasher which uses the given hash and equality
+// functions. Keys which are equal must produce the same hash.
+func HasherFunc[K any](hash func(key K) uint32, equal func(a, b K) bool) Hasher[K] {
+	return &funcHasher[K]{hash: hash, equal: equal}
+}
+
+// funcHasher implements Hasher using functions.
+type funcHasher[K any] struct {
+	hash  func(key K) uint32
+	equal func(a, b K) bool
+}
+
+// Hash returns a hash for key.
+func (h *funcHasher[K]) Hash(key K) uint32 {
+	return h.hash(key)
+}
+
+// Equal returns true if a is equal to b. Otherwise returns false.
+func (h *funcHasher[K]) Equal(a, b K) bool {
+	return h.equal(a, b)
+}
+
+// UnsupportedKeyTypeError is returned when a default hasher or comparer does
+// not exist for a key type.
+type UnsupportedKeyTypeError struct {
+	Type reflect.Type // key type
+	Op   string       // "hasher" or "comparer"
+}
+
+// Error returns the error message.
+func (e *UnsupportedKeyTypeError) Error() string {
+	return fmt.Sprintf("immutable: no default %s for %s key type", e.Op, e.Type)
+}
+
+// keyType returns the static type of K.
+func keyType[K any]() reflect.Type {
+	return reflect.TypeOf((*K)(nil)).Elem()
+}
+
+// hasDefaultHasher returns true if NewHasher() supports the type of key.
+func hasDefaultHasher[K any](key K) bool {
+	switch any(key).(type) {
+	case []byte:
+		return true
+	}
+	switch keyType[K]().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.String:
+		return true
+	}
+	return false
+}
+
+// hasDefaultComparer returns true if NewComparer() supports the type of key.
+func hasDefaultComparer[K any](key K) bool {
+	// Default comparers currently exist for the same types as default hashers.
+	return hasDefaultHasher(key)
+}
+
 // Hash returns a hash for value.
 func hashString(value string) uint32 {
 	var hash uint32
@@ -2352,6 +5098,24 @@ func (h *defaultHasher[K]) Equal(a, b K) bool {
 	return any(a) == any(b)
 }
 
+// bytesHasher implements Hasher for byte slice keys.
+type bytesHasher struct{}
+
+// Hash returns an FNV-1a hash for key.
+func (h *bytesHasher) Hash(key []byte) uint32 {
+	hash := uint32(2166136261)
+	for _, b := range key {
+		hash ^= uint32(b)
+		hash *= 16777619
+	}
+	return hash
+}
+
+// Equal returns true if a and b contain the same bytes.
+func (h *bytesHasher) Equal(a, b []byte) bool {
+	return bytes.Equal(a, b)
+}
+
 // Comparer allows the comparison of two keys for the purpose of sorting.
 type Comparer[K any] interface {
 	// Returns -1 if a is less than b, returns 1 if a is greater than b,
@@ -2360,13 +5124,15 @@ type Comparer[K any] interface {
 }
 
 // NewComparer returns the built-in comparer for a given key type.
-// Note that only int-ish and string-ish types are supported, despite the 'comparable' constraint.
+// Note that only int-ish, string-ish, and byte slice types are supported, despite the 'comparable' constraint.
 // Attempts to use other types will result in a panic - users should define their own Comparers for these cases.
 func NewComparer[K any](key K) Comparer[K] {
 	// Attempt to use non-reflection based comparer first.
 	switch (any(key)).(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, string:
 		return &defaultComparer[K]{}
+	case []byte:
+		return any(&bytesComparer{}).(Comparer[K])
 	}
 	// Fallback to reflection-based comparer otherwise.
 	// This is used when caller wraps a type around a primitive type.
@@ -2452,6 +5218,156 @@ func (c *reflectComparer[K]) Compare(a, b K) int {
 	panic(fmt.Sprintf("immutable.reflectComparer.Compare: must set comparer for %T type", a))
 }
 
+// bytesComparer compares byte slice keys bytewise. Implements Comparer.
+type bytesComparer struct{}
+
+// Compare returns -1 if a is less than b, returns 1 if a is greater than b,
+// and returns 0 if a is equal to b.
+func (c *bytesComparer) Compare(a, b []byte) int {
+	return bytes.Compare(a, b)
+}
+
+// FloatComparer returns a comparer for floating-point keys. Floats do not have
+// a default comparer because NaN is unordered, so this comparer sorts every
+// NaN value after all other values and treats NaN values as equal to each
+// other. Negative and positive zero are treated as equal.
+func FloatComparer[K constraints.Float]() Comparer[K] {
+	return &floatComparer[K]{}
+}
+
+// floatComparer compares floating-point keys. Implements Comparer.
+type floatComparer[K constraints.Float] struct{}
+
+// Compare returns -1 if a is less than b, returns 1 if a is greater than b,
+// and returns 0 if a is equal to b. NaN is greater than all other values.
+func (c *floatComparer[K]) Compare(a, b K) int {
+	if aNaN, bNaN := a != a, b != b; aNaN || bNaN {
+		switch {
+		case aNaN && bNaN:
+			return 0
+		case aNaN:
+			return 1
+		default:
+			return -1
+		}
+	}
+	return defaultCompare(a, b)
+}
+
+// ComparerFunc returns a comparer derived from a less function, such as one
+// passed to sort.Slice(). The less function must define a strict weak
+// ordering: a key is never less than itself and two keys are considered equal
+// if neither is less than the other.
+func ComparerFunc[K any](less func(a, b K) bool) Comparer[K] {
+	return &lessComparer[K]{less: less}
+}
+
+// lessComparer compares keys using a less function. Implements Comparer.
+type lessComparer[K any] struct {
+	less func(a, b K) bool
+}
+
+// Compare returns -1 if a is less than b, returns 1 if b is less than a, and
+// returns 0 otherwise.
+func (c *lessComparer[K]) Compare(a, b K) int {
+	if c.less(a, b) {
+		return -1
+	} else if c.less(b, a) {
+		return 1
+	}
+	return 0
+}
+
+// ReverseComparer returns a comparer which orders keys in the opposite order
+// of c. This can be used to iterate a sorted map or set from largest to
+// smallest key.
+func ReverseComparer[K any](c Comparer[K]) Comparer[K] {
+	return &reverseComparer[K]{c: c}
+}
+
+// reverseComparer inverts the result of another comparer. Implements Comparer.
+type reverseComparer[K any] struct {
+	c Comparer[K]
+}
+
+// Compare returns -1 if a is greater than b, returns 1 if a is less than b,
+// and returns 0 if a is equal to b according to the underlying comparer.
+func (c *reverseComparer[K]) Compare(a, b K) int {
+	return c.c.Compare(b, a)
+}
+
+// gobEncodeEntries encodes aligned key & value slices using encoding/gob.
+func gobEncodeEntries[K, V any](keys []K, values []V) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(keys); err != nil {
+		return nil, err
+	} else if err := enc.Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gobDecodeEntries decodes key & value slices encoded by gobEncodeEntries().
+func gobDecodeEntries[K, V any](data []byte) (keys []K, values []V, err error) {
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&keys); err != nil {
+		return nil, nil, err
+	} else if err := dec.Decode(&values); err != nil {
+		return nil, nil, err
+	} else if len(keys) != len(values) {
+		return nil, nil, fmt.Errorf("immutable: gob key/value count mismatch: %d != %d", len(keys), len(values))
+	}
+	return keys, values, nil
+}
+
+// jsonKeyString returns the string form of key for use as a JSON object key.
+// Returns an error if key does not have an underlying string type.
+func jsonKeyString[K any](key K) (string, error) {
+	if v := reflect.ValueOf(key); v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("immutable: cannot encode %T key as JSON object key", key)
+}
+
+// jsonCheckKeyType returns an error if keys of type K can never be encoded as
+// JSON object keys. Interface key types are checked by jsonKeyString() instead.
+func jsonCheckKeyType[K any]() error {
+	typ := keyType[K]()
+	if kind := typ.Kind(); kind != reflect.String && kind != reflect.Interface {
+		return fmt.Errorf("immutable: cannot encode %s key as JSON object key", typ)
+	}
+	return nil
+}
+
+// writeJSONEntry writes a single "key":value object entry to buf.
+func writeJSONEntry[V any](buf *bytes.Buffer, key string, value V) error {
+	kbuf, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	vbuf, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(kbuf)
+	buf.WriteByte(':')
+	buf.Write(vbuf)
+	return nil
+}
+
+// jsonParseKey returns s as a key of type K. Returns an error if K does not
+// have an underlying string type.
+func jsonParseKey[K any](s string) (key K, err error) {
+	v := reflect.ValueOf(&key).Elem()
+	if v.Kind() != reflect.String {
+		return key, fmt.Errorf("immutable: cannot decode JSON object key into %s key", v.Type())
+	}
+	v.SetString(s)
+	return key, nil
+}
+
 func assert(condition bool, message string) {
 	if !condition {
 		panic(message)
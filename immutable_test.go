@@ -1,10 +1,19 @@
 package immutable
 
 import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"math"
 	"math/rand"
+	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"golang.org/x/exp/constraints"
@@ -141,6 +150,28 @@ func TestList(t *testing.T) {
 		}
 	})
 
+	t.Run("SliceFromOutOfRange", func(t *testing.T) {
+		var r string
+		func() {
+			defer func() { r = recover().(string) }()
+			NewList("foo").SliceFrom(2)
+		}()
+		if r != `immutable.List.Slice: start index 2 out of bounds` {
+			t.Fatalf("unexpected panic: %q", r)
+		}
+	})
+
+	t.Run("SliceToOutOfRange", func(t *testing.T) {
+		var r string
+		func() {
+			defer func() { r = recover().(string) }()
+			NewList("foo").SliceTo(2)
+		}()
+		if r != `immutable.List.Slice: end index 2 out of bounds` {
+			t.Fatalf("unexpected panic: %q", r)
+		}
+	})
+
 	t.Run("SliceInvalidIndex", func(t *testing.T) {
 		var r string
 		func() {
@@ -244,10 +275,20 @@ func TestList(t *testing.T) {
 			case rnd == 0: // slice
 				start, end := l.ChooseSliceIndices(rand)
 				l.Slice(start, end)
+			case rnd == 1: // append slice
+				l.AppendSlice(randomInts(rand, rand.Intn(100)))
+			case rnd == 2: // prepend slice
+				l.PrependSlice(randomInts(rand, rand.Intn(100)))
 			case rnd < 10: // set
 				if l.Len() > 0 {
 					l.Set(l.ChooseIndex(rand), rand.Intn(10000))
 				}
+			case rnd < 12: // insert
+				l.InsertAt(rand.Intn(l.Len()+1), rand.Intn(10000))
+			case rnd < 14: // remove
+				if l.Len() > 0 {
+					l.RemoveAt(l.ChooseIndex(rand))
+				}
 			case rnd < 30: // prepend
 				l.Prepend(rand.Intn(10000))
 			default: // append
@@ -314,6 +355,24 @@ func (l *TList) Prepend(v int) {
 	l.std = append([]int{v}, l.std...)
 }
 
+// AppendSlice adds vs to the end of the slice and List.
+func (l *TList) AppendSlice(vs []int) {
+	l.prev = l.im
+	l.im = l.im.AppendAll(vs...)
+	l.builder.AppendSlice(vs...)
+	l.std = append(l.std, vs...)
+}
+
+// PrependSlice adds vs to the beginning of the slice and List.
+func (l *TList) PrependSlice(vs []int) {
+	l.prev = l.im
+	for i := len(vs) - 1; i >= 0; i-- {
+		l.im = l.im.Prepend(vs[i])
+	}
+	l.builder.PrependSlice(vs...)
+	l.std = append(append([]int{}, vs...), l.std...)
+}
+
 // Set updates the value at index i to v in the slice and List.
 func (l *TList) Set(i, v int) {
 	l.prev = l.im
@@ -322,6 +381,22 @@ func (l *TList) Set(i, v int) {
 	l.std[i] = v
 }
 
+// InsertAt inserts v at index i in the slice and List.
+func (l *TList) InsertAt(i, v int) {
+	l.prev = l.im
+	l.im = l.im.InsertAt(i, v)
+	l.builder.InsertAt(i, v)
+	l.std = append(l.std[:i], append([]int{v}, l.std[i:]...)...)
+}
+
+// RemoveAt removes the element at index i from the slice and List.
+func (l *TList) RemoveAt(i int) {
+	l.prev = l.im
+	l.im = l.im.RemoveAt(i)
+	l.builder.RemoveAt(i)
+	l.std = append(l.std[:i], l.std[i+1:]...)
+}
+
 // Slice contracts the slice and List to the range of start/end indices.
 func (l *TList) Slice(start, end int) {
 	l.prev = l.im
@@ -346,6 +421,12 @@ func (l *TList) Validate() error {
 		}
 	}
 
+	if err := l.im.Validate(); err != nil {
+		return err
+	} else if err := l.builder.list.Validate(); err != nil {
+		return fmt.Errorf("builder: %w", err)
+	}
+
 	if err := l.validateForwardIterator("basic", l.im.Iterator()); err != nil {
 		return err
 	} else if err := l.validateBackwardIterator("basic", l.im.Iterator()); err != nil {
@@ -395,173 +476,1447 @@ func (l *TList) validateBackwardIterator(typ string, itr *ListIterator[int]) err
 	return nil
 }
 
-func BenchmarkList_Append(b *testing.B) {
-	b.ReportAllocs()
-	l := NewList[int]()
-	for i := 0; i < b.N; i++ {
-		l = l.Append(i)
+func TestCountDistinct(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		if n := CountDistinct(NewList[int](), nil); n != 0 {
+			t.Fatalf("CountDistinct()=%d, expected 0", n)
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		l := NewList(1, 2, 2, 3, 1, 3, 3, 4)
+		if n := CountDistinct(l, nil); n != 4 {
+			t.Fatalf("CountDistinct()=%d, expected 4", n)
+		}
+	})
+
+	t.Run("By", func(t *testing.T) {
+		l := NewList([]string{"a"}, []string{"b", "c"}, []string{"d"})
+		if n := CountDistinctBy(l, nil, func(v []string) int { return len(v) }); n != 2 {
+			t.Fatalf("CountDistinctBy()=%d, expected 2", n)
+		}
+	})
+}
+
+func TestMultisetEqual(t *testing.T) {
+	if !MultisetEqual(NewList[int](), NewList[int](), nil) {
+		t.Fatal("expected empty lists to be equal")
+	} else if !MultisetEqual(NewList(1, 2, 2, 3), NewList(2, 3, 1, 2), nil) {
+		t.Fatal("expected lists to be equal")
+	} else if MultisetEqual(NewList(1, 2, 2, 3), NewList(1, 2, 3, 3), nil) {
+		t.Fatal("expected lists with different multiplicities to be unequal")
+	} else if MultisetEqual(NewList(1, 2), NewList(1, 2, 2), nil) {
+		t.Fatal("expected lists with different lengths to be unequal")
 	}
 }
 
-func BenchmarkList_Prepend(b *testing.B) {
-	b.ReportAllocs()
-	l := NewList[int]()
-	for i := 0; i < b.N; i++ {
-		l = l.Prepend(i)
+func TestCommonPrefixLen(t *testing.T) {
+	equal := func(a, b string) bool { return a == b }
+	for _, tt := range []struct {
+		a, b []string
+		exp  int
+	}{
+		{nil, nil, 0},
+		{[]string{"usr"}, nil, 0},
+		{[]string{"usr", "local", "bin"}, []string{"usr", "local", "lib"}, 2},
+		{[]string{"usr", "local"}, []string{"usr", "local", "lib"}, 2},
+		{[]string{"usr", "local"}, []string{"usr", "local"}, 2},
+		{[]string{"etc"}, []string{"usr"}, 0},
+	} {
+		if n := CommonPrefixLen(NewList(tt.a...), NewList(tt.b...), equal); n != tt.exp {
+			t.Fatalf("CommonPrefixLen(%v, %v)=%d, expected %d", tt.a, tt.b, n, tt.exp)
+		}
 	}
 }
 
-func BenchmarkList_Set(b *testing.B) {
-	const n = 10000
+func TestMapListE(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		other, err := MapListE(NewList(1, 2, 3), func(v int) (string, error) {
+			return fmt.Sprint(v * 10), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, exp := other.Len(), 3; got != exp {
+			t.Fatalf("Len()=%d, expected %d", got, exp)
+		}
+		for i, exp := range []string{"10", "20", "30"} {
+			if got := other.Get(i); got != exp {
+				t.Fatalf("Get(%d)=%q, expected %q", i, got, exp)
+			}
+		}
+	})
 
-	l := NewList[int]()
-	for i := 0; i < 10000; i++ {
-		l = l.Append(i)
-	}
-	b.ReportAllocs()
-	b.ResetTimer()
+	t.Run("Error", func(t *testing.T) {
+		var n int
+		other, err := MapListE(NewList(1, 2, 3), func(v int) (int, error) {
+			if n++; v == 2 {
+				return 0, fmt.Errorf("marker")
+			}
+			return v, nil
+		})
+		if err == nil || err.Error() != "marker" {
+			t.Fatalf("unexpected error: %v", err)
+		} else if other != nil {
+			t.Fatal("expected nil list")
+		} else if n != 2 {
+			t.Fatalf("fn called %d times, expected 2", n)
+		}
+	})
+}
 
-	for i := 0; i < b.N; i++ {
-		l = l.Set(i%n, i*10)
+func TestList_Shared(t *testing.T) {
+	t.Run("Append", func(t *testing.T) {
+		l := NewList[int]()
+		for i := 0; i < 2000; i++ {
+			var n int
+			if l, n = l.AppendShared(i); n != int(l.root.depth())+1 {
+				t.Fatalf("%d. AppendShared()=%d, expected %d", i, n, l.root.depth()+1)
+			}
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		l := NewList[int]()
+		for i := 0; i < 2000; i++ {
+			l = l.Append(i)
+		}
+		other, n := l.SetShared(1000, -1)
+		if exp := int(l.root.depth()) + 1; n != exp {
+			t.Fatalf("SetShared()=%d, expected %d", n, exp)
+		} else if v := other.Get(1000); v != -1 {
+			t.Fatalf("Get()=%d, expected -1", v)
+		} else if v := l.Get(1000); v != 1000 {
+			t.Fatalf("original mutated: %d", v)
+		}
+	})
+}
+
+func TestList_FirstLast(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		l := NewList[int]()
+		if v, ok := l.First(); ok {
+			t.Fatalf("First()=<%v,%v>, expected no value", v, ok)
+		} else if v, ok := l.Last(); ok {
+			t.Fatalf("Last()=<%v,%v>, expected no value", v, ok)
+		}
+	})
+
+	t.Run("Sliced", func(t *testing.T) {
+		l := NewList[int]()
+		for i := 0; i < 1000; i++ {
+			l = l.Prepend(i)
+		}
+		l = l.Slice(100, 900)
+		if v, ok := l.First(); !ok || v != 899 {
+			t.Fatalf("First()=<%v,%v>, expected <899,true>", v, ok)
+		} else if v, ok := l.Last(); !ok || v != 100 {
+			t.Fatalf("Last()=<%v,%v>, expected <100,true>", v, ok)
+		}
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		b := NewListBuilder[string]()
+		if _, ok := b.First(); ok {
+			t.Fatal("expected no value")
+		}
+		b.Append("foo")
+		b.Append("bar")
+		if v, ok := b.First(); !ok || v != "foo" {
+			t.Fatalf("First()=<%v,%v>, expected <foo,true>", v, ok)
+		} else if v, ok := b.Last(); !ok || v != "bar" {
+			t.Fatalf("Last()=<%v,%v>, expected <bar,true>", v, ok)
+		}
+	})
+}
+
+func TestList_Pop(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		l := NewList[int]()
+		if v, other, ok := l.PopFirst(); ok || other != l {
+			t.Fatalf("PopFirst()=<%v,%v>, expected no value", v, ok)
+		} else if v, other, ok := l.PopLast(); ok || other != l {
+			t.Fatalf("PopLast()=<%v,%v>, expected no value", v, ok)
+		}
+	})
+
+	t.Run("Deque", func(t *testing.T) {
+		l := NewList(0, 1, 2, 3, 4)
+		v, other, ok := l.PopFirst()
+		if !ok || v != 0 {
+			t.Fatalf("PopFirst()=<%v,%v>, expected <0,true>", v, ok)
+		}
+		v, other, ok = other.PopLast()
+		if !ok || v != 4 {
+			t.Fatalf("PopLast()=<%v,%v>, expected <4,true>", v, ok)
+		}
+		if other.Len() != 3 || other.Get(0) != 1 || other.Get(2) != 3 {
+			t.Fatalf("unexpected list: len=%d", other.Len())
+		} else if l.Len() != 5 {
+			t.Fatal("original list mutated")
+		}
+	})
+}
+
+func TestList_Equal(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	l := NewList(1, 2, 3)
+
+	if !l.Equal(l, eq) {
+		t.Fatal("expected list to equal itself")
+	} else if !l.Equal(NewList(1, 2, 3), eq) {
+		t.Fatal("expected lists to be equal")
+	} else if l.Equal(NewList(1, 2), eq) {
+		t.Fatal("expected lists with different lengths to be unequal")
+	} else if l.Equal(l.Set(1, 4), eq) {
+		t.Fatal("expected lists with different elements to be unequal")
+	} else if l.Slice(0, 2).Equal(l.Slice(1, 3), eq) {
+		t.Fatal("expected slices with different elements to be unequal")
+	} else if !NewList[int]().Equal(NewList[int](), eq) {
+		t.Fatal("expected empty lists to be equal")
 	}
 }
 
-func BenchmarkList_Iterator(b *testing.B) {
-	const n = 10000
-	l := NewList[int]()
-	for i := 0; i < 10000; i++ {
-		l = l.Append(i)
+func TestList_IndexOf(t *testing.T) {
+	equal := func(a, b string) bool { return a == b }
+	l := NewList("foo", "bar", "baz", "bar")
+
+	if i := l.IndexOf("bar", equal); i != 1 {
+		t.Fatalf("IndexOf()=%d, expected 1", i)
+	} else if i := l.LastIndexOf("bar", equal); i != 3 {
+		t.Fatalf("LastIndexOf()=%d, expected 3", i)
+	} else if i := l.IndexOf("bat", equal); i != -1 {
+		t.Fatalf("IndexOf()=%d, expected -1", i)
+	} else if i := l.LastIndexOf("bat", equal); i != -1 {
+		t.Fatalf("LastIndexOf()=%d, expected -1", i)
+	} else if !l.Contains("baz", equal) {
+		t.Fatal("expected list to contain value")
+	} else if l.Contains("bat", equal) {
+		t.Fatal("expected list to not contain value")
+	} else if NewList[string]().Contains("foo", equal) {
+		t.Fatal("expected empty list to not contain value")
 	}
-	b.ReportAllocs()
-	b.ResetTimer()
+}
 
-	b.Run("Forward", func(b *testing.B) {
-		itr := l.Iterator()
-		for i := 0; i < b.N; i++ {
-			if i%n == 0 {
-				itr.First()
+func TestList_Concat(t *testing.T) {
+	newIntList := func(start, end int) *List[int] {
+		l := NewList[int]()
+		for i := start; i < end; i++ {
+			l = l.Append(i)
+		}
+		return l
+	}
+	validate := func(t *testing.T, l *List[int], n int) {
+		t.Helper()
+		if l.Len() != n {
+			t.Fatalf("Len()=%d, expected %d", l.Len(), n)
+		}
+		for i := 0; i < n; i++ {
+			if got := l.Get(i); got != i {
+				t.Fatalf("Get(%d)=%d, expected %d", i, got, i)
 			}
-			itr.Next()
 		}
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		validate(t, NewList[int]().Concat(NewList[int]()), 0)
+		validate(t, newIntList(0, 10).Concat(NewList[int]()), 10)
+		validate(t, NewList[int]().Concat(newIntList(0, 10)), 10)
 	})
 
-	b.Run("Reverse", func(b *testing.B) {
-		itr := l.Iterator()
-		for i := 0; i < b.N; i++ {
-			if i%n == 0 {
-				itr.Last()
+	t.Run("LargerLeft", func(t *testing.T) {
+		a, b := newIntList(0, 5000), newIntList(5000, 6000)
+		validate(t, a.Concat(b), 6000)
+		validate(t, a, 5000)
+	})
+
+	t.Run("LargerRight", func(t *testing.T) {
+		a, b := newIntList(0, 1000), newIntList(1000, 6000)
+		c := a.Concat(b)
+		validate(t, c, 6000)
+		validate(t, a, 1000)
+		if b.Len() != 5000 || b.Get(0) != 1000 {
+			t.Fatal("right list mutated")
+		}
+	})
+
+	// Ensure concatenating onto the same list twice does not share updates.
+	t.Run("Reuse", func(t *testing.T) {
+		for _, n := range []int{1, 31, 32, 33, 1025} {
+			base := newIntList(n, 5000)
+			x := newIntList(0, n).Concat(base)
+			y := NewListRepeat(-1, n).Concat(base)
+			validate(t, x, 5000)
+			if y.Get(0) != -1 || y.Get(n-1) != -1 || y.Get(n) != n {
+				t.Fatalf("n=%d: unexpected values", n)
+			} else if base.Len() != 5000-n || base.Get(0) != n {
+				t.Fatalf("n=%d: right list mutated", n)
+			}
+			x, y = base.Concat(newIntList(0, n)), base.Concat(NewListRepeat(-1, n))
+			if x.Get(5000-n) != 0 || y.Get(5000-n) != -1 || base.Len() != 5000-n {
+				t.Fatalf("n=%d: unexpected values", n)
 			}
-			itr.Prev()
 		}
 	})
+
+	t.Run("Builder", func(t *testing.T) {
+		b := NewListBuilder[int]()
+		b.Append(0)
+		b.AppendList(newIntList(1, 100))
+		validate(t, b.List(), 100)
+	})
 }
 
-func BenchmarkBuiltinSlice_Append(b *testing.B) {
-	b.Run("Int", func(b *testing.B) {
-		b.ReportAllocs()
-		var a []int
-		for i := 0; i < b.N; i++ {
-			a = append(a, i)
+func TestList_ConcatSlice(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		l := NewList(1, 2)
+		if other := l.ConcatSlice(nil); other != l {
+			t.Fatal("expected original list")
 		}
 	})
-	b.Run("Interface", func(b *testing.B) {
-		b.ReportAllocs()
-		var a []interface{}
-		for i := 0; i < b.N; i++ {
-			a = append(a, i)
+
+	t.Run("Immutable", func(t *testing.T) {
+		l := NewList[int]()
+		for i := 0; i < 1000; i++ {
+			l = l.Prepend(i)
+		}
+		l = l.Slice(10, 990)
+
+		values := make([]int, 5000)
+		for i := range values {
+			values[i] = -i
+		}
+		other := l.ConcatSlice(values)
+		l2 := l.Append(-1)
+
+		if other.Len() != 980+5000 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+		for i := 0; i < 980; i++ {
+			if got := other.Get(i); got != 989-i {
+				t.Fatalf("Get(%d)=%d, expected %d", i, got, 989-i)
+			}
+		}
+		for i := range values {
+			if got := other.Get(980 + i); got != -i {
+				t.Fatalf("Get(%d)=%d, expected %d", 980+i, got, -i)
+			}
+		}
+		if l.Len() != 980 || l2.Get(980) != -1 {
+			t.Fatal("original list mutated")
 		}
 	})
 }
 
-func BenchmarkListBuilder_Append(b *testing.B) {
-	b.ReportAllocs()
-	builder := NewListBuilder[int]()
-	for i := 0; i < b.N; i++ {
-		builder.Append(i)
+func TestList_SetOrAppend(t *testing.T) {
+	l := NewList(1, 2)
+	other := l.SetOrAppend(2, 3).SetOrAppend(0, 0)
+	if got, exp := fmt.Sprint(other.ToSlice()), "[0 2 3]"; got != exp {
+		t.Fatalf("unexpected values: %s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(l.ToSlice()), "[1 2]"; got != exp {
+		t.Fatalf("original list changed: %s", got)
 	}
-}
 
-func BenchmarkListBuilder_Prepend(b *testing.B) {
-	b.ReportAllocs()
-	builder := NewListBuilder[int]()
-	for i := 0; i < b.N; i++ {
-		builder.Prepend(i)
+	b := NewListBuilder[int]()
+	b.SetOrAppend(0, 1)
+	b.SetOrAppend(1, 2)
+	b.SetOrAppend(0, 0)
+	if got, exp := fmt.Sprint(b.ToSlice()), "[0 2]"; got != exp {
+		t.Fatalf("unexpected values: %s, expected %s", got, exp)
+	}
+
+	var r string
+	func() {
+		defer func() { r = fmt.Sprint(recover()) }()
+		l.SetOrAppend(3, 0)
+	}()
+	if r != `immutable.List.SetOrAppend: index 3 out of bounds` {
+		t.Fatalf("unexpected panic: %q", r)
 	}
 }
 
-func BenchmarkListBuilder_Set(b *testing.B) {
-	const n = 10000
+func TestList_Swap(t *testing.T) {
+	l, std := NewList[int](), make([]int, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		l, std = l.Prepend(i), append([]int{i}, std...)
+	}
 
-	builder := NewListBuilder[int]()
-	for i := 0; i < 10000; i++ {
-		builder.Append(i)
+	rand := rand.New(rand.NewSource(0))
+	b := NewListBuilderFrom(l)
+	orig := l
+	for n := 0; n < 1000; n++ {
+		i, j := rand.Intn(len(std)), rand.Intn(len(std))
+		l = l.Swap(i, j)
+		b.Swap(i, j)
+		std[i], std[j] = std[j], std[i]
+	}
+	if got, exp := fmt.Sprint(l.ToSlice()), fmt.Sprint(std); got != exp {
+		t.Fatal("unexpected list values")
+	} else if got := fmt.Sprint(b.ToSlice()); got != exp {
+		t.Fatal("unexpected builder values")
+	} else if orig.Get(0) != 1999 || orig.Get(1999) != 0 {
+		t.Fatal("original list changed")
 	}
-	b.ReportAllocs()
-	b.ResetTimer()
 
-	for i := 0; i < b.N; i++ {
-		builder.Set(i%n, i*10)
+	var r string
+	func() {
+		defer func() { r = fmt.Sprint(recover()) }()
+		l.Swap(0, 2000)
+	}()
+	if r != `immutable.List.Swap: index 2000 out of bounds` {
+		t.Fatalf("unexpected panic: %q", r)
 	}
 }
 
-func ExampleList_Append() {
-	l := NewList[string]()
-	l = l.Append("foo")
-	l = l.Append("bar")
-	l = l.Append("baz")
+func TestListBuilder_Sort(t *testing.T) {
+	type item struct{ k, seq int }
 
-	fmt.Println(l.Get(0))
-	fmt.Println(l.Get(1))
-	fmt.Println(l.Get(2))
-	// Output:
-	// foo
-	// bar
-	// baz
-}
+	rand := rand.New(rand.NewSource(0))
+	l := NewList[item]()
+	for i := 0; i < 5000; i++ {
+		l = l.Append(item{k: rand.Intn(100), seq: i})
+	}
 
-func ExampleList_Prepend() {
-	l := NewList[string]()
-	l = l.Prepend("foo")
-	l = l.Prepend("bar")
-	l = l.Prepend("baz")
+	b := NewListBuilderFrom(l)
+	b.Sort(func(x, y item) bool { return x.k < y.k })
+	b.Append(item{k: -1})
+	other := b.List()
 
-	fmt.Println(l.Get(0))
-	fmt.Println(l.Get(1))
-	fmt.Println(l.Get(2))
-	// Output:
-	// baz
-	// bar
-	// foo
+	if other.Len() != 5001 {
+		t.Fatalf("unexpected len: %d", other.Len())
+	}
+	for i := 1; i < 5000; i++ {
+		prev, v := other.Get(i-1), other.Get(i)
+		if prev.k > v.k || (prev.k == v.k && prev.seq > v.seq) {
+			t.Fatalf("unsorted at %d: %v, %v", i, prev, v)
+		}
+	}
+	if v := l.Get(0); v.seq != 0 {
+		t.Fatal("original list changed")
+	}
+
+	empty := NewListBuilder[int]()
+	empty.Sort(func(x, y int) bool { return x < y })
+	if !empty.IsEmpty() {
+		t.Fatal("expected empty builder")
+	}
 }
 
-func ExampleList_Set() {
-	l := NewList[string]()
-	l = l.Append("foo")
-	l = l.Append("bar")
-	l = l.Set(1, "baz")
+func TestList_InsertAt(t *testing.T) {
+	l := NewList(1, 3)
+	l = l.InsertAt(0, 0)
+	l = l.InsertAt(2, 2)
+	l = l.InsertAt(4, 4)
+	for i := 0; i < 5; i++ {
+		if got := l.Get(i); got != i {
+			t.Fatalf("Get(%d)=%d, expected %d", i, got, i)
+		}
+	}
 
-	fmt.Println(l.Get(0))
-	fmt.Println(l.Get(1))
-	// Output:
-	// foo
-	// baz
+	var r string
+	func() {
+		defer func() { r = recover().(string) }()
+		l.InsertAt(6, 0)
+	}()
+	if r != "immutable.List.InsertAt: index 6 out of bounds" {
+		t.Fatalf("unexpected panic: %q", r)
+	}
 }
 
-func ExampleList_Slice() {
-	l := NewList[string]()
-	l = l.Append("foo")
-	l = l.Append("bar")
-	l = l.Append("baz")
-	l = l.Slice(1, 3)
+func TestList_InsertSortedBy(t *testing.T) {
+	type item struct{ k, seq int }
+	key := func(v item) int { return v.k }
 
-	fmt.Println(l.Get(0))
-	fmt.Println(l.Get(1))
-	// Output:
-	// bar
-	// baz
-}
+	l := NewList[item]()
+	for seq, k := range []int{5, 1, 3, 3, 9, 0} {
+		var index int
+		l, index = l.InsertSortedBy(item{k, seq}, key)
+		if got := l.Get(index); got.seq != seq {
+			t.Fatalf("Get(%d)=%v, expected seq %d", index, got, seq)
+		}
+	}
 
-func ExampleList_Iterator() {
+	exp := []item{{0, 5}, {1, 1}, {3, 2}, {3, 3}, {5, 0}, {9, 4}}
+	for i := range exp {
+		if got := l.Get(i); got != exp[i] {
+			t.Fatalf("Get(%d)=%v, expected %v", i, got, exp[i])
+		}
+	}
+}
+
+func TestList_RemoveAt(t *testing.T) {
+	l := NewList(0, 1, 2, 3, 4)
+	l = l.RemoveAt(4)
+	l = l.RemoveAt(0)
+	l = l.RemoveAt(1)
+	if l.Len() != 2 || l.Get(0) != 1 || l.Get(1) != 3 {
+		t.Fatalf("unexpected list: len=%d", l.Len())
+	} else if l = l.RemoveAt(0).RemoveAt(0); l.Len() != 0 {
+		t.Fatalf("unexpected size: %d", l.Len())
+	}
+
+	var r string
+	func() {
+		defer func() { r = recover().(string) }()
+		l.RemoveAt(0)
+	}()
+	if r != "immutable.List.RemoveAt: index 0 out of bounds" {
+		t.Fatalf("unexpected panic: %q", r)
+	}
+}
+
+// Ensure inserts & removals across node boundaries do not affect the original list.
+func TestList_InsertRemoveAt_Large(t *testing.T) {
+	var values []int
+	for i := 0; i < 2000; i++ {
+		values = append(values, i)
+	}
+	l := NewListFromSlice(values)
+	for i := -1; i >= -40; i-- {
+		l = l.Prepend(i)
+		values = append([]int{i}, values...)
+	}
+
+	for _, index := range []int{0, 1, 31, 32, 33, 500, 1019, 1020, 1021, 1500, 2038, 2039} {
+		insert := append(append(append([]int{}, values[:index]...), -100), values[index:]...)
+		if other := l.InsertAt(index, -100); fmt.Sprint(other.ToSlice()) != fmt.Sprint(insert) {
+			t.Fatalf("InsertAt(%d): unexpected values", index)
+		} else if err := other.Validate(); err != nil {
+			t.Fatalf("InsertAt(%d): %s", index, err)
+		}
+
+		remove := append(append([]int{}, values[:index]...), values[index+1:]...)
+		if other := l.RemoveAt(index); fmt.Sprint(other.ToSlice()) != fmt.Sprint(remove) {
+			t.Fatalf("RemoveAt(%d): unexpected values", index)
+		} else if err := other.Validate(); err != nil {
+			t.Fatalf("RemoveAt(%d): %s", index, err)
+		}
+
+		if fmt.Sprint(l.ToSlice()) != fmt.Sprint(values) {
+			t.Fatalf("%d: original list changed", index)
+		}
+	}
+}
+
+func TestList_IndexFrom(t *testing.T) {
+	l := NewList("a", ",", "b", ",", "c")
+	isComma := func(v string) bool { return v == "," }
+
+	if i := l.IndexFrom(0, isComma); i != 1 {
+		t.Fatalf("IndexFrom(0)=%d, expected 1", i)
+	} else if i := l.IndexFrom(1, isComma); i != 1 {
+		t.Fatalf("IndexFrom(1)=%d, expected 1", i)
+	} else if i := l.IndexFrom(2, isComma); i != 3 {
+		t.Fatalf("IndexFrom(2)=%d, expected 3", i)
+	} else if i := l.IndexFrom(4, isComma); i != -1 {
+		t.Fatalf("IndexFrom(4)=%d, expected -1", i)
+	} else if i := l.IndexFrom(100, isComma); i != -1 {
+		t.Fatalf("IndexFrom(100)=%d, expected -1", i)
+	} else if i := l.IndexFrom(-1, isComma); i != 1 {
+		t.Fatalf("IndexFrom(-1)=%d, expected 1", i)
+	}
+}
+
+func TestList_Find(t *testing.T) {
+	l := NewList(1, 2, 3, 4, 5)
+	isEven := func(v int) bool { return v%2 == 0 }
+
+	if v, ok := l.Find(isEven); !ok || v != 2 {
+		t.Fatalf("Find()=<%v,%v>, expected <2,true>", v, ok)
+	} else if v, ok := l.FindLast(isEven); !ok || v != 4 {
+		t.Fatalf("FindLast()=<%v,%v>, expected <4,true>", v, ok)
+	}
+
+	var calls int
+	l.Find(func(v int) bool { calls++; return v == 1 })
+	if calls != 1 {
+		t.Fatalf("expected Find to stop at first match, got %d calls", calls)
+	}
+
+	isNegative := func(v int) bool { return v < 0 }
+	if v, ok := l.Find(isNegative); ok || v != 0 {
+		t.Fatalf("Find()=<%v,%v>, expected <0,false>", v, ok)
+	} else if v, ok := l.FindLast(isNegative); ok || v != 0 {
+		t.Fatalf("FindLast()=<%v,%v>, expected <0,false>", v, ok)
+	} else if _, ok := NewList[int]().Find(isEven); ok {
+		t.Fatal("expected no match on empty list")
+	}
+}
+
+func TestList_CountAnyEvery(t *testing.T) {
+	l := NewList(1, 2, 3, 4, 5)
+	isEven := func(v int) bool { return v%2 == 0 }
+	isPositive := func(v int) bool { return v > 0 }
+
+	if n := l.Count(isEven); n != 2 {
+		t.Fatalf("Count()=%d, expected 2", n)
+	} else if !l.Any(isEven) {
+		t.Fatal("expected Any() to be true")
+	} else if l.Every(isEven) {
+		t.Fatal("expected Every() to be false")
+	} else if !l.Every(isPositive) {
+		t.Fatal("expected Every() to be true")
+	}
+
+	var calls int
+	l.Every(func(v int) bool { calls++; return v < 2 })
+	if calls != 2 {
+		t.Fatalf("expected Every to short-circuit, got %d calls", calls)
+	}
+
+	empty := NewList[int]()
+	if empty.Count(isEven) != 0 || empty.Any(isEven) || !empty.Every(isEven) {
+		t.Fatal("unexpected results for empty list")
+	}
+
+	b := NewListBuilder[int]()
+	b.AppendSlice(2, 4, 6)
+	if n := b.Count(isEven); n != 3 {
+		t.Fatalf("Count()=%d, expected 3", n)
+	} else if !b.Any(isEven) || !b.Every(isEven) {
+		t.Fatal("unexpected builder results")
+	}
+}
+
+func TestList_ToSlice(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		if a := NewList[int]().ToSlice(); len(a) != 0 {
+			t.Fatalf("unexpected slice: %v", a)
+		}
+	})
+
+	t.Run("Large", func(t *testing.T) {
+		b := NewListBuilder[int]()
+		for i := 0; i < 10000; i++ {
+			b.Append(i)
+		}
+		a := b.ToSlice()
+		if len(a) != 10000 || cap(a) != 10000 {
+			t.Fatalf("unexpected len/cap: %d/%d", len(a), cap(a))
+		}
+		for i := range a {
+			if a[i] != i {
+				t.Fatalf("a[%d]=%d, expected %d", i, a[i], i)
+			}
+		}
+	})
+}
+
+func TestClone(t *testing.T) {
+	l := NewList(1, 2, 3)
+	if other := l.Clone(); other == l || other.Len() != 3 || other.Get(2) != 3 {
+		t.Fatal("unexpected list clone")
+	} else if other.Append(4); l.Len() != 3 {
+		t.Fatal("unexpected mutation of list")
+	}
+
+	m := NewMap[int, int](nil).Set(1, 1)
+	if other := m.Clone(); other == m || other.Len() != 1 {
+		t.Fatal("unexpected map clone")
+	} else if other.hasher != m.hasher {
+		t.Fatal("expected hasher to be shared")
+	}
+
+	sm := NewSortedMap[int, int](nil).Set(1, 1)
+	if other := sm.Clone(); other == sm || other.Len() != 1 {
+		t.Fatal("unexpected sorted map clone")
+	} else if err := json.Unmarshal([]byte(`{}`), &other); err != nil {
+		t.Fatal(err)
+	} else if sm.Len() != 1 {
+		t.Fatal("unexpected mutation of sorted map")
+	}
+}
+
+func TestBuilders_Clear(t *testing.T) {
+	lb := NewListBuilderWithCapacity[int](100)
+	lb.Append(1)
+	lb.Clear()
+	lb.Append(2)
+	if got, exp := fmt.Sprint(lb.List().ToSlice()), "[2]"; got != exp {
+		t.Fatalf("unexpected list: %s, expected %s", got, exp)
+	}
+
+	h := HasherFunc(func(k int) uint32 { return uint32(k % 10) }, func(a, b int) bool { return a%10 == b%10 })
+	mb := NewMapBuilder[int, int](h)
+	mb.Set(1, 1)
+	mb.Clear()
+	if mb.Len() != 0 {
+		t.Fatalf("unexpected map length: %d", mb.Len())
+	}
+	mb.Set(2, 2)
+	mb.Set(12, 12)
+	if m := mb.Map(); m.Len() != 1 || m.hasher != h {
+		t.Fatal("expected hasher to be kept after clear")
+	}
+
+	smb := NewSortedMapBuilder[int, int](ReverseComparer(NewComparer(0)))
+	smb.Set(1, 1)
+	smb.Clear()
+	smb.Set(2, 2)
+	smb.Set(3, 3)
+	if got, exp := fmt.Sprint(smb.Map().Keys()), "[3 2]"; got != exp {
+		t.Fatalf("unexpected keys: %s, expected %s", got, exp)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	var l List[int]
+	var m Map[int, int]
+	var sm SortedMap[int, int]
+	if !l.IsEmpty() || !m.IsEmpty() || !sm.IsEmpty() {
+		t.Fatal("expected zero values to be empty")
+	}
+
+	if NewList(1).IsEmpty() {
+		t.Fatal("expected non-empty list")
+	} else if NewMap[int, int](nil).Set(1, 1).IsEmpty() {
+		t.Fatal("expected non-empty map")
+	} else if NewSortedMap[int, int](nil).Set(1, 1).IsEmpty() {
+		t.Fatal("expected non-empty sorted map")
+	}
+
+	lb, mb, smb := NewListBuilder[int](), NewMapBuilder[int, int](nil), NewSortedMapBuilder[int, int](nil)
+	if !lb.IsEmpty() || !mb.IsEmpty() || !smb.IsEmpty() {
+		t.Fatal("expected new builders to be empty")
+	}
+	lb.Append(1)
+	mb.Set(1, 1)
+	smb.Set(1, 1)
+	if lb.IsEmpty() || mb.IsEmpty() || smb.IsEmpty() {
+		t.Fatal("expected non-empty builders")
+	}
+}
+
+func TestList_Validate(t *testing.T) {
+	l := NewListFromSlice(make([]int, 100))
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	} else if err := NewList[int]().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Clear the occupied bit of the last element.
+	node := l.root
+	for node.depth() > 0 {
+		b := node.(*listBranchNode[int])
+		node = b.children[(99>>(b.d*listNodeBits))&listNodeMask]
+	}
+	leaf := node.(*listLeafNode[int])
+	leaf.occupied &^= 1 << 3
+	if err := l.Validate(); err == nil || err.Error() != "immutable: list index 99 is not occupied" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Mark a slot after the last element as occupied.
+	leaf.occupied |= 1<<3 | 1<<4
+	if err := l.Validate(); err == nil || err.Error() != "immutable: list slot 100 outside of list is occupied" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestListIterator_SeekFunc(t *testing.T) {
+	l := NewList[int]()
+	for i := 0; i < 100; i++ {
+		l = l.Append(i)
+	}
+	isMultipleOf := func(n int) func(int) bool {
+		return func(v int) bool { return v%n == 0 && v != 0 }
+	}
+
+	itr := l.Iterator()
+	itr.SeekFunc(isMultipleOf(40))
+	if i, v := itr.Next(); i != 40 || v != 40 {
+		t.Fatalf("unexpected position: <%d,%d>", i, v)
+	}
+
+	// Resume the scan from the current position.
+	itr.SeekFunc(isMultipleOf(40))
+	if i, _ := itr.Next(); i != 80 {
+		t.Fatalf("unexpected position: %d", i)
+	}
+
+	// Ensure a match at the current position does not move the iterator.
+	itr.Seek(50)
+	if itr.SeekFunc(isMultipleOf(10)); itr.index != 50 {
+		t.Fatalf("unexpected position: %d", itr.index)
+	}
+
+	if itr.SeekFunc(func(v int) bool { return v < 0 }); !itr.Done() {
+		t.Fatal("expected done")
+	}
+}
+
+func TestListIterator_Remaining(t *testing.T) {
+	l := NewList(randomInts(rand.New(rand.NewSource(0)), 100)...)
+	itr := l.Iterator()
+	for i := 100; i > 0; i-- {
+		if n := itr.Remaining(); n != i {
+			t.Fatalf("unexpected remaining: %d, expected %d", n, i)
+		}
+		itr.Next()
+	}
+	if n := itr.Remaining(); n != 0 {
+		t.Fatalf("unexpected remaining: %d", n)
+	}
+
+	itr.Seek(90)
+	if n := itr.Remaining(); n != 10 {
+		t.Fatalf("unexpected remaining: %d", n)
+	}
+	if n := NewList[int]().Iterator().Remaining(); n != 0 {
+		t.Fatalf("unexpected remaining: %d", n)
+	}
+}
+
+func TestList_SliceFromTo(t *testing.T) {
+	l := NewList(0, 1, 2, 3, 4)
+	if got, exp := fmt.Sprint(l.SliceFrom(2).ToSlice()), "[2 3 4]"; got != exp {
+		t.Fatalf("SliceFrom()=%s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(l.SliceTo(2).ToSlice()), "[0 1]"; got != exp {
+		t.Fatalf("SliceTo()=%s, expected %s", got, exp)
+	} else if l.SliceFrom(5).Len() != 0 || l.SliceTo(0).Len() != 0 {
+		t.Fatal("expected empty slices at list bounds")
+	}
+}
+
+func TestList_Each(t *testing.T) {
+	l := NewList(1, 2, 3, 4, 5)
+
+	var a []int
+	l.Each(func(v int) { a = append(a, v) })
+	if got, exp := fmt.Sprint(a), "[1 2 3 4 5]"; got != exp {
+		t.Fatalf("Each()=%s, expected %s", got, exp)
+	}
+
+	a = nil
+	l.EachUntil(func(v int) bool {
+		a = append(a, v)
+		return v < 3
+	})
+	if got, exp := fmt.Sprint(a), "[1 2 3]"; got != exp {
+		t.Fatalf("EachUntil()=%s, expected %s", got, exp)
+	}
+
+	NewList[int]().EachUntil(func(v int) bool {
+		t.Fatal("unexpected callback for empty list")
+		return true
+	})
+}
+
+func TestNewListRepeat(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 1000, 1025} {
+		l := NewListRepeat("x", n)
+		if l.Len() != n {
+			t.Fatalf("n=%d: unexpected len: %d", n, l.Len())
+		} else if err := l.Validate(); err != nil {
+			t.Fatalf("n=%d: %s", n, err)
+		}
+		for i := 0; i < n; i++ {
+			if v := l.Get(i); v != "x" {
+				t.Fatalf("n=%d: unexpected value at %d: %q", n, i, v)
+			}
+		}
+	}
+
+	t.Run("Negative", func(t *testing.T) {
+		var r string
+		func() {
+			defer func() { r = fmt.Sprint(recover()) }()
+			NewListRepeat(0, -1)
+		}()
+		if r != `immutable.NewListRepeat: negative count -1` {
+			t.Fatalf("unexpected panic: %q", r)
+		}
+	})
+}
+
+func TestNewListFromSlice(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 1024, 1025, 40000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			a := make([]int, n)
+			for i := range a {
+				a[i] = i
+			}
+
+			l := NewTList()
+			l.im = NewListFromSlice(a)
+			l.builder = NewListBuilderFrom(l.im)
+			l.std = append([]int{}, a...)
+			if n > 0 {
+				a[0] = -1 // modifying the source slice must not affect the list
+			}
+			if err := l.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			// Ensure the list can continue to grow in both directions.
+			l.Prepend(-1)
+			l.Append(n)
+			if err := l.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestListBuilder_AppendSlice(t *testing.T) {
+	b := NewListBuilder[int]()
+	b.AppendSlice(3, 4, 5)
+	b.PrependSlice(0, 1, 2)
+	b.AppendSlice()
+	for i := 0; i < 10; i++ {
+		a := make([]int, 100)
+		for j := range a {
+			a[j] = 6 + i*100 + j
+		}
+		b.AppendSlice(a...)
+	}
+
+	l := b.List()
+	if l.Len() != 1006 {
+		t.Fatalf("unexpected length: %d", l.Len())
+	}
+	for i := 0; i < l.Len(); i++ {
+		if v := l.Get(i); v != i {
+			t.Fatalf("Get(%d)=%d", i, v)
+		}
+	}
+
+	other := l.AppendAll(1006, 1007)
+	if other.Len() != 1008 || other.Get(1007) != 1007 || l.Len() != 1006 {
+		t.Fatalf("unexpected AppendAll() result")
+	}
+
+	// Appending different values to the same list must not affect each other.
+	ones, twos := make([]int, 2000), make([]int, 2000)
+	for i := range ones {
+		ones[i], twos[i] = 1, 2
+	}
+	l1, l2 := l.AppendAll(ones...), l.AppendAll(twos...)
+	for i := l.Len(); i < l1.Len(); i++ {
+		if l1.Get(i) != 1 || l2.Get(i) != 2 {
+			t.Fatalf("unexpected shared mutation at index %d", i)
+		}
+	}
+}
+
+func TestNewListBuilderWithCapacity(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 32, 33, 1024, 1025, 5000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			// Append beyond the capacity hint to ensure the list still grows.
+			b := NewListBuilderWithCapacity[int](n)
+			for i := 0; i < n+100; i++ {
+				b.Append(i)
+			}
+
+			l := b.List()
+			if l.Len() != n+100 {
+				t.Fatalf("unexpected length: %d", l.Len())
+			}
+			for i := 0; i < l.Len(); i++ {
+				if v := l.Get(i); v != i {
+					t.Fatalf("Get(%d)=%d", i, v)
+				}
+			}
+			if got, exp := fmt.Sprint(l.Slice(1, 3).ToSlice()), "[1 2]"; got != exp {
+				t.Fatalf("Slice()=%s, expected %s", got, exp)
+			}
+		})
+	}
+
+	t.Run("Trim", func(t *testing.T) {
+		b := NewListBuilderWithCapacity[int](5000)
+		for i := 0; i < 10; i++ {
+			b.Append(i)
+		}
+		if l := b.List(); l.root.containsAfter(l.origin + l.Len() - 1) {
+			t.Fatal("expected unused nodes to be removed")
+		}
+
+		if l := NewListBuilderWithCapacity[int](5000).List(); l.Len() != 0 || l.root.depth() != 0 {
+			t.Fatal("expected empty list to be reset")
+		}
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		b := NewListBuilderWithCapacity[int](5000)
+		for i := 1; i <= 10; i++ {
+			b.Append(i)
+		}
+		b.Prepend(0)
+
+		var a []int
+		itr := b.Iterator()
+		for itr.Last(); !itr.Done(); {
+			_, v := itr.Prev()
+			a = append(a, v)
+		}
+		if got, exp := fmt.Sprint(a), "[10 9 8 7 6 5 4 3 2 1 0]"; got != exp {
+			t.Fatalf("unexpected values: %s, expected %s", got, exp)
+		}
+	})
+}
+
+func TestNewListBuilderFrom(t *testing.T) {
+	l := NewList[int]()
+	for i := 0; i < 1000; i++ {
+		l = l.Append(i)
+	}
+
+	b := NewListBuilderFrom(l)
+	if b.Len() != 1000 || b.Get(500) != 500 {
+		t.Fatalf("unexpected builder contents")
+	}
+	b.Set(0, -1)
+	b.Append(1000)
+	b.RemoveAt(1)
+
+	other := b.List()
+	if other.Len() != 1000 || other.Get(0) != -1 || other.Get(1) != 2 || other.Get(999) != 1000 {
+		t.Fatalf("unexpected builder list")
+	}
+	for i := 0; i < 1000; i++ {
+		if v := l.Get(i); v != i {
+			t.Fatalf("source list mutated: Get(%d)=%d", i, v)
+		}
+	}
+	if l.Len() != 1000 {
+		t.Fatalf("unexpected source list length: %d", l.Len())
+	}
+}
+
+func TestNewListBuilderFrom_PathCopy(t *testing.T) {
+	l := NewList[int]()
+	for i := 0; i < 5000; i++ {
+		l = l.Prepend(-i)
+	}
+
+	// Only the path to the updated index should be copied.
+	b := NewListBuilderFrom(l)
+	b.Set(0, 100)
+	other := b.Build()
+	root, otherRoot := l.root.(*listBranchNode[int]), other.root.(*listBranchNode[int])
+	var shared int
+	for i := range root.children {
+		if root.children[i] != nil && root.children[i] == otherRoot.children[i] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		t.Fatal("expected unchanged nodes to be shared")
+	}
+
+	// Interleave updates and snapshots and ensure no snapshot changes.
+	rand := rand.New(rand.NewSource(0))
+	snapshots, exp := []*List[int]{l, other}, [][]int{l.ToSlice(), other.ToSlice()}
+	values := other.ToSlice()
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 50; j++ {
+			switch v := rand.Int(); rand.Intn(10) {
+			case 0:
+				index := rand.Intn(b.Len())
+				b.Set(index, v)
+				values[index] = v
+			case 1:
+				x, y := rand.Intn(b.Len()), rand.Intn(b.Len())
+				b.Swap(x, y)
+				values[x], values[y] = values[y], values[x]
+			case 2:
+				b.Append(v)
+				values = append(values, v)
+			case 3:
+				b.Prepend(v)
+				values = append([]int{v}, values...)
+			case 4:
+				b.AppendSlice(v, v+1, v+2)
+				values = append(values, v, v+1, v+2)
+			case 5:
+				b.PrependSlice(v, v+1, v+2)
+				values = append([]int{v, v + 1, v + 2}, values...)
+			case 6:
+				index := rand.Intn(b.Len() + 1)
+				b.InsertAt(index, v)
+				values = append(values[:index], append([]int{v}, values[index:]...)...)
+			case 7:
+				index := rand.Intn(b.Len())
+				b.RemoveAt(index)
+				values = append(values[:index], values[index+1:]...)
+			case 8:
+				b.AppendList(NewList(v, v+1))
+				values = append(values, v, v+1)
+			default:
+				start := rand.Intn(5)
+				end := b.Len() - rand.Intn(5)
+				b.Slice(start, end)
+				values = append([]int(nil), values[start:end]...)
+			}
+		}
+		snapshot := b.Build()
+		if got, want := fmt.Sprint(snapshot.ToSlice()), fmt.Sprint(values); got != want {
+			t.Fatalf("%d: unexpected builder values", i)
+		} else if err := snapshot.Validate(); err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		snapshots, exp = append(snapshots, snapshot), append(exp, snapshot.ToSlice())
+	}
+	for i := range snapshots {
+		if got, want := fmt.Sprint(snapshots[i].ToSlice()), fmt.Sprint(exp[i]); got != want {
+			t.Fatalf("snapshot %d changed", i)
+		} else if err := snapshots[i].Validate(); err != nil {
+			t.Fatalf("snapshot %d: %s", i, err)
+		}
+	}
+}
+
+func TestList_ForEachWindow(t *testing.T) {
+	t.Run("All", func(t *testing.T) {
+		var windows [][]int
+		NewList(1, 2, 3, 4).ForEachWindow(2, func(window []int) bool {
+			windows = append(windows, append([]int(nil), window...))
+			return true
+		})
+		if got, exp := fmt.Sprint(windows), "[[1 2] [2 3] [3 4]]"; got != exp {
+			t.Fatalf("windows=%s, expected %s", got, exp)
+		}
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		var n int
+		NewList(1, 2, 3, 4, 5).ForEachWindow(3, func(window []int) bool {
+			n++
+			return window[0] < 2
+		})
+		if n != 2 {
+			t.Fatalf("unexpected callback count: %d", n)
+		}
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		NewList(1, 2).ForEachWindow(3, func(window []int) bool {
+			t.Fatal("unexpected callback")
+			return true
+		})
+	})
+
+	t.Run("InvalidSize", func(t *testing.T) {
+		var r string
+		func() {
+			defer func() { r = recover().(string) }()
+			NewList(1).ForEachWindow(0, func(window []int) bool { return true })
+		}()
+		if r != `immutable.List.ForEachWindow: invalid window size 0` {
+			t.Fatalf("unexpected panic: %q", r)
+		}
+	})
+}
+
+func TestList_JSON(t *testing.T) {
+	t.Run("Marshal", func(t *testing.T) {
+		if buf, err := json.Marshal(NewList("foo", "bar")); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `["foo","bar"]`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
+		}
+		if buf, err := json.Marshal(NewList[int]()); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `[]`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
+		}
+	})
+
+	t.Run("Unmarshal", func(t *testing.T) {
+		var l List[int]
+		if err := json.Unmarshal([]byte(`[1,2,3]`), &l); err != nil {
+			t.Fatal(err)
+		} else if l.Len() != 3 || l.Get(0) != 1 || l.Get(2) != 3 {
+			t.Fatalf("unexpected list: len=%d", l.Len())
+		}
+		if other := l.Append(4); other.Len() != 4 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+	})
+
+	t.Run("UnmarshalNull", func(t *testing.T) {
+		l := NewList(1)
+		if err := l.UnmarshalJSON([]byte(`null`)); err != nil {
+			t.Fatal(err)
+		} else if l.Len() != 0 {
+			t.Fatalf("unexpected size: %d", l.Len())
+		}
+	})
+
+	t.Run("UnmarshalError", func(t *testing.T) {
+		var l List[int]
+		if err := json.Unmarshal([]byte(`["foo"]`), &l); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestList_Reverse(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		if n := NewList[int]().Reverse().Len(); n != 0 {
+			t.Fatalf("unexpected size: %d", n)
+		}
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		if l := NewList(1).Reverse(); l.Len() != 1 || l.Get(0) != 1 {
+			t.Fatalf("unexpected list: len=%d", l.Len())
+		}
+	})
+
+	t.Run("Large", func(t *testing.T) {
+		const n = 10000
+		l := NewList[int]()
+		for i := 0; i < n; i++ {
+			l = l.Prepend(i)
+		}
+		other := l.Reverse()
+		for i := 0; i < n; i++ {
+			if got := other.Get(i); got != i {
+				t.Fatalf("Get(%d)=%d, expected %d", i, got, i)
+			} else if got := l.Get(i); got != n-i-1 {
+				t.Fatalf("original mutated: Get(%d)=%d", i, got)
+			}
+		}
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		b := NewListBuilder[string]()
+		b.Append("foo")
+		b.Append("bar")
+		b.Append("baz")
+		b.Reverse()
+		l := b.List()
+		if l.Get(0) != "baz" || l.Get(1) != "bar" || l.Get(2) != "foo" {
+			t.Fatalf("unexpected order: %v, %v, %v", l.Get(0), l.Get(1), l.Get(2))
+		}
+	})
+}
+
+func TestFlatMap(t *testing.T) {
+	l := FlatMap(NewList(0, 1, 2, 3), func(v int) *List[string] {
+		other := NewList[string]()
+		for i := 0; i < v; i++ {
+			other = other.Append(fmt.Sprint(v))
+		}
+		return other
+	})
+
+	exp := []string{"1", "2", "2", "3", "3", "3"}
+	if l.Len() != len(exp) {
+		t.Fatalf("Len()=%d, expected %d", l.Len(), len(exp))
+	}
+	for i := range exp {
+		if got := l.Get(i); got != exp[i] {
+			t.Fatalf("Get(%d)=%q, expected %q", i, got, exp[i])
+		}
+	}
+}
+
+func TestDedupBy(t *testing.T) {
+	type event struct {
+		id   int
+		tags []string
+	}
+	l := NewList(
+		event{1, []string{"a"}},
+		event{1, []string{"b"}},
+		event{2, nil},
+		event{1, []string{"c"}},
+		event{1, nil},
+	)
+
+	other := DedupBy(l, func(e event) int { return e.id })
+	if other.Len() != 3 {
+		t.Fatalf("unexpected size: %d", other.Len())
+	}
+	for i, exp := range []string{"[a]", "[]", "[c]"} {
+		if got := fmt.Sprint(other.Get(i).tags); got != exp {
+			t.Fatalf("Get(%d).tags=%s, expected %s", i, got, exp)
+		}
+	}
+
+	if n := DedupBy(NewList[int](), func(v int) int { return v }).Len(); n != 0 {
+		t.Fatalf("unexpected size: %d", n)
+	}
+}
+
+func BenchmarkList_Append(b *testing.B) {
+	b.ReportAllocs()
+	l := NewList[int]()
+	for i := 0; i < b.N; i++ {
+		l = l.Append(i)
+	}
+}
+
+func BenchmarkList_Prepend(b *testing.B) {
+	b.ReportAllocs()
+	l := NewList[int]()
+	for i := 0; i < b.N; i++ {
+		l = l.Prepend(i)
+	}
+}
+
+func BenchmarkList_Set(b *testing.B) {
+	const n = 10000
+
+	l := NewList[int]()
+	for i := 0; i < 10000; i++ {
+		l = l.Append(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l = l.Set(i%n, i*10)
+	}
+}
+
+func BenchmarkList_Iterator(b *testing.B) {
+	const n = 10000
+	l := NewList[int]()
+	for i := 0; i < 10000; i++ {
+		l = l.Append(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.Run("Forward", func(b *testing.B) {
+		itr := l.Iterator()
+		for i := 0; i < b.N; i++ {
+			if i%n == 0 {
+				itr.First()
+			}
+			itr.Next()
+		}
+	})
+
+	b.Run("Reverse", func(b *testing.B) {
+		itr := l.Iterator()
+		for i := 0; i < b.N; i++ {
+			if i%n == 0 {
+				itr.Last()
+			}
+			itr.Prev()
+		}
+	})
+}
+
+func BenchmarkBuiltinSlice_Append(b *testing.B) {
+	b.Run("Int", func(b *testing.B) {
+		b.ReportAllocs()
+		var a []int
+		for i := 0; i < b.N; i++ {
+			a = append(a, i)
+		}
+	})
+	b.Run("Interface", func(b *testing.B) {
+		b.ReportAllocs()
+		var a []interface{}
+		for i := 0; i < b.N; i++ {
+			a = append(a, i)
+		}
+	})
+}
+
+func BenchmarkListBuilder_Append(b *testing.B) {
+	b.ReportAllocs()
+	builder := NewListBuilder[int]()
+	for i := 0; i < b.N; i++ {
+		builder.Append(i)
+	}
+}
+
+func BenchmarkListBuilder_AppendWithCapacity(b *testing.B) {
+	b.ReportAllocs()
+	builder := NewListBuilderWithCapacity[int](b.N)
+	for i := 0; i < b.N; i++ {
+		builder.Append(i)
+	}
+}
+
+func BenchmarkListBuilder_Prepend(b *testing.B) {
+	b.ReportAllocs()
+	builder := NewListBuilder[int]()
+	for i := 0; i < b.N; i++ {
+		builder.Prepend(i)
+	}
+}
+
+func BenchmarkListBuilder_Set(b *testing.B) {
+	const n = 10000
+
+	builder := NewListBuilder[int]()
+	for i := 0; i < 10000; i++ {
+		builder.Append(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		builder.Set(i%n, i*10)
+	}
+}
+
+func ExampleList_Append() {
+	l := NewList[string]()
+	l = l.Append("foo")
+	l = l.Append("bar")
+	l = l.Append("baz")
+
+	fmt.Println(l.Get(0))
+	fmt.Println(l.Get(1))
+	fmt.Println(l.Get(2))
+	// Output:
+	// foo
+	// bar
+	// baz
+}
+
+func ExampleList_Prepend() {
+	l := NewList[string]()
+	l = l.Prepend("foo")
+	l = l.Prepend("bar")
+	l = l.Prepend("baz")
+
+	fmt.Println(l.Get(0))
+	fmt.Println(l.Get(1))
+	fmt.Println(l.Get(2))
+	// Output:
+	// baz
+	// bar
+	// foo
+}
+
+func ExampleList_Set() {
+	l := NewList[string]()
+	l = l.Append("foo")
+	l = l.Append("bar")
+	l = l.Set(1, "baz")
+
+	fmt.Println(l.Get(0))
+	fmt.Println(l.Get(1))
+	// Output:
+	// foo
+	// baz
+}
+
+func ExampleList_Slice() {
+	l := NewList[string]()
+	l = l.Append("foo")
+	l = l.Append("bar")
+	l = l.Append("baz")
+	l = l.Slice(1, 3)
+
+	fmt.Println(l.Get(0))
+	fmt.Println(l.Get(1))
+	// Output:
+	// bar
+	// baz
+}
+
+func ExampleList_Iterator() {
 	l := NewList[string]()
 	l = l.Append("foo")
 	l = l.Append("bar")
@@ -955,6 +2310,21 @@ func TestMap_Get(t *testing.T) {
 	})
 }
 
+func TestMap_GetOrDefault(t *testing.T) {
+	m := NewMap[string, int](nil)
+	if v := m.GetOrDefault("foo", 10); v != 10 {
+		t.Fatalf("GetOrDefault()=%d, expected 10", v)
+	}
+	m = m.Set("foo", 1)
+	if v := m.GetOrDefault("foo", 10); v != 1 {
+		t.Fatalf("GetOrDefault()=%d, expected 1", v)
+	} else if v := m.GetOrElse("foo", func() int { panic("unexpected call") }); v != 1 {
+		t.Fatalf("GetOrElse()=%d, expected 1", v)
+	} else if v := m.GetOrElse("bar", func() int { return 20 }); v != 20 {
+		t.Fatalf("GetOrElse()=%d, expected 20", v)
+	}
+}
+
 func TestMap_Set(t *testing.T) {
 	t.Run("Simple", func(t *testing.T) {
 		m := NewMap[int, string](nil)
@@ -990,89 +2360,546 @@ func TestMap_Set(t *testing.T) {
 		}
 	})
 
-	t.Run("VerySmall", func(t *testing.T) {
-		const n = 6
+	t.Run("VerySmall", func(t *testing.T) {
+		const n = 6
+		m := NewMap[int, int](nil)
+		for i := 0; i < n; i++ {
+			m = m.Set(i, i+1)
+		}
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(i); !ok || v != i+1 {
+				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
+			}
+		}
+
+		// NOTE: Array nodes store entries in insertion order.
+		itr := m.Iterator()
+		for i := 0; i < n; i++ {
+			if k, v, ok := itr.Next(); !ok || k != i || v != i+1 {
+				t.Fatalf("MapIterator.Next()=<%v,%v>, exp <%v,%v>", k, v, i, i+1)
+			}
+		}
+		if !itr.Done() {
+			t.Fatal("expected iterator done")
+		}
+	})
+
+	t.Run("Small", func(t *testing.T) {
+		const n = 1000
+		m := NewMap[int, int](nil)
+		for i := 0; i < n; i++ {
+			m = m.Set(i, i+1)
+		}
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(i); !ok || v != i+1 {
+				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
+			}
+		}
+	})
+
+	t.Run("Large", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skipping: short")
+		}
+
+		const n = 1000000
+		m := NewMap[int, int](nil)
+		for i := 0; i < n; i++ {
+			m = m.Set(i, i+1)
+		}
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(i); !ok || v != i+1 {
+				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
+			}
+		}
+	})
+
+	t.Run("StringKeys", func(t *testing.T) {
+		m := NewMap[string, string](nil)
+		m = m.Set("foo", "bar")
+		m = m.Set("baz", "bat")
+		m = m.Set("", "EMPTY")
+		if v, ok := m.Get("foo"); !ok || v != "bar" {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		} else if v, ok := m.Get("baz"); !ok || v != "bat" {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		} else if v, ok := m.Get(""); !ok || v != "EMPTY" {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		}
+		if v, ok := m.Get("no_such_key"); ok {
+			t.Fatalf("expected no value: <%v,%v>", v, ok)
+		}
+	})
+
+	RunRandom(t, "Random", func(t *testing.T, rand *rand.Rand) {
+		m := NewTestMap()
+		for i := 0; i < 10000; i++ {
+			switch rand.Intn(2) {
+			case 1: // overwrite
+				m.Set(m.ExistingKey(rand), rand.Intn(10000))
+			default: // set new key
+				m.Set(m.NewKey(rand), rand.Intn(10000))
+			}
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSortedMap_DeleteRange(t *testing.T) {
+	newMap := func(n int) *SortedMap[int, int] {
+		m := NewSortedMap[int, int](nil)
+		for i := 0; i < n; i++ {
+			m = m.Set(i, i)
+		}
+		return m
+	}
+
+	for _, tt := range []struct {
+		name   string
+		lo, hi int
+		exp    int
+	}{
+		{"Empty", 50, 50, 1000},
+		{"Inverted", 60, 50, 1000},
+		{"Middle", 100, 900, 200},
+		{"Whole", 0, 1000, 0},
+		{"Outside", -10, 2000, 0},
+		{"Below", -10, 0, 1000},
+		{"Above", 1000, 2000, 1000},
+		{"Single", 500, 501, 999},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMap(1000)
+			other := m.DeleteRange(tt.lo, tt.hi)
+			if other.Len() != tt.exp {
+				t.Fatalf("Len()=%d, expected %d", other.Len(), tt.exp)
+			} else if m.Len() != 1000 || len(m.Keys()) != 1000 {
+				t.Fatal("unexpected mutation of original map")
+			}
+			for _, k := range other.Keys() {
+				if k >= tt.lo && k < tt.hi {
+					t.Fatalf("unexpected key in deleted range: %d", k)
+				}
+			}
+			if len(other.Keys()) != tt.exp {
+				t.Fatalf("unexpected key count: %d", len(other.Keys()))
+			}
+		})
+	}
+
+	RunRandom(t, "Random", func(t *testing.T, rand *rand.Rand) {
+		m := NewTSortedMap()
+		for j := 0; j < 5000; j++ {
+			switch rand.Intn(50) {
+			case 0: // delete range
+				lo, hi := m.ExistingKey(rand), m.ExistingKey(rand)
+				m.DeleteRange(lo, hi)
+			default: // set new key
+				m.Set(m.NewKey(rand), rand.Intn(10000))
+			}
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatal(err)
+		} else if got, exp := m.im.Len(), len(m.std); got != exp {
+			t.Fatalf("Len()=%d, expected %d", got, exp)
+		}
+	})
+}
+
+func TestMap_Any(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		if k, v, ok := NewMap[int, int](nil).Any(); ok {
+			t.Fatalf("Any()=<%v,%v>, expected no value", k, v)
+		}
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		const n = 1000
+		m := NewMap[int, int](nil)
+		for i := 0; i < n; i++ {
+			m = m.Set(i, i*2)
+		}
+
+		seen := make(map[int]struct{})
+		for m.Len() > 0 {
+			k, v, ok := m.Any()
+			if !ok {
+				t.Fatal("expected value")
+			} else if v != k*2 {
+				t.Fatalf("unexpected value for key %d: %d", k, v)
+			}
+			seen[k] = struct{}{}
+			m = m.Delete(k)
+		}
+		if len(seen) != n {
+			t.Fatalf("unexpected number of keys: %d", len(seen))
+		}
+	})
+}
+
+func TestMap_KeysValues(t *testing.T) {
+	m := NewMap[int, int](nil)
+	for i := 0; i < 1000; i++ {
+		m = m.Set(i, i*10)
+	}
+
+	keys, values := m.Keys(), m.Values()
+	if len(keys) != 1000 || len(values) != 1000 {
+		t.Fatalf("unexpected lengths: %d/%d", len(keys), len(values))
+	}
+	sort.Ints(keys)
+	sort.Ints(values)
+	for i := 0; i < 1000; i++ {
+		if keys[i] != i || values[i] != i*10 {
+			t.Fatalf("%d. unexpected key/value: %d/%d", i, keys[i], values[i])
+		}
+	}
+}
+
+func TestMap_WithHasher(t *testing.T) {
+	m := NewMap[int, int](nil)
+	for i := 0; i < 1000; i++ {
+		m = m.Set(i, i*2)
+	}
+
+	h := &mockHasher[int]{
+		hash:  func(value int) uint32 { return uint32(value % 16) },
+		equal: func(a, b int) bool { return a == b },
+	}
+	other := m.WithHasher(h)
+	if other.hasher != h {
+		t.Fatal("expected new hasher")
+	} else if other.Len() != 1000 {
+		t.Fatalf("unexpected len: %d", other.Len())
+	}
+	for i := 0; i < 1000; i++ {
+		if v, ok := other.Get(i); !ok || v != i*2 {
+			t.Fatalf("Get(%d)=<%v,%v>", i, v, ok)
+		}
+	}
+	if _, ok := m.hasher.(*mockHasher[int]); ok {
+		t.Fatal("original map hasher changed")
+	}
+}
+
+// Ensure the default hasher is resolved when a map is created so that shared
+// empty maps are never modified.
+func TestMap_DefaultHasher(t *testing.T) {
+	if m := NewMap[string, int](nil); m.hasher == nil {
+		t.Fatal("expected map hasher")
+	}
+
+	// Types without a default are left unset until a key is inserted.
+	type key struct{ a int }
+	if m := NewMap[key, int](nil); m.hasher != nil {
+		t.Fatal("expected no map hasher")
+	}
+}
+
+func TestMap_SortedKeys(t *testing.T) {
+	m := NewMap[int, string](nil)
+	for _, i := range rand.New(rand.NewSource(0)).Perm(100) {
+		m = m.Set(i, strconv.Itoa(i))
+	}
+
+	keys := m.SortedKeys(nil)
+	for i, k := range keys {
+		if k != i {
+			t.Fatalf("unexpected key at %d: %d", i, k)
+		}
+	}
+	if keys := m.SortedKeys(ReverseComparer(NewComparer(0))); keys[0] != 99 {
+		t.Fatalf("unexpected first key: %d", keys[0])
+	}
+
+	var i int
+	m.EachSorted(nil, func(k int, v string) {
+		if k != i || v != strconv.Itoa(i) {
+			t.Fatalf("unexpected entry: <%v,%v>", k, v)
+		}
+		i++
+	})
+	if i != 100 {
+		t.Fatalf("unexpected count: %d", i)
+	}
+
+	empty := NewMap[int, string](nil)
+	if keys := empty.SortedKeys(nil); len(keys) != 0 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	empty.EachSorted(nil, func(k int, v string) { t.Fatal("unexpected callback") })
+}
+
+func TestMap_Pop(t *testing.T) {
+	m := NewMap[string, int](nil).Set("a", 1).Set("b", 2)
+	if v, other, ok := m.Pop("a"); !ok || v != 1 {
+		t.Fatalf("Pop()=<%v,%v>, expected <1,true>", v, ok)
+	} else if other.Len() != 1 || m.Len() != 2 {
+		t.Fatalf("unexpected len: %d/%d", other.Len(), m.Len())
+	} else if _, ok := other.Get("a"); ok {
+		t.Fatal("expected key to be removed")
+	}
+	if _, other, ok := m.Pop("z"); ok || other != m {
+		t.Fatal("expected original map for missing key")
+	}
+
+	b := NewMapBuilder[string, int](nil)
+	b.Set("a", 1)
+	if v, ok := b.Pop("a"); !ok || v != 1 {
+		t.Fatalf("Pop()=<%v,%v>, expected <1,true>", v, ok)
+	} else if _, ok := b.Pop("a"); ok || b.Len() != 0 {
+		t.Fatal("expected key to be removed")
+	}
+}
+
+func TestMap_Filter(t *testing.T) {
+	m := NewMap[int, int](nil)
+	for i := 0; i < 1000; i++ {
+		m = m.Set(i, i*10)
+	}
+
+	even := m.Filter(func(k, v int) bool { return k%2 == 0 })
+	if even.Len() != 500 || m.Len() != 1000 {
+		t.Fatalf("unexpected lengths: %d/%d", even.Len(), m.Len())
+	}
+	var sum int
+	even.Each(func(k, v int) {
+		if k%2 != 0 || v != k*10 {
+			t.Fatalf("unexpected pair: <%d,%d>", k, v)
+		}
+		sum += k
+	})
+	if sum != 249500 {
+		t.Fatalf("unexpected sum: %d", sum)
+	}
+
+	if odd := m.Reject(func(k, v int) bool { return k%2 == 0 }); odd.Len() != 500 {
+		t.Fatalf("unexpected rejected length: %d", odd.Len())
+	} else if _, ok := odd.Get(2); ok {
+		t.Fatal("expected even key to be rejected")
+	} else if odd.hasher != m.hasher {
+		t.Fatal("expected hasher to be preserved")
+	}
+
+	other := TransformMap(even, nil, func(k, v int) (string, int) { return strconv.Itoa(k), v + 1 })
+	if other.Len() != 500 {
+		t.Fatalf("unexpected transformed length: %d", other.Len())
+	} else if v, ok := other.Get("10"); !ok || v != 101 {
+		t.Fatalf("unexpected transformed value: %d, %v", v, ok)
+	}
+}
+
+func TestMap_KeysAndValues(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		keys, values := NewMap[int, int](nil).KeysAndValues()
+		if len(keys) != 0 || len(values) != 0 {
+			t.Fatalf("unexpected keys/values: %v/%v", keys, values)
+		}
+	})
+
+	t.Run("Large", func(t *testing.T) {
+		const n = 10000
 		m := NewMap[int, int](nil)
 		for i := 0; i < n; i++ {
-			m = m.Set(i, i+1)
+			m = m.Set(i, i*10)
 		}
-		for i := 0; i < n; i++ {
-			if v, ok := m.Get(i); !ok || v != i+1 {
-				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
+
+		keys, values := m.KeysAndValues()
+		if len(keys) != n || len(values) != n {
+			t.Fatalf("unexpected lengths: %d/%d", len(keys), len(values))
+		}
+		for i := range keys {
+			if values[i] != keys[i]*10 {
+				t.Fatalf("%d. unexpected value for key %d: %d", i, keys[i], values[i])
 			}
 		}
+	})
+}
 
-		// NOTE: Array nodes store entries in insertion order.
-		itr := m.Iterator()
-		for i := 0; i < n; i++ {
-			if k, v, ok := itr.Next(); !ok || k != i || v != i+1 {
-				t.Fatalf("MapIterator.Next()=<%v,%v>, exp <%v,%v>", k, v, i, i+1)
+func TestMap_JSON(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		m := NewMap[string, int](nil)
+		for i := 0; i < 100; i++ {
+			m = m.Set(fmt.Sprint(i), i)
+		}
+
+		buf, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var other Map[string, int]
+		if err := json.Unmarshal(buf, &other); err != nil {
+			t.Fatal(err)
+		} else if other.Len() != 100 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+		for i := 0; i < 100; i++ {
+			if v, ok := other.Get(fmt.Sprint(i)); !ok || v != i {
+				t.Fatalf("unexpected value: <%v,%v>", v, ok)
 			}
 		}
-		if !itr.Done() {
-			t.Fatal("expected iterator done")
+	})
+
+	t.Run("StringKind", func(t *testing.T) {
+		type Key string
+		m := NewMap[Key, string](nil).Set("foo", "bar")
+		if buf, err := json.Marshal(m); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `{"foo":"bar"}`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
 		}
 	})
 
-	t.Run("Small", func(t *testing.T) {
-		const n = 1000
-		m := NewMap[int, int](nil)
-		for i := 0; i < n; i++ {
-			m = m.Set(i, i+1)
+	t.Run("ErrNonStringKey", func(t *testing.T) {
+		if _, err := json.Marshal(NewMap[int, int](nil).Set(1, 2)); err == nil {
+			t.Fatal("expected error")
+		} else if _, err := json.Marshal(NewMap[int, int](nil)); err == nil {
+			t.Fatal("expected error for empty map")
 		}
-		for i := 0; i < n; i++ {
-			if v, ok := m.Get(i); !ok || v != i+1 {
-				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
-			}
+		var m Map[int, int]
+		if err := json.Unmarshal([]byte(`{"1":2}`), &m); err == nil || err.Error() != `immutable: cannot decode JSON object key into int key` {
+			t.Fatalf("unexpected error: %v", err)
 		}
 	})
 
-	t.Run("Large", func(t *testing.T) {
-		if testing.Short() {
-			t.Skip("skipping: short")
+	t.Run("InterfaceKey", func(t *testing.T) {
+		if buf, err := json.Marshal(NewMap[any, int](nil)); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `{}`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
 		}
+		if buf, err := json.Marshal(NewMap[any, int](nil).Set("foo", 1)); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `{"foo":1}`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
+		}
+		if _, err := json.Marshal(NewMap[any, int](nil).Set(1, 1)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
 
-		const n = 1000000
+func TestMap_Gob(t *testing.T) {
+	m := NewMap[int, string](nil)
+	for i := 0; i < 1000; i++ {
+		m = m.Set(i, fmt.Sprint(i))
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+
+	var other *Map[int, string]
+	if err := gob.NewDecoder(&buf).Decode(&other); err != nil {
+		t.Fatal(err)
+	} else if other.Len() != 1000 {
+		t.Fatalf("unexpected size: %d", other.Len())
+	}
+	for i := 0; i < 1000; i++ {
+		if v, ok := other.Get(i); !ok || v != fmt.Sprint(i) {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		}
+	}
+}
+
+func TestNewMapOf(t *testing.T) {
+	entries := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+
+	m := NewMapOf(nil, entries)
+	if m.Len() != 1000 {
+		t.Fatalf("unexpected len: %d", m.Len())
+	}
+	for k, v := range entries {
+		if got, ok := m.Get(k); !ok || got != v {
+			t.Fatalf("Get(%q)=<%v,%v>, expected %d", k, got, ok, v)
+		}
+	}
+
+	if m := NewMapOf[string, int](nil, nil); m.Len() != 0 || m.hasher == nil {
+		t.Fatal("expected empty map with default hasher")
+	}
+}
+
+func TestMapSetMany(t *testing.T) {
+	entries := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		entries[i] = i * 2
+	}
+
+	t.Run("Empty", func(t *testing.T) {
 		m := NewMap[int, int](nil)
-		for i := 0; i < n; i++ {
-			m = m.Set(i, i+1)
+		other := MapSetMany(m, entries)
+		if m.Len() != 0 {
+			t.Fatal("original map mutated")
+		} else if other.Len() != 1000 {
+			t.Fatalf("unexpected size: %d", other.Len())
 		}
-		for i := 0; i < n; i++ {
-			if v, ok := m.Get(i); !ok || v != i+1 {
-				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
+		for k, v := range entries {
+			if got, ok := other.Get(k); !ok || got != v {
+				t.Fatalf("unexpected value for key %d: <%v,%v>", k, got, ok)
 			}
 		}
+		if other := MapSetMany(other, nil); other.Len() != 1000 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
 	})
 
-	t.Run("StringKeys", func(t *testing.T) {
-		m := NewMap[string, string](nil)
-		m = m.Set("foo", "bar")
-		m = m.Set("baz", "bat")
-		m = m.Set("", "EMPTY")
-		if v, ok := m.Get("foo"); !ok || v != "bar" {
-			t.Fatalf("unexpected value: <%v,%v>", v, ok)
-		} else if v, ok := m.Get("baz"); !ok || v != "bat" {
-			t.Fatalf("unexpected value: <%v,%v>", v, ok)
-		} else if v, ok := m.Get(""); !ok || v != "EMPTY" {
-			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+	t.Run("Existing", func(t *testing.T) {
+		m := NewMap[int, int](nil)
+		for i := 500; i < 1500; i++ {
+			m = m.Set(i, -1)
 		}
-		if v, ok := m.Get("no_such_key"); ok {
-			t.Fatalf("expected no value: <%v,%v>", v, ok)
+		other := MapSetMany(m, entries)
+		if other.Len() != 1500 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+		for i := 500; i < 1500; i++ {
+			if v, _ := m.Get(i); v != -1 {
+				t.Fatalf("original map mutated: key=%d, value=%d", i, v)
+			}
+		}
+		if v, _ := other.Get(600); v != 1200 {
+			t.Fatalf("unexpected value: %d", v)
 		}
 	})
 
-	RunRandom(t, "Random", func(t *testing.T, rand *rand.Rand) {
-		m := NewTestMap()
-		for i := 0; i < 10000; i++ {
-			switch rand.Intn(2) {
-			case 1: // overwrite
-				m.Set(m.ExistingKey(rand), rand.Intn(10000))
-			default: // set new key
-				m.Set(m.NewKey(rand), rand.Intn(10000))
+	// Ensure every node type is copied before it is updated in-place.
+	t.Run("NodeTypes", func(t *testing.T) {
+		h := HasherFunc(func(k int) uint32 { return uint32(k % 300) }, func(a, b int) bool { return a == b })
+		for _, n := range []int{1, 5, 20, 200, 1000} {
+			m := NewMap[int, int](h)
+			for i := 0; i < n; i++ {
+				m = m.Set(i, -1)
+			}
+			other := MapSetMany(m, entries)
+			for i := 0; i < n; i++ {
+				if v, _ := m.Get(i); v != -1 {
+					t.Fatalf("n=%d: original map mutated: key=%d, value=%d", n, i, v)
+				} else if v, _ := other.Get(i); v != i*2 {
+					t.Fatalf("n=%d: unexpected value: key=%d, value=%d", n, i, v)
+				}
+			}
+			if m.Len() != n || other.Len() != 1000 {
+				t.Fatalf("n=%d: unexpected sizes: %d, %d", n, m.Len(), other.Len())
 			}
 		}
-		if err := m.Validate(); err != nil {
-			t.Fatal(err)
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		b := NewMapBuilder[int, int](nil)
+		b.Set(5000, 1)
+		MapBuilderSetMany(b, entries)
+		if b.Len() != 1001 {
+			t.Fatalf("unexpected size: %d", b.Len())
+		} else if v, _ := b.Get(999); v != 1998 {
+			t.Fatalf("unexpected value: %d", v)
 		}
 	})
 }
@@ -1189,6 +3016,56 @@ func TestMap_Delete(t *testing.T) {
 	})
 }
 
+func TestMapIterator_Remaining(t *testing.T) {
+	m := NewMap[int, int](nil)
+	for i := 0; i < 100; i++ {
+		m = m.Set(i, i)
+	}
+
+	itr := m.Iterator()
+	for i := 100; i > 0; i-- {
+		if n := itr.Remaining(); n != i {
+			t.Fatalf("unexpected remaining: %d, expected %d", n, i)
+		}
+		itr.Next()
+	}
+	if n := itr.Remaining(); n != 0 {
+		t.Fatalf("unexpected remaining: %d", n)
+	}
+
+	itr.Reset()
+	if n := itr.Remaining(); n != 100 {
+		t.Fatalf("unexpected remaining after reset: %d", n)
+	}
+}
+
+func TestMapIterator_Reset(t *testing.T) {
+	m := NewMap[int, int](nil)
+	for i := 0; i < 1000; i++ {
+		m = m.Set(i, i)
+	}
+
+	var keys []int
+	itr := m.Iterator()
+	for !itr.Done() {
+		k, _, _ := itr.Next()
+		keys = append(keys, k)
+	}
+
+	itr.Reset()
+	for i := 0; !itr.Done(); i++ {
+		if k, _, _ := itr.Next(); k != keys[i] {
+			t.Fatalf("unexpected key at %d: %d, expected %d", i, k, keys[i])
+		}
+	}
+
+	if itr := NewMap[int, int](nil).Iterator(); !itr.Done() {
+		t.Fatal("expected done")
+	} else if itr.Reset(); !itr.Done() {
+		t.Fatal("expected done after reset")
+	}
+}
+
 // Ensure map works even with hash conflicts.
 func TestMap_LimitedHash(t *testing.T) {
 	if testing.Short() {
@@ -1243,60 +3120,482 @@ func TestMap_LimitedHash(t *testing.T) {
 		for _, key := range keys {
 			m = m.Delete(key)
 		}
-		if m.Len() != 0 {
-			t.Fatalf("unexpected size: %d", m.Len())
+		if m.Len() != 0 {
+			t.Fatalf("unexpected size: %d", m.Len())
+		}
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		h := mockHasher[int]{
+			hash:  func(value int) uint32 { return hashUint64(uint64(value)) },
+			equal: func(a, b int) bool { return a == b },
+		}
+		b := NewMapBuilder[int, int](&h)
+
+		rand := rand.New(rand.NewSource(0))
+		keys := rand.Perm(100000)
+		for _, i := range keys {
+			b.Set(i, i) // initial set
+		}
+		for i := range keys {
+			b.Set(i, i*2) // overwrite
+		}
+		if b.Len() != len(keys) {
+			t.Fatalf("unexpected len: %d", b.Len())
+		}
+
+		// Verify all key/value pairs in map.
+		for i := 0; i < b.Len(); i++ {
+			if v, ok := b.Get(i); !ok || v != i*2 {
+				t.Fatalf("Get(%d)=<%v,%v>", i, v, ok)
+			}
+		}
+
+		// Verify iteration.
+		itr := b.Iterator()
+		for !itr.Done() {
+			if k, v, ok := itr.Next(); !ok || v != k*2 {
+				t.Fatalf("MapIterator.Next()=<%v,%v>, expected value %v", k, v, k*2)
+			}
+		}
+
+		// Verify not found works.
+		if _, ok := b.Get(10000000); ok {
+			t.Fatal("expected no value")
+		}
+
+		// Remove all keys.
+		for _, key := range keys {
+			b.Delete(key)
+		}
+		if b.Len() != 0 {
+			t.Fatalf("unexpected size: %d", b.Len())
+		}
+	})
+}
+
+// Ensure fully built collections can be read by multiple goroutines at once.
+// Run with -race to detect any hidden shared state.
+func TestConcurrentReads(t *testing.T) {
+	const n = 1000
+	l := NewListBuilder[int]()
+	m := NewMapBuilder[int, int](nil)
+	sm := NewSortedMapBuilder[int, int](nil)
+	for i := 0; i < n; i++ {
+		l.Append(i)
+		m.Set(i, i)
+		sm.Set(i, i)
+	}
+	list, hmap, smap := l.List(), m.Map(), sm.Map()
+	set, sset := NewSet[int](nil, 1, 2, 3), NewSortedSet[int](nil, 1, 2, 3)
+	empty := NewSortedMap[int, int](nil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if list.Get(i) != i {
+					t.Errorf("unexpected list value at %d", i)
+				} else if v, _ := hmap.Get(i); v != i {
+					t.Errorf("unexpected map value at %d", i)
+				} else if v, _ := smap.Get(i); v != i {
+					t.Errorf("unexpected sorted map value at %d", i)
+				}
+			}
+			if list.Len() != n || hmap.Len() != n || smap.Len() != n {
+				t.Error("unexpected len")
+			} else if !set.Has(2) || !sset.Has(3) {
+				t.Error("expected set value")
+			}
+			for itr := list.Iterator(); !itr.Done(); {
+				itr.Next()
+			}
+			for itr := hmap.Iterator(); !itr.Done(); {
+				itr.Next()
+			}
+			for itr := smap.Iterator(); !itr.Done(); {
+				itr.Next()
+			}
+
+			// Derive new maps from a shared empty map.
+			if other := empty.Set(g, g); other.Len() != 1 {
+				t.Error("unexpected derived len")
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestMapBuilder_SetNew(t *testing.T) {
+	b := NewMapBuilder[string, int](nil)
+	if !b.SetNew("foo", 1) {
+		t.Fatal("expected new key to be set")
+	} else if b.SetNew("foo", 2) {
+		t.Fatal("expected existing key to not be set")
+	} else if !b.SetNew("bar", 3) {
+		t.Fatal("expected new key to be set")
+	}
+
+	m := b.Map()
+	if v, ok := m.Get("foo"); !ok || v != 1 {
+		t.Fatalf("unexpected value: <%v,%v>", v, ok)
+	} else if v, ok := m.Get("bar"); !ok || v != 3 {
+		t.Fatalf("unexpected value: <%v,%v>", v, ok)
+	}
+}
+
+func TestMapBuilder_Merge(t *testing.T) {
+	other := NewMap[string, int](nil).Set("a", 1).Set("b", 2)
+
+	b := NewMapBuilder[string, int](nil)
+	b.Set("b", 10)
+	b.Set("c", 3)
+	b.Merge(other)
+	if b.Len() != 3 {
+		t.Fatalf("unexpected len: %d", b.Len())
+	} else if v, _ := b.Get("b"); v != 2 {
+		t.Fatalf("unexpected value: %d", v)
+	} else if v, _ := b.Get("a"); v != 1 {
+		t.Fatalf("unexpected value: %d", v)
+	}
+
+	b = NewMapBuilder[string, int](nil)
+	b.Set("b", 10)
+	b.MergeFunc(other, func(key string, existing, value int) int { return existing + value })
+	if v, _ := b.Get("b"); v != 12 {
+		t.Fatalf("unexpected value: %d", v)
+	} else if v, _ := b.Get("a"); v != 1 {
+		t.Fatalf("unexpected value: %d", v)
+	}
+
+	// Ensure the source map is not modified.
+	if other.Len() != 2 {
+		t.Fatalf("unexpected source len: %d", other.Len())
+	} else if v, _ := other.Get("b"); v != 2 {
+		t.Fatalf("source map changed: %d", v)
+	}
+}
+
+func TestMapBuilder_Snapshot(t *testing.T) {
+	b := NewMapBuilder[int, int](nil)
+	for i := 0; i < 100; i++ {
+		b.Set(i, i)
+	}
+
+	snap := b.Snapshot()
+	b.Set(0, -1)
+	b.Set(100, 100)
+	b.Delete(50)
+	if !b.Valid() {
+		t.Fatal("expected builder to remain valid")
+	} else if b.Len() != 100 {
+		t.Fatalf("unexpected builder len: %d", b.Len())
+	}
+
+	// Ensure the snapshot is unaffected by changes to the builder.
+	if snap.Len() != 100 {
+		t.Fatalf("unexpected snapshot len: %d", snap.Len())
+	}
+	for i := 0; i < 100; i++ {
+		if v, ok := snap.Get(i); !ok || v != i {
+			t.Fatalf("snapshot Get(%d)=<%v,%v>", i, v, ok)
+		}
+	}
+
+	snap2 := b.Snapshot()
+	b.Clear()
+	if v, _ := snap2.Get(0); v != -1 {
+		t.Fatalf("unexpected value: %d", v)
+	} else if _, ok := snap2.Get(50); ok {
+		t.Fatal("expected key to be deleted")
+	} else if snap2.Len() != 100 || b.Len() != 0 {
+		t.Fatalf("unexpected len: %d/%d", snap2.Len(), b.Len())
+	}
+}
+
+func TestBuilders_Restore(t *testing.T) {
+	t.Run("Map", func(t *testing.T) {
+		b := NewMapBuilder[int, int](nil)
+		for i := 0; i < 100; i++ {
+			b.Set(i, i)
+		}
+
+		// Roll back changes made after the snapshot.
+		snap := b.Snapshot()
+		b.Set(0, -1)
+		b.Delete(1)
+		b.Restore(snap)
+		if v, _ := b.Get(0); v != 0 {
+			t.Fatalf("unexpected restored value: %d", v)
+		} else if _, ok := b.Get(1); !ok || b.Len() != 100 {
+			t.Fatalf("unexpected restored len: %d", b.Len())
+		}
+
+		// Ensure the builder can be mutated again without affecting the snapshot.
+		b.Set(2, -2)
+		b.Delete(3)
+		if v, _ := snap.Get(2); v != 2 {
+			t.Fatalf("snapshot changed: %d", v)
+		} else if _, ok := snap.Get(3); !ok || snap.Len() != 100 {
+			t.Fatalf("unexpected snapshot len: %d", snap.Len())
+		} else if v, _ := b.Get(2); v != -2 || b.Len() != 99 {
+			t.Fatalf("unexpected builder state: value=%d, len=%d", v, b.Len())
+		}
+	})
+
+	t.Run("SortedMap", func(t *testing.T) {
+		b := NewSortedMapBuilder[int, int](nil)
+		for i := 0; i < 100; i++ {
+			b.Set(i, i)
+		}
+
+		// Roll back changes made after the build.
+		m := b.Build()
+		b.Set(0, -1)
+		b.DeleteRange(10, 20)
+		b.Restore(m)
+		if v, _ := b.Get(0); v != 0 {
+			t.Fatalf("unexpected restored value: %d", v)
+		} else if _, ok := b.Get(15); !ok || b.Len() != 100 {
+			t.Fatalf("unexpected restored len: %d", b.Len())
+		}
+
+		// Ensure the builder can be mutated again without affecting the map.
+		b.Set(2, -2)
+		b.Delete(3)
+		if v, _ := m.Get(2); v != 2 {
+			t.Fatalf("built map changed: %d", v)
+		} else if _, ok := m.Get(3); !ok || m.Len() != 100 {
+			t.Fatalf("unexpected built map len: %d", m.Len())
+		} else if v, _ := b.Get(2); v != -2 || b.Len() != 99 {
+			t.Fatalf("unexpected builder state: value=%d, len=%d", v, b.Len())
+		}
+	})
+}
+
+func TestBuilders_Build(t *testing.T) {
+	t.Run("List", func(t *testing.T) {
+		b := NewListBuilderWithCapacity[int](100)
+		for i := 0; i < 10; i++ {
+			b.Append(i)
+		}
+		l := b.Build()
+		b.Set(0, -1)
+		b.Append(10)
+		if l.Len() != 10 || l.Get(0) != 0 {
+			t.Fatalf("built list changed: len=%d, first=%d", l.Len(), l.Get(0))
+		} else if b.Len() != 11 || b.Get(0) != -1 {
+			t.Fatalf("unexpected builder state: len=%d, first=%d", b.Len(), b.Get(0))
+		}
+		if err := l.Validate(); err != nil {
+			t.Fatal(err)
+		} else if l2 := b.List(); l2.Len() != 11 {
+			t.Fatalf("unexpected len: %d", l2.Len())
+		}
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		b := NewMapBuilder[int, int](nil)
+		b.Set(1, 1)
+		m := b.Build()
+		b.Set(1, 2)
+		if v, _ := m.Get(1); v != 1 {
+			t.Fatalf("built map changed: %d", v)
+		} else if v, _ := b.Get(1); v != 2 {
+			t.Fatalf("unexpected builder value: %d", v)
+		}
+	})
+
+	t.Run("SortedMap", func(t *testing.T) {
+		b := NewSortedMapBuilder[int, int](nil)
+		for i := 0; i < 100; i++ {
+			b.Set(i, i)
+		}
+		m := b.Build()
+		b.Set(0, -1)
+		b.Delete(50)
+		b.DeleteRange(60, 70)
+		if m.Len() != 100 {
+			t.Fatalf("built map changed: len=%d", m.Len())
+		}
+		for i := 0; i < 100; i++ {
+			if v, ok := m.Get(i); !ok || v != i {
+				t.Fatalf("built map Get(%d)=<%v,%v>", i, v, ok)
+			}
+		}
+		if b.Len() != 89 {
+			t.Fatalf("unexpected builder len: %d", b.Len())
+		} else if v, _ := b.Get(0); v != -1 {
+			t.Fatalf("unexpected builder value: %d", v)
 		}
 	})
+}
 
-	t.Run("Builder", func(t *testing.T) {
-		h := mockHasher[int]{
-			hash:  func(value int) uint32 { return hashUint64(uint64(value)) },
-			equal: func(a, b int) bool { return a == b },
+func TestBuilders_BuildPathCopy(t *testing.T) {
+	t.Run("Map", func(t *testing.T) {
+		b := NewMapBuilder[int, int](nil)
+		for i := 0; i < 1000; i++ {
+			b.Set(i, i)
+		}
+
+		// Only the path to the updated key should be copied.
+		m := b.Build()
+		b.Set(0, -1)
+		root, other := m.root.(*mapHashArrayNode[int, int]), b.m.root.(*mapHashArrayNode[int, int])
+		var shared int
+		for i := range root.nodes {
+			if root.nodes[i] != nil && root.nodes[i] == other.nodes[i] {
+				shared++
+			}
+		}
+		if shared == 0 {
+			t.Fatal("expected unchanged nodes to be shared")
 		}
-		b := NewMapBuilder[int, int](&h)
 
-		rand := rand.New(rand.NewSource(0))
-		keys := rand.Perm(100000)
-		for _, i := range keys {
-			b.Set(i, i) // initial set
+		// Interleave updates and snapshots and ensure no snapshot changes.
+		entries := func(m *Map[int, int]) map[int]int {
+			a := make(map[int]int)
+			for itr := m.Iterator(); !itr.Done(); {
+				k, v, _ := itr.Next()
+				a[k] = v
+			}
+			return a
 		}
-		for i := range keys {
-			b.Set(i, i*2) // overwrite
+		rand := rand.New(rand.NewSource(0))
+		std := entries(b.m)
+		snapshots, exp := []*Map[int, int]{m}, []string{fmt.Sprint(entries(m))}
+		for i := 0; i < 100; i++ {
+			for j := 0; j < 20; j++ {
+				switch k, v := rand.Intn(2000), rand.Int(); rand.Intn(5) {
+				case 0:
+					b.Delete(k)
+					delete(std, k)
+				case 1:
+					if b.SetNew(k, v) {
+						std[k] = v
+					}
+				case 2:
+					b.Merge(NewMap[int, int](nil).Set(k, v).Set(k+1, v))
+					std[k], std[k+1] = v, v
+				case 3:
+					MapBuilderSetMany(b, map[int]int{k: v})
+					std[k] = v
+				default:
+					b.Set(k, v)
+					std[k] = v
+				}
+			}
+			snapshot := b.Snapshot()
+			if got, want := fmt.Sprint(entries(snapshot)), fmt.Sprint(std); got != want {
+				t.Fatalf("%d: unexpected builder values", i)
+			}
+			snapshots, exp = append(snapshots, snapshot), append(exp, fmt.Sprint(entries(snapshot)))
 		}
-		if b.Len() != len(keys) {
-			t.Fatalf("unexpected len: %d", b.Len())
+		for i := range snapshots {
+			if got := fmt.Sprint(entries(snapshots[i])); got != exp[i] {
+				t.Fatalf("snapshot %d changed", i)
+			}
 		}
+	})
 
-		// Verify all key/value pairs in map.
-		for i := 0; i < b.Len(); i++ {
-			if v, ok := b.Get(i); !ok || v != i*2 {
-				t.Fatalf("Get(%d)=<%v,%v>", i, v, ok)
-			}
+	t.Run("SortedMap", func(t *testing.T) {
+		b := NewSortedMapBuilder[int, int](nil)
+		for i := 0; i < 1000; i++ {
+			b.Set(i, i)
 		}
 
-		// Verify iteration.
-		itr := b.Iterator()
-		for !itr.Done() {
-			if k, v, ok := itr.Next(); !ok || v != k*2 {
-				t.Fatalf("MapIterator.Next()=<%v,%v>, expected value %v", k, v, k*2)
+		// Only the path to the updated key should be copied.
+		m := b.Build()
+		b.Set(0, -1)
+		root, other := m.root.(*sortedMapBranchNode[int, int]), b.m.root.(*sortedMapBranchNode[int, int])
+		var shared int
+		for i := range root.elems {
+			if root.elems[i].node == other.elems[i].node {
+				shared++
 			}
 		}
-
-		// Verify not found works.
-		if _, ok := b.Get(10000000); ok {
-			t.Fatal("expected no value")
+		if shared == 0 {
+			t.Fatal("expected unchanged nodes to be shared")
 		}
 
-		// Remove all keys.
-		for _, key := range keys {
-			b.Delete(key)
+		// Interleave updates and snapshots and ensure no snapshot changes.
+		entries := func(m *SortedMap[int, int]) map[int]int {
+			a := make(map[int]int)
+			for itr := m.Iterator(); !itr.Done(); {
+				k, v, _ := itr.Next()
+				a[k] = v
+			}
+			return a
 		}
-		if b.Len() != 0 {
-			t.Fatalf("unexpected size: %d", b.Len())
+		rand := rand.New(rand.NewSource(0))
+		std := entries(b.m)
+		snapshots, exp := []*SortedMap[int, int]{m}, []string{fmt.Sprint(entries(m))}
+		for i := 0; i < 100; i++ {
+			for j := 0; j < 20; j++ {
+				switch k, v := rand.Intn(2000), rand.Int(); rand.Intn(4) {
+				case 0:
+					b.Delete(k)
+					delete(std, k)
+				case 1:
+					hi := k + rand.Intn(100)
+					b.DeleteRange(k, hi)
+					for key := range std {
+						if key >= k && key < hi {
+							delete(std, key)
+						}
+					}
+				case 2:
+					SortedMapBuilderSetMany(b, map[int]int{k: v})
+					std[k] = v
+				default:
+					b.Set(k, v)
+					std[k] = v
+				}
+			}
+			snapshot := b.Build()
+			if got, want := fmt.Sprint(entries(snapshot)), fmt.Sprint(std); got != want {
+				t.Fatalf("%d: unexpected builder values", i)
+			}
+			snapshots, exp = append(snapshots, snapshot), append(exp, fmt.Sprint(entries(snapshot)))
+		}
+		for i := range snapshots {
+			if got := fmt.Sprint(entries(snapshots[i])); got != exp[i] {
+				t.Fatalf("snapshot %d changed", i)
+			}
 		}
 	})
 }
 
+func TestMapBuilder_Valid(t *testing.T) {
+	b := NewMapBuilder[int, int](nil)
+	if !b.Valid() {
+		t.Fatal("expected builder to be valid")
+	}
+	b.Map()
+	if b.Valid() {
+		t.Fatal("expected builder to be invalid")
+	}
+
+	var r string
+	func() {
+		defer func() { r = recover().(string) }()
+		b.Set(1, 1)
+	}()
+	if r != `immutable.MapBuilder: builder invalid after Map() invocation` {
+		t.Fatalf("unexpected panic: %q", r)
+	}
+
+	func() {
+		defer func() { r = recover().(string) }()
+		b.Map()
+	}()
+	if r != `immutable.MapBuilder.Map(): duplicate call to fetch map` {
+		t.Fatalf("unexpected panic: %q", r)
+	}
+}
+
 // TMap represents a combined immutable and stdlib map.
 type TMap struct {
 	im, prev *Map[int, int]
@@ -1797,6 +4096,180 @@ func TestInternalSortedMapBranchNode(t *testing.T) {
 	})
 }
 
+func TestNewSortedMapOf(t *testing.T) {
+	entries := make(map[int]string)
+	for i := 0; i < 1000; i++ {
+		entries[i] = strconv.Itoa(i)
+	}
+
+	m := NewSortedMapOf(nil, entries)
+	if m.Len() != 1000 {
+		t.Fatalf("unexpected len: %d", m.Len())
+	}
+	itr := m.Iterator()
+	for i := 0; !itr.Done(); i++ {
+		if k, v, _ := itr.Next(); k != i || v != strconv.Itoa(i) {
+			t.Fatalf("unexpected entry: <%v,%v>", k, v)
+		}
+	}
+
+	if m := NewSortedMapOf(ReverseComparer(NewComparer(0)), entries); m.Len() != 1000 {
+		t.Fatalf("unexpected len: %d", m.Len())
+	} else if k, _, _ := m.Min(); k != 999 {
+		t.Fatalf("unexpected min key: %d", k)
+	}
+}
+
+func TestNewSortedMapBuilderFromSorted(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 1025, 40000} {
+		keys, values := make([]int, n), make([]int, n)
+		for i := range keys {
+			keys[i], values[i] = i*2, i
+		}
+
+		b := NewSortedMapBuilderFromSorted(nil, keys, values)
+		if b.Len() != n {
+			t.Fatalf("n=%d: unexpected len: %d", n, b.Len())
+		}
+		m := b.Build()
+		itr := m.Iterator()
+		for i := 0; !itr.Done(); i++ {
+			if k, v, _ := itr.Next(); k != i*2 || v != i {
+				t.Fatalf("n=%d: unexpected entry: <%v,%v>", n, k, v)
+			}
+		}
+		for i := 0; i < n; i += 7 {
+			if got := m.Rank(i * 2); got != i {
+				t.Fatalf("n=%d: Rank(%d)=%d, expected %d", n, i*2, got, i)
+			}
+		}
+
+		// Ensure the builder can continue to insert into the loaded tree.
+		for i := 0; i < n; i += 3 {
+			b.Set(i*2+1, -1)
+		}
+		b.Delete(0)
+		if v, ok := b.Get(1); n > 0 && (!ok || v != -1) {
+			t.Fatalf("n=%d: unexpected value: <%v,%v>", n, v, ok)
+		} else if m.Len() != n {
+			t.Fatalf("n=%d: built map changed", n)
+		}
+	}
+
+	for _, tt := range []struct {
+		fn  func()
+		exp string
+	}{
+		{func() { NewSortedMapBuilderFromSorted(nil, []int{1, 2}, []int{1}) }, `immutable.NewSortedMapBuilderFromSorted: key count 2 does not match value count 1`},
+		{func() { NewSortedMapBuilderFromSorted(nil, []int{1, 3, 3}, []int{1, 2, 3}) }, `immutable.NewSortedMapBuilderFromSorted: key at index 2 not in increasing order`},
+	} {
+		var r string
+		func() {
+			defer func() { r = fmt.Sprint(recover()) }()
+			tt.fn()
+		}()
+		if r != tt.exp {
+			t.Fatalf("unexpected panic: %q, expected %q", r, tt.exp)
+		}
+	}
+}
+
+func TestSortedMapSetMany(t *testing.T) {
+	entries := make(map[int]int)
+	for i := 0; i < 1000; i++ {
+		entries[i] = i * 2
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		m := NewSortedMap[int, int](nil)
+		other := SortedMapSetMany(m, entries)
+		if m.Len() != 0 {
+			t.Fatal("original map mutated")
+		} else if other.Len() != 1000 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+
+		itr := other.Iterator()
+		for i := 0; !itr.Done(); i++ {
+			if k, v, _ := itr.Next(); k != i || v != i*2 {
+				t.Fatalf("unexpected entry: <%v,%v>", k, v)
+			}
+		}
+		if other := SortedMapSetMany(other, nil); other.Len() != 1000 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+	})
+
+	t.Run("Existing", func(t *testing.T) {
+		m := NewSortedMap[int, int](nil)
+		for i := 500; i < 1500; i++ {
+			m = m.Set(i, -1)
+		}
+		other := SortedMapSetMany(m, entries)
+		if other.Len() != 1500 {
+			t.Fatalf("unexpected size: %d", other.Len())
+		}
+		for i := 500; i < 1500; i++ {
+			if v, _ := m.Get(i); v != -1 {
+				t.Fatalf("original map mutated: key=%d, value=%d", i, v)
+			}
+		}
+		if v, _ := other.Get(600); v != 1200 {
+			t.Fatalf("unexpected value: %d", v)
+		} else if v, _ := other.Get(1200); v != -1 {
+			t.Fatalf("unexpected value: %d", v)
+		}
+	})
+
+	// Ensure shared leaves and branches are copied before they are updated.
+	t.Run("Interleaved", func(t *testing.T) {
+		for _, n := range []int{1, 10, 100, 2000} {
+			m := NewSortedMap[int, int](nil)
+			for i := 0; i < n; i++ {
+				m = m.Set(i*2-1, -1)
+			}
+			other := SortedMapSetMany(m, entries)
+
+			keys := m.Keys()
+			if len(keys) != n || m.Len() != n {
+				t.Fatalf("n=%d: original map resized", n)
+			}
+			for _, k := range keys {
+				if v, _ := m.Get(k); k%2 == 0 || v != -1 {
+					t.Fatalf("n=%d: original map mutated: key=%d, value=%d", n, k, v)
+				}
+			}
+			itr := other.Iterator()
+			for i := 0; !itr.Done(); i++ {
+				if k, _, _ := itr.Next(); other.Rank(k) != i {
+					t.Fatalf("n=%d: unexpected rank for %d: %d", n, k, other.Rank(k))
+				}
+			}
+			union := make(map[int]struct{})
+			for _, k := range keys {
+				union[k] = struct{}{}
+			}
+			for k := range entries {
+				union[k] = struct{}{}
+			}
+			if other.Len() != len(union) {
+				t.Fatalf("n=%d: unexpected size: %d, expected %d", n, other.Len(), len(union))
+			}
+		}
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		b := NewSortedMapBuilder[int, int](nil)
+		b.Set(5000, 1)
+		SortedMapBuilderSetMany(b, entries)
+		if b.Len() != 1001 {
+			t.Fatalf("unexpected size: %d", b.Len())
+		} else if v, _ := b.Get(999); v != 1998 {
+			t.Fatalf("unexpected value: %d", v)
+		}
+	})
+}
+
 func TestSortedMap_Get(t *testing.T) {
 	t.Run("Empty", func(t *testing.T) {
 		m := NewSortedMap[int, int](nil)
@@ -1806,6 +4279,38 @@ func TestSortedMap_Get(t *testing.T) {
 	})
 }
 
+func TestSortedMap_GetOrDefault(t *testing.T) {
+	m := NewSortedMap[string, int](nil)
+	if v := m.GetOrDefault("foo", 10); v != 10 {
+		t.Fatalf("GetOrDefault()=%d, expected 10", v)
+	}
+	m = m.Set("foo", 1)
+	if v := m.GetOrDefault("foo", 10); v != 1 {
+		t.Fatalf("GetOrDefault()=%d, expected 1", v)
+	} else if v := m.GetOrElse("foo", func() int { panic("unexpected call") }); v != 1 {
+		t.Fatalf("GetOrElse()=%d, expected 1", v)
+	} else if v := m.GetOrElse("bar", func() int { return 20 }); v != 20 {
+		t.Fatalf("GetOrElse()=%d, expected 20", v)
+	}
+}
+
+// Ensure the default comparer is resolved when a sorted map is created so that
+// shared empty maps are never modified.
+func TestSortedMap_DefaultComparer(t *testing.T) {
+	if m := NewSortedMap[int, int](nil); m.comparer == nil {
+		t.Fatal("expected sorted map comparer")
+	} else if m := NewSortedMap[[]byte, int](nil); m.comparer == nil {
+		t.Fatal("expected sorted map comparer")
+	}
+
+	// Types without a default are left unset until a key is inserted.
+	if m := NewSortedMap[any, int](nil); m.comparer != nil {
+		t.Fatal("expected no sorted map comparer")
+	} else if other := m.Set(1, 1); other.comparer == nil || m.comparer != nil {
+		t.Fatal("expected comparer only on derived map")
+	}
+}
+
 func TestSortedMap_Set(t *testing.T) {
 	t.Run("Simple", func(t *testing.T) {
 		m := NewSortedMap[int, string](nil)
@@ -1835,63 +4340,291 @@ func TestSortedMap_Set(t *testing.T) {
 			t.Skip("skipping: short")
 		}
 
-		const n = 1000000
-		m := NewSortedMap[int, int](nil)
-		for i := 0; i < n; i++ {
-			m = m.Set(i, i+1)
+		const n = 1000000
+		m := NewSortedMap[int, int](nil)
+		for i := 0; i < n; i++ {
+			m = m.Set(i, i+1)
+		}
+		for i := 0; i < n; i++ {
+			if v, ok := m.Get(i); !ok || v != i+1 {
+				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
+			}
+		}
+	})
+
+	t.Run("StringKeys", func(t *testing.T) {
+		m := NewSortedMap[string, string](nil)
+		m = m.Set("foo", "bar")
+		m = m.Set("baz", "bat")
+		m = m.Set("", "EMPTY")
+		if v, ok := m.Get("foo"); !ok || v != "bar" {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		} else if v, ok := m.Get("baz"); !ok || v != "bat" {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		} else if v, ok := m.Get(""); !ok || v != "EMPTY" {
+			t.Fatalf("unexpected value: <%v,%v>", v, ok)
+		}
+		if v, ok := m.Get("no_such_key"); ok {
+			t.Fatalf("expected no value: <%v,%v>", v, ok)
+		}
+	})
+
+	t.Run("NoDefaultComparer", func(t *testing.T) {
+		var r string
+		func() {
+			defer func() { r = recover().(string) }()
+			m := NewSortedMap[float64, string](nil)
+			m = m.Set(float64(100), "bar")
+		}()
+		if r != `immutable.NewComparer: must set comparer for float64 type` {
+			t.Fatalf("unexpected panic: %q", r)
+		}
+	})
+
+	RunRandom(t, "Random", func(t *testing.T, rand *rand.Rand) {
+		m := NewTSortedMap()
+		for j := 0; j < 10000; j++ {
+			switch rand.Intn(2) {
+			case 1: // overwrite
+				m.Set(m.ExistingKey(rand), rand.Intn(10000))
+			default: // set new key
+				m.Set(m.NewKey(rand), rand.Intn(10000))
+			}
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestSortedMap_KeysValues(t *testing.T) {
+	m := NewSortedMap[int, string](nil)
+	if keys := m.Keys(); len(keys) != 0 {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+	m = m.Set(3, "c").Set(1, "a").Set(2, "b")
+	if got, exp := fmt.Sprint(m.Keys()), "[1 2 3]"; got != exp {
+		t.Fatalf("Keys()=%s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(m.Values()), "[a b c]"; got != exp {
+		t.Fatalf("Values()=%s, expected %s", got, exp)
+	}
+}
+
+func TestSortedMap_MinMax(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	if _, _, ok := m.Min(); ok {
+		t.Fatal("expected no min for empty map")
+	} else if _, _, ok := m.Max(); ok {
+		t.Fatal("expected no max for empty map")
+	}
+
+	for _, i := range rand.Perm(1000) {
+		m = m.Set(i, i*10)
+	}
+	if k, v, ok := m.Min(); !ok || k != 0 || v != 0 {
+		t.Fatalf("Min()=<%v,%v,%v>", k, v, ok)
+	} else if k, v, ok := m.Max(); !ok || k != 999 || v != 9990 {
+		t.Fatalf("Max()=<%v,%v,%v>", k, v, ok)
+	}
+}
+
+func TestSortedMap_Pop(t *testing.T) {
+	m := NewSortedMap[string, int](nil).Set("a", 1).Set("b", 2)
+	if v, other, ok := m.Pop("b"); !ok || v != 2 {
+		t.Fatalf("Pop()=<%v,%v>, expected <2,true>", v, ok)
+	} else if other.Len() != 1 || m.Len() != 2 {
+		t.Fatalf("unexpected len: %d/%d", other.Len(), m.Len())
+	}
+	if _, other, ok := m.Pop("z"); ok || other != m {
+		t.Fatal("expected original map for missing key")
+	}
+
+	b := NewSortedMapBuilder[string, int](nil)
+	b.Set("a", 1)
+	if v, ok := b.Pop("a"); !ok || v != 1 {
+		t.Fatalf("Pop()=<%v,%v>, expected <1,true>", v, ok)
+	} else if _, ok := b.Pop("a"); ok || b.Len() != 0 {
+		t.Fatal("expected key to be removed")
+	}
+}
+
+func TestSortedMap_Filter(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	for _, i := range rand.Perm(100) {
+		m = m.Set(i, i*10)
+	}
+
+	filtered := m.Filter(func(k, v int) bool { return k >= 90 })
+	var keys []int
+	filtered.Each(func(k, v int) { keys = append(keys, k) })
+	if got, exp := fmt.Sprint(keys), "[90 91 92 93 94 95 96 97 98 99]"; got != exp {
+		t.Fatalf("Each()=%s, expected %s", got, exp)
+	} else if m.Len() != 100 {
+		t.Fatalf("unexpected mutation of map")
+	}
+
+	if rejected := m.Reject(func(k, v int) bool { return k >= 10 }); fmt.Sprint(rejected.Keys()) != "[0 1 2 3 4 5 6 7 8 9]" {
+		t.Fatalf("unexpected rejected keys: %v", rejected.Keys())
+	}
+
+	other := TransformSortedMap(filtered, nil, func(k, v int) (int, string) { return -k, strconv.Itoa(v) })
+	if got, exp := fmt.Sprint(other.Keys()), "[-99 -98 -97 -96 -95 -94 -93 -92 -91 -90]"; got != exp {
+		t.Fatalf("Keys()=%s, expected %s", got, exp)
+	} else if v, _ := other.Get(-95); v != "950" {
+		t.Fatalf("unexpected transformed value: %s", v)
+	}
+}
+
+func TestSortedMap_RankSelect(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	for i := 0; i < 10000; i += 2 {
+		m = m.Set(i, i*10)
+	}
+
+	for _, tt := range []struct{ key, rank int }{
+		{-1, 0}, {0, 0}, {1, 1}, {2, 1}, {5000, 2500}, {9998, 4999}, {9999, 5000}, {20000, 5000},
+	} {
+		if got := m.Rank(tt.key); got != tt.rank {
+			t.Fatalf("Rank(%d)=%d, expected %d", tt.key, got, tt.rank)
+		}
+	}
+
+	for i := 0; i < m.Len(); i++ {
+		if k, v, ok := m.Select(i); !ok || k != i*2 || v != i*20 {
+			t.Fatalf("Select(%d)=<%v,%v,%v>", i, k, v, ok)
+		}
+	}
+	if _, _, ok := m.Select(-1); ok {
+		t.Fatal("expected no entry")
+	} else if _, _, ok := m.Select(m.Len()); ok {
+		t.Fatal("expected no entry")
+	}
+
+	// Ensure subtree sizes are maintained across deletes.
+	other := m.DeleteRange(1000, 2000).Delete(0)
+	if got := other.Rank(2000); got != 499 {
+		t.Fatalf("Rank()=%d, expected 499", got)
+	} else if k, _, _ := other.Select(499); k != 2000 {
+		t.Fatalf("Select()=%d, expected 2000", k)
+	} else if got := m.Rank(2000); got != 1000 {
+		t.Fatalf("original map changed: Rank()=%d", got)
+	}
+
+	if got := NewSortedMap[int, int](nil).Rank(10); got != 0 {
+		t.Fatalf("Rank()=%d, expected 0", got)
+	}
+}
+
+func TestSortedMap_FloorCeil(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	if _, _, ok := m.Floor(1); ok {
+		t.Fatal("expected no floor for empty map")
+	} else if _, _, ok := m.Ceil(1); ok {
+		t.Fatal("expected no ceil for empty map")
+	}
+
+	// Insert even keys so odd keys fall between entries.
+	for _, i := range rand.Perm(500) {
+		m = m.Set(i*2, i)
+	}
+
+	for key := -1; key <= 1001; key++ {
+		floor, ceil := key-key%2, key+key%2
+		if key < 0 {
+			floor, ceil = -2, 0
+		} else if key > 998 {
+			floor = 998
+		}
+
+		if k, v, ok := m.Floor(key); floor < 0 && ok {
+			t.Fatalf("Floor(%d): unexpected key %d", key, k)
+		} else if floor >= 0 && (!ok || k != floor || v != floor/2) {
+			t.Fatalf("Floor(%d)=<%v,%v,%v>, expected %d", key, k, v, ok, floor)
+		}
+
+		if k, v, ok := m.Ceil(key); ceil > 998 && ok {
+			t.Fatalf("Ceil(%d): unexpected key %d", key, k)
+		} else if ceil <= 998 && (!ok || k != ceil || v != ceil/2) {
+			t.Fatalf("Ceil(%d)=<%v,%v,%v>, expected %d", key, k, v, ok, ceil)
+		}
+	}
+}
+
+func TestSortedMap_JSON(t *testing.T) {
+	t.Run("Marshal", func(t *testing.T) {
+		m := NewSortedMap[string, int](nil)
+		m = m.Set("c", 3).Set("a", 1).Set("b", 2)
+		if buf, err := json.Marshal(m); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `{"a":1,"b":2,"c":3}`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
 		}
-		for i := 0; i < n; i++ {
-			if v, ok := m.Get(i); !ok || v != i+1 {
-				t.Fatalf("unexpected value for key=%v: <%v,%v>", i, v, ok)
-			}
+		if buf, err := json.Marshal(NewSortedMap[string, int](nil)); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `{}`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
 		}
 	})
 
-	t.Run("StringKeys", func(t *testing.T) {
-		m := NewSortedMap[string, string](nil)
-		m = m.Set("foo", "bar")
-		m = m.Set("baz", "bat")
-		m = m.Set("", "EMPTY")
-		if v, ok := m.Get("foo"); !ok || v != "bar" {
-			t.Fatalf("unexpected value: <%v,%v>", v, ok)
-		} else if v, ok := m.Get("baz"); !ok || v != "bat" {
-			t.Fatalf("unexpected value: <%v,%v>", v, ok)
-		} else if v, ok := m.Get(""); !ok || v != "EMPTY" {
-			t.Fatalf("unexpected value: <%v,%v>", v, ok)
-		}
-		if v, ok := m.Get("no_such_key"); ok {
-			t.Fatalf("expected no value: <%v,%v>", v, ok)
+	t.Run("ComparerOrder", func(t *testing.T) {
+		m := NewSortedMap[string, int](&mockComparer[string]{
+			compare: func(a, b string) int { return -strings.Compare(a, b) },
+		})
+		m = m.Set("a", 1).Set("c", 3).Set("b", 2)
+		if buf, err := json.Marshal(m); err != nil {
+			t.Fatal(err)
+		} else if got, exp := string(buf), `{"c":3,"b":2,"a":1}`; got != exp {
+			t.Fatalf("json.Marshal()=%s, expected %s", got, exp)
 		}
 	})
 
-	t.Run("NoDefaultComparer", func(t *testing.T) {
-		var r string
-		func() {
-			defer func() { r = recover().(string) }()
-			m := NewSortedMap[float64, string](nil)
-			m = m.Set(float64(100), "bar")
-		}()
-		if r != `immutable.NewComparer: must set comparer for float64 type` {
-			t.Fatalf("unexpected panic: %q", r)
+	t.Run("Unmarshal", func(t *testing.T) {
+		var m SortedMap[string, int]
+		if err := json.Unmarshal([]byte(`{"b":2,"a":1}`), &m); err != nil {
+			t.Fatal(err)
+		}
+		itr := m.Iterator()
+		if k, v, _ := itr.Next(); k != "a" || v != 1 {
+			t.Fatalf("unexpected entry: <%v,%v>", k, v)
+		} else if k, v, _ := itr.Next(); k != "b" || v != 2 {
+			t.Fatalf("unexpected entry: <%v,%v>", k, v)
 		}
 	})
 
-	RunRandom(t, "Random", func(t *testing.T, rand *rand.Rand) {
-		m := NewTSortedMap()
-		for j := 0; j < 10000; j++ {
-			switch rand.Intn(2) {
-			case 1: // overwrite
-				m.Set(m.ExistingKey(rand), rand.Intn(10000))
-			default: // set new key
-				m.Set(m.NewKey(rand), rand.Intn(10000))
-			}
-		}
-		if err := m.Validate(); err != nil {
-			t.Fatal(err)
+	t.Run("ErrNonStringKey", func(t *testing.T) {
+		if _, err := json.Marshal(NewSortedMap[int, int](nil).Set(1, 2)); err == nil {
+			t.Fatal("expected error")
+		} else if _, err := json.Marshal(NewSortedMap[int, int](nil)); err == nil {
+			t.Fatal("expected error for empty map")
 		}
 	})
 }
 
+func TestSortedMap_Gob(t *testing.T) {
+	m := NewSortedMap[string, int](nil)
+	for i := 0; i < 1000; i++ {
+		m = m.Set(fmt.Sprintf("%04d", i), i)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+
+	var other *SortedMap[string, int]
+	if err := gob.NewDecoder(&buf).Decode(&other); err != nil {
+		t.Fatal(err)
+	} else if other.Len() != 1000 {
+		t.Fatalf("unexpected size: %d", other.Len())
+	}
+	itr := other.Iterator()
+	for i := 0; i < 1000; i++ {
+		if k, v, ok := itr.Next(); !ok || k != fmt.Sprintf("%04d", i) || v != i {
+			t.Fatalf("unexpected entry: <%v,%v>", k, v)
+		}
+	}
+}
+
 // Ensure map can support overwrites as it expands.
 func TestSortedMap_Overwrite(t *testing.T) {
 	const n = 1000
@@ -2101,6 +4834,292 @@ func TestSortedMap_Iterator(t *testing.T) {
 	})
 }
 
+func TestSumRange(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		m := NewSortedMap[int, int](nil)
+		if sum := SumRange(m, 0, 100, func(v int) int { return v }); sum != 0 {
+			t.Fatalf("SumRange()=%d, expected 0", sum)
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		m := NewSortedMap[int, float64](nil)
+		for i := 0; i < 1000; i++ {
+			m = m.Set(i, float64(i)/2)
+		}
+		sum := SumRange(m, 10, 20, func(v float64) float64 { return v * 2 })
+		if exp := 145.0; sum != exp {
+			t.Fatalf("SumRange()=%v, expected %v", sum, exp)
+		}
+		if sum := SumRange(m, 2000, 3000, func(v float64) float64 { return v }); sum != 0 {
+			t.Fatalf("SumRange()=%v, expected 0", sum)
+		}
+	})
+}
+
+func TestSortedMapKeysSymmetricDifference(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		a, b := NewSortedMap[int, int](nil), NewSortedMap[int, int](nil)
+		if keys := SortedMapKeysSymmetricDifference(a, b); len(keys) != 0 {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+		if keys := SortedMapKeysSymmetricDifference(a, b.Set(1, 1)); len(keys) != 1 || keys[0] != 1 {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		a, b := NewSortedMap[int, int](nil), NewSortedMap[int, int](nil)
+		for i := 0; i < 1000; i += 2 {
+			a = a.Set(i, i)
+		}
+		for i := 0; i < 1000; i += 3 {
+			b = b.Set(i, i)
+		}
+
+		var exp []int
+		for i := 0; i < 1000; i++ {
+			if (i%2 == 0) != (i%3 == 0) {
+				exp = append(exp, i)
+			}
+		}
+
+		keys := SortedMapKeysSymmetricDifference(a, b)
+		if len(keys) != len(exp) {
+			t.Fatalf("unexpected key count: %d, expected %d", len(keys), len(exp))
+		}
+		for i := range exp {
+			if keys[i] != exp[i] {
+				t.Fatalf("keys[%d]=%d, expected %d", i, keys[i], exp[i])
+			}
+		}
+	})
+}
+
+func TestSortedMap_RangeIterator(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	for i := 0; i < 1000; i += 2 {
+		m = m.Set(i, i)
+	}
+
+	forward := func(itr *SortedMapIterator[int, int]) (keys []int) {
+		for !itr.Done() {
+			k, _, _ := itr.Next()
+			keys = append(keys, k)
+		}
+		return keys
+	}
+	backward := func(itr *SortedMapIterator[int, int]) (keys []int) {
+		for itr.Last(); !itr.Done(); {
+			k, _, _ := itr.Prev()
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	for _, tt := range []struct {
+		lo, hi                   int
+		loInclusive, hiInclusive bool
+		exp, rev                 string
+	}{
+		{10, 16, true, true, "[10 12 14 16]", "[16 14 12 10]"},
+		{10, 16, false, true, "[12 14 16]", "[16 14 12]"},
+		{10, 16, true, false, "[10 12 14]", "[14 12 10]"},
+		{10, 16, false, false, "[12 14]", "[14 12]"},
+		{9, 17, false, false, "[10 12 14 16]", "[16 14 12 10]"},
+		{994, 2000, true, true, "[994 996 998]", "[998 996 994]"},
+		{-10, 2, true, true, "[0 2]", "[2 0]"},
+		{10, 10, false, true, "[]", "[]"},
+		{2000, 3000, true, true, "[]", "[]"},
+	} {
+		itr := m.RangeIterator(tt.lo, tt.loInclusive, tt.hi, tt.hiInclusive)
+		if got := fmt.Sprint(forward(itr)); got != tt.exp {
+			t.Fatalf("%+v: forward=%s, expected %s", tt, got, tt.exp)
+		}
+
+		if got := fmt.Sprint(backward(itr)); got != tt.rev {
+			t.Fatalf("%+v: backward=%s, expected %s", tt, got, tt.rev)
+		}
+	}
+
+	t.Run("Seek", func(t *testing.T) {
+		itr := m.RangeIterator(10, true, 20, false)
+		itr.Seek(15)
+		if got, exp := fmt.Sprint(forward(itr)), "[16 18]"; got != exp {
+			t.Fatalf("forward=%s, expected %s", got, exp)
+		}
+		itr.Seek(0)
+		if got, exp := fmt.Sprint(forward(itr)), "[10 12 14 16 18]"; got != exp {
+			t.Fatalf("forward=%s, expected %s", got, exp)
+		}
+	})
+
+	t.Run("SeekRange", func(t *testing.T) {
+		itr := m.Iterator()
+		itr.SeekRange(11, 20)
+		if got, exp := fmt.Sprint(forward(itr)), "[12 14 16 18 20]"; got != exp {
+			t.Fatalf("forward=%s, expected %s", got, exp)
+		} else if got, exp := fmt.Sprint(backward(itr)), "[20 18 16 14 12]"; got != exp {
+			t.Fatalf("backward=%s, expected %s", got, exp)
+		}
+
+		itr.SeekRange(990, 2000)
+		if n := itr.Remaining(); n != 5 {
+			t.Fatalf("unexpected remaining: %d", n)
+		}
+		if itr.SeekRange(20, 10); !itr.Done() {
+			t.Fatal("expected done")
+		}
+	})
+}
+
+func TestSortedMapIterator_SeekReverse(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	for i := 0; i < 1000; i += 2 {
+		m = m.Set(i, i)
+	}
+
+	t.Run("Exact", func(t *testing.T) {
+		itr := m.Iterator()
+		itr.SeekReverse(500)
+		for i := 500; i >= 0; i -= 2 {
+			if k, _, ok := itr.Prev(); !ok || k != i {
+				t.Fatalf("unexpected key: <%v,%v>, expected %d", k, ok, i)
+			}
+		}
+		if !itr.Done() {
+			t.Fatal("expected done")
+		}
+	})
+
+	t.Run("Miss", func(t *testing.T) {
+		itr := m.Iterator()
+		itr.SeekReverse(501)
+		if k, _, _ := itr.Prev(); k != 500 {
+			t.Fatalf("unexpected key: %d", k)
+		}
+		itr.SeekReverse(2000)
+		if k, _, _ := itr.Prev(); k != 998 {
+			t.Fatalf("unexpected key: %d", k)
+		}
+		if itr.SeekReverse(-1); !itr.Done() {
+			t.Fatal("expected done")
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		itr := m.RangeIterator(100, false, 200, false)
+		itr.SeekReverse(300)
+		if k, _, _ := itr.Prev(); k != 198 {
+			t.Fatalf("unexpected key: %d", k)
+		}
+		itr.SeekReverse(103)
+		if k, _, _ := itr.Prev(); k != 102 {
+			t.Fatalf("unexpected key: %d", k)
+		} else if !itr.Done() {
+			t.Fatal("expected done")
+		}
+		if itr.SeekReverse(100); !itr.Done() {
+			t.Fatal("expected done")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		itr := NewSortedMap[int, int](nil).Iterator()
+		if itr.SeekReverse(10); !itr.Done() {
+			t.Fatal("expected done")
+		}
+	})
+}
+
+func TestSortedMapIterator_Remaining(t *testing.T) {
+	m := NewSortedMap[int, int](nil)
+	for i := 0; i < 1000; i += 2 {
+		m = m.Set(i, i)
+	}
+
+	t.Run("Full", func(t *testing.T) {
+		itr := m.Iterator()
+		for i := 500; i > 0; i-- {
+			if n := itr.Remaining(); n != i {
+				t.Fatalf("unexpected remaining: %d, expected %d", n, i)
+			}
+			itr.Next()
+		}
+		if n := itr.Remaining(); n != 0 {
+			t.Fatalf("unexpected remaining: %d", n)
+		}
+
+		itr.Seek(901)
+		if n := itr.Remaining(); n != 49 {
+			t.Fatalf("unexpected remaining: %d", n)
+		}
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		itr := m.RangeIterator(100, false, 200, true)
+		for i := 50; i > 0; i-- {
+			if n := itr.Remaining(); n != i {
+				t.Fatalf("unexpected remaining: %d, expected %d", n, i)
+			}
+			itr.Next()
+		}
+		if n := itr.Remaining(); n != 0 {
+			t.Fatalf("unexpected remaining: %d", n)
+		}
+
+		if itr := m.RangeIterator(2000, true, 3000, true); itr.Remaining() != 0 {
+			t.Fatalf("unexpected remaining: %d", itr.Remaining())
+		}
+	})
+}
+
+func TestSortedMapIterator_Position(t *testing.T) {
+	const n = 1000
+	m := NewSortedMap[int, int](nil)
+	for i := 0; i < n; i += 2 {
+		m = m.Set(i, i)
+	}
+
+	t.Run("Forward", func(t *testing.T) {
+		itr := m.Iterator()
+		if pos := itr.Position(); pos != -1 {
+			t.Fatalf("SortedMapIterator.Position()=%d, expected -1", pos)
+		}
+		for i := 0; !itr.Done(); i++ {
+			itr.Next()
+			if pos := itr.Position(); pos != i {
+				t.Fatalf("SortedMapIterator.Position()=%d, expected %d", pos, i)
+			}
+		}
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		itr := m.Iterator()
+		itr.Last()
+		for i := m.Len() - 1; !itr.Done(); i-- {
+			itr.Prev()
+			if pos := itr.Position(); pos != i {
+				t.Fatalf("SortedMapIterator.Position()=%d, expected %d", pos, i)
+			}
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		itr := m.Iterator()
+		for i := 0; i < n-1; i++ {
+			itr.Seek(i)
+			if pos := itr.Position(); pos != -1 {
+				t.Fatalf("SortedMapIterator.Position()=%d, expected -1", pos)
+			}
+			itr.Next()
+			if exp := (i + 1) / 2; itr.Position() != exp {
+				t.Fatalf("Seek(%d): SortedMapIterator.Position()=%d, expected %d", i, itr.Position(), exp)
+			}
+		}
+	})
+}
+
 func TestNewHasher(t *testing.T) {
 	t.Run("builtin", func(t *testing.T) {
 		t.Run("int", func(t *testing.T) { testNewHasher(t, int(100)) })
@@ -2116,7 +5135,16 @@ func TestNewHasher(t *testing.T) {
 		t.Run("uint64", func(t *testing.T) { testNewHasher(t, uint64(100)) })
 
 		t.Run("string", func(t *testing.T) { testNewHasher(t, "foo") })
-		//t.Run("byteSlice", func(t *testing.T) { testNewHasher(t, []byte("foo")) })
+		t.Run("byteSlice", func(t *testing.T) {
+			h := NewHasher([]byte("foo"))
+			if h.Hash([]byte("foo")) != h.Hash([]byte("foo")) {
+				t.Fatal("expected hash consistency")
+			} else if !h.Equal([]byte("foo"), []byte("foo")) {
+				t.Fatal("expected hash equality")
+			} else if h.Equal([]byte("foo"), []byte("bar")) {
+				t.Fatal("expected hash inequality")
+			}
+		})
 	})
 
 	t.Run("reflection", func(t *testing.T) {
@@ -2131,6 +5159,70 @@ func TestNewHasher(t *testing.T) {
 	})
 }
 
+func TestNewMapE(t *testing.T) {
+	if m, err := NewMapE[int, int](nil); err != nil {
+		t.Fatal(err)
+	} else if m.Set(1, 2).Len() != 1 {
+		t.Fatal("unexpected map length")
+	}
+
+	type point struct{ x, y int }
+	_, err := NewMapE[point, int](nil)
+	var e *UnsupportedKeyTypeError
+	if !errors.As(err, &e) || e.Type != reflect.TypeOf(point{}) {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if got, exp := err.Error(), "immutable: no default hasher for immutable.point key type"; got != exp {
+		t.Fatalf("Error()=%q, expected %q", got, exp)
+	}
+
+	if _, err := NewMapE[point, int](HasherFunc(func(p point) uint32 { return uint32(p.x) }, func(a, b point) bool { return a == b })); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewSortedMapE(t *testing.T) {
+	if m, err := NewSortedMapE[[]byte, int](nil); err != nil {
+		t.Fatal(err)
+	} else if m.Set([]byte("foo"), 1).Len() != 1 {
+		t.Fatal("unexpected map length")
+	}
+
+	_, err := NewSortedMapE[float64, int](nil)
+	if got, exp := fmt.Sprint(err), "immutable: no default comparer for float64 key type"; got != exp {
+		t.Fatalf("unexpected error: %s", got)
+	}
+}
+
+func TestByteSliceKeys(t *testing.T) {
+	m := NewMap[[]byte, int](nil).Set([]byte("foo"), 1).Set([]byte("bar"), 2).Set([]byte("foo"), 3)
+	if m.Len() != 2 {
+		t.Fatalf("unexpected map length: %d", m.Len())
+	} else if v, ok := m.Get([]byte("foo")); !ok || v != 3 {
+		t.Fatalf("unexpected map value: %d, %v", v, ok)
+	}
+
+	sm := NewSortedMap[[]byte, int](nil).Set([]byte("foo"), 1).Set([]byte("bar"), 2).Set([]byte("baz"), 3)
+	if got, exp := fmt.Sprint(sm.Values()), "[2 3 1]"; got != exp {
+		t.Fatalf("unexpected sorted map values: %s, expected %s", got, exp)
+	} else if v, ok := sm.Get([]byte("baz")); !ok || v != 3 {
+		t.Fatalf("unexpected sorted map value: %d, %v", v, ok)
+	}
+}
+
+func TestHasherFunc(t *testing.T) {
+	h := HasherFunc(
+		func(key string) uint32 { return hashString(strings.ToLower(key)) },
+		func(a, b string) bool { return strings.EqualFold(a, b) },
+	)
+
+	m := NewMap[string, int](h).Set("Foo", 1).Set("FOO", 2).Set("bar", 3)
+	if m.Len() != 2 {
+		t.Fatalf("unexpected length: %d", m.Len())
+	} else if v, ok := m.Get("foo"); !ok || v != 2 {
+		t.Fatalf("unexpected value: %d, %v", v, ok)
+	}
+}
+
 func testNewHasher[V constraints.Ordered](t *testing.T, v V) {
 	t.Helper()
 	h := NewHasher(v)
@@ -2155,7 +5247,16 @@ func TestNewComparer(t *testing.T) {
 		t.Run("uint64", func(t *testing.T) { testNewComparer(t, uint64(100), uint64(101)) })
 
 		t.Run("string", func(t *testing.T) { testNewComparer(t, "bar", "foo") })
-		//t.Run("byteSlice", func(t *testing.T) { testNewComparer(t, []byte("bar"), []byte("foo")) })
+		t.Run("byteSlice", func(t *testing.T) {
+			c := NewComparer([]byte("bar"))
+			if c.Compare([]byte("bar"), []byte("foo")) != -1 {
+				t.Fatal("expected comparer LT")
+			} else if c.Compare([]byte("bar"), []byte("bar")) != 0 {
+				t.Fatal("expected comparer EQ")
+			} else if c.Compare([]byte("foo"), []byte("bar")) != 1 {
+				t.Fatal("expected comparer GT")
+			}
+		})
 	})
 
 	t.Run("reflection", func(t *testing.T) {
@@ -2182,6 +5283,84 @@ func testNewComparer[T constraints.Ordered](t *testing.T, x, y T) {
 	}
 }
 
+func TestReverseComparer(t *testing.T) {
+	c := ReverseComparer(NewComparer(0))
+	if c.Compare(1, 2) != 1 || c.Compare(2, 1) != -1 || c.Compare(1, 1) != 0 {
+		t.Fatal("unexpected reverse comparison")
+	}
+
+	m := NewSortedMap[int, int](c)
+	for _, i := range rand.Perm(100) {
+		m = m.Set(i, i)
+	}
+	if k, _, _ := m.Iterator().Next(); k != 99 {
+		t.Fatalf("unexpected first key: %d", k)
+	} else if got, exp := fmt.Sprint(NewSortedSet(c, 1, 3, 2).Items()), "[3 2 1]"; got != exp {
+		t.Fatalf("unexpected sorted set items: %s, expected %s", got, exp)
+	}
+}
+
+func TestFloatComparer(t *testing.T) {
+	c := FloatComparer[float64]()
+	nan := math.NaN()
+	for _, tt := range []struct {
+		a, b float64
+		exp  int
+	}{
+		{1, 2, -1},
+		{2, 1, 1},
+		{1, 1, 0},
+		{math.Inf(1), nan, -1},
+		{nan, math.Inf(1), 1},
+		{nan, nan, 0},
+		{math.Copysign(0, -1), 0, 0},
+	} {
+		if got := c.Compare(tt.a, tt.b); got != tt.exp {
+			t.Fatalf("Compare(%v, %v)=%d, expected %d", tt.a, tt.b, got, tt.exp)
+		}
+	}
+
+	m := NewSortedMap[float64, string](c).Set(nan, "nan").Set(2.5, "b").Set(-1.5, "a")
+	if got, exp := fmt.Sprint(m.Values()), "[a b nan]"; got != exp {
+		t.Fatalf("unexpected values: %s, expected %s", got, exp)
+	} else if v, ok := m.Get(nan); !ok || v != "nan" {
+		t.Fatalf("unexpected NaN lookup: %q, %v", v, ok)
+	}
+}
+
+func TestComparerFunc(t *testing.T) {
+	type point struct{ x, y int }
+	c := ComparerFunc(func(a, b point) bool {
+		return a.x < b.x || (a.x == b.x && a.y < b.y)
+	})
+	if c.Compare(point{1, 2}, point{1, 3}) != -1 {
+		t.Fatal("expected comparer LT")
+	} else if c.Compare(point{2, 0}, point{1, 3}) != 1 {
+		t.Fatal("expected comparer GT")
+	} else if c.Compare(point{1, 2}, point{1, 2}) != 0 {
+		t.Fatal("expected comparer EQ")
+	}
+
+	m := NewSortedMap[point, string](c)
+	m = m.Set(point{2, 1}, "c").Set(point{1, 5}, "b").Set(point{1, 1}, "a")
+	if got, exp := fmt.Sprint(m.Values()), "[a b c]"; got != exp {
+		t.Fatalf("unexpected values: %s, expected %s", got, exp)
+	}
+}
+
+func TestSortedMap_StructKeys(t *testing.T) {
+	type key struct{ a, b int }
+	c := ComparerFunc(func(x, y key) bool { return x.a < y.a || (x.a == y.a && x.b < y.b) })
+
+	m1 := NewSortedMap[key, int](c).Set(key{1, 1}, 1).Set(key{1, 2}, 2).Set(key{2, 0}, 3)
+	m2 := NewSortedMap[key, int](c).Set(key{1, 2}, 2).Set(key{3, 0}, 4)
+	if got, exp := fmt.Sprint(SortedMapKeysSymmetricDifference(m1, m2)), "[{1 1} {2 0} {3 0}]"; got != exp {
+		t.Fatalf("unexpected keys: %s, expected %s", got, exp)
+	} else if sum := SumRange(m1, key{1, 2}, key{9, 9}, func(v int) int { return v }); sum != 5 {
+		t.Fatalf("unexpected sum: %d", sum)
+	}
+}
+
 // TSortedMap represents a combined immutable and stdlib sorted map.
 type TSortedMap struct {
 	im, prev *SortedMap[int, int]
@@ -2240,6 +5419,22 @@ func (m *TSortedMap) Delete(k int) {
 	}
 }
 
+func (m *TSortedMap) DeleteRange(lo, hi int) {
+	m.prev = m.im
+	m.im = m.im.DeleteRange(lo, hi)
+	m.builder.DeleteRange(lo, hi)
+
+	keys := m.keys[:0]
+	for _, k := range m.keys {
+		if k >= lo && k < hi {
+			delete(m.std, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	m.keys = keys
+}
+
 func (m *TSortedMap) Validate() error {
 	for _, k := range m.keys {
 		if v, ok := m.im.Get(k); !ok {
@@ -2259,6 +5454,14 @@ func (m *TSortedMap) Validate() error {
 	}
 
 	sort.Ints(m.keys)
+	for i, k := range m.keys {
+		if got := m.im.Rank(k); got != i {
+			return fmt.Errorf("Rank(%d)=%d, expected %d", k, got, i)
+		} else if got, _, ok := m.im.Select(i); !ok || got != k {
+			return fmt.Errorf("Select(%d)=<%d,%v>, expected %d", i, got, ok, k)
+		}
+	}
+
 	if err := m.validateForwardIterator(m.im.Iterator()); err != nil {
 		return fmt.Errorf("basic: %s", err)
 	} else if err := m.validateBackwardIterator(m.im.Iterator()); err != nil {
@@ -2504,6 +5707,15 @@ func RunRandom(t *testing.T, name string, fn func(t *testing.T, rand *rand.Rand)
 	})
 }
 
+// randomInts returns a slice of n random integers.
+func randomInts(rand *rand.Rand, n int) []int {
+	a := make([]int, n)
+	for i := range a {
+		a[i] = rand.Intn(10000)
+	}
+	return a
+}
+
 func uniqueIntSlice(a []int) []int {
 	m := make(map[int]struct{})
 	other := make([]int, 0, len(a))
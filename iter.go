@@ -0,0 +1,118 @@
+//go:build go1.23
+
+package immutable
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the index/value pairs in the list, in order.
+func (l *List[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for itr := l.Iterator(); !itr.Done(); {
+			if !yield(itr.Next()) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values in the list, in order.
+func (l *List[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for itr := l.Iterator(); !itr.Done(); {
+			if _, v := itr.Next(); !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the index/value pairs in the list, in
+// reverse order.
+func (l *List[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		itr := l.Iterator()
+		itr.Last()
+		for !itr.Done() {
+			if !yield(itr.Prev()) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the key/value pairs in the map.
+// No guarantee is made about the iteration order.
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for itr := m.Iterator(); !itr.Done(); {
+			if k, v, _ := itr.Next(); !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the key/value pairs in the map, in ascending
+// key order.
+func (m *SortedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for itr := m.Iterator(); !itr.Done(); {
+			if k, v, _ := itr.Next(); !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the key/value pairs in the map, in
+// descending key order.
+func (m *SortedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		itr := m.Iterator()
+		itr.Last()
+		for !itr.Done() {
+			if k, v, _ := itr.Prev(); !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the values in the set.
+// No guarantee is made about the iteration order.
+func (s Set[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for itr := s.Iterator(); !itr.Done(); {
+			if v, _ := itr.Next(); !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the values in the set, in ascending order.
+func (s SortedSet[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for itr := s.Iterator(); !itr.Done(); {
+			if v, _ := itr.Next(); !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the values in the set, in descending
+// order.
+func (s SortedSet[T]) Backward() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		itr := s.Iterator()
+		itr.Last()
+		for !itr.Done() {
+			if v, _ := itr.Prev(); !yield(v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+//go:build go1.23
+
+package immutable
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestList_All(t *testing.T) {
+	l := NewList("foo", "bar", "baz")
+
+	var a []string
+	for i, v := range l.All() {
+		a = append(a, fmt.Sprintf("%d:%s", i, v))
+	}
+	if got, exp := fmt.Sprint(a), "[0:foo 1:bar 2:baz]"; got != exp {
+		t.Fatalf("All()=%s, expected %s", got, exp)
+	}
+
+	a = nil
+	for i, v := range l.Backward() {
+		a = append(a, fmt.Sprintf("%d:%s", i, v))
+	}
+	if got, exp := fmt.Sprint(a), "[2:baz 1:bar 0:foo]"; got != exp {
+		t.Fatalf("Backward()=%s, expected %s", got, exp)
+	}
+
+	if got, exp := fmt.Sprint(slices.Collect(l.Values())), "[foo bar baz]"; got != exp {
+		t.Fatalf("Values()=%s, expected %s", got, exp)
+	}
+
+	t.Run("Break", func(t *testing.T) {
+		var n int
+		for range l.All() {
+			if n++; n == 2 {
+				break
+			}
+		}
+		for range l.Values() {
+			n++
+			break
+		}
+		for range l.Backward() {
+			n++
+			break
+		}
+		if n != 4 {
+			t.Fatalf("unexpected iteration count: %d", n)
+		}
+	})
+}
+
+func TestMap_All(t *testing.T) {
+	m := NewMap[int, int](nil)
+	for i := 0; i < 100; i++ {
+		m = m.Set(i, i*10)
+	}
+
+	seen := make(map[int]int)
+	for k, v := range m.All() {
+		seen[k] = v
+	}
+	if len(seen) != 100 {
+		t.Fatalf("unexpected pair count: %d", len(seen))
+	}
+	for k, v := range seen {
+		if v != k*10 {
+			t.Fatalf("unexpected value for key %d: %d", k, v)
+		}
+	}
+
+	var n int
+	for range m.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatalf("unexpected iteration count after break: %d", n)
+	}
+}
+
+func TestSortedMap_All(t *testing.T) {
+	m := NewSortedMap[int, string](nil)
+	for _, i := range []int{3, 1, 2} {
+		m = m.Set(i, fmt.Sprint(i*10))
+	}
+
+	var a []string
+	for k, v := range m.All() {
+		a = append(a, fmt.Sprintf("%d:%s", k, v))
+	}
+	if got, exp := fmt.Sprint(a), "[1:10 2:20 3:30]"; got != exp {
+		t.Fatalf("All()=%s, expected %s", got, exp)
+	}
+
+	a = nil
+	for k, v := range m.Backward() {
+		a = append(a, fmt.Sprintf("%d:%s", k, v))
+		if k == 2 {
+			break
+		}
+	}
+	if got, exp := fmt.Sprint(a), "[3:30 2:20]"; got != exp {
+		t.Fatalf("Backward()=%s, expected %s", got, exp)
+	}
+}
+
+func TestSet_All(t *testing.T) {
+	s := NewSet[int](nil, 1, 2, 3)
+	a := slices.Collect(s.All())
+	slices.Sort(a)
+	if got, exp := fmt.Sprint(a), "[1 2 3]"; got != exp {
+		t.Fatalf("All()=%s, expected %s", got, exp)
+	}
+
+	var n int
+	for range s.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatalf("unexpected iteration count after break: %d", n)
+	}
+}
+
+func TestSortedSet_All(t *testing.T) {
+	s := NewSortedSet[int](nil, 3, 1, 2)
+	if got, exp := fmt.Sprint(slices.Collect(s.All())), "[1 2 3]"; got != exp {
+		t.Fatalf("All()=%s, expected %s", got, exp)
+	}
+
+	var a []int
+	for v := range s.Backward() {
+		if a = append(a, v); v == 2 {
+			break
+		}
+	}
+	if got, exp := fmt.Sprint(a), "[3 2]"; got != exp {
+		t.Fatalf("Backward()=%s, expected %s", got, exp)
+	}
+}
@@ -0,0 +1,212 @@
+package immutable
+
+// SortedMultiMap represents a sorted map which associates one or more values
+// with each key. Values for a key are kept in insertion order.
+//
+// Internally, the SortedMultiMap stores values as a SortedMap[K,*List[V]].
+type SortedMultiMap[K, V any] struct {
+	size int                     // total number of key/value pairs
+	m    *SortedMap[K, *List[V]] // values by key
+}
+
+// NewSortedMultiMap returns a new instance of SortedMultiMap. If comparer is
+// nil then a default comparer is used. A comparer must be provided for key
+// types without a default comparer, such as structs.
+func NewSortedMultiMap[K, V any](comparer Comparer[K]) *SortedMultiMap[K, V] {
+	return &SortedMultiMap[K, V]{m: NewSortedMap[K, *List[V]](comparer)}
+}
+
+// Len returns the total number of key/value pairs in the map.
+func (m *SortedMultiMap[K, V]) Len() int {
+	return m.size
+}
+
+// Get returns the list of values for the given key. Returns an empty list if
+// the key does not exist.
+func (m *SortedMultiMap[K, V]) Get(key K) *List[V] {
+	if l, ok := m.m.Get(key); ok {
+		return l
+	}
+	return NewList[V]()
+}
+
+// Add returns a map with value added to the end of the values for key.
+func (m *SortedMultiMap[K, V]) Add(key K, value V) *SortedMultiMap[K, V] {
+	return &SortedMultiMap[K, V]{
+		size: m.size + 1,
+		m:    m.m.Set(key, m.Get(key).Append(value)),
+	}
+}
+
+// Delete returns a map with all values for the given key removed.
+// Returns the original map if the key does not exist.
+func (m *SortedMultiMap[K, V]) Delete(key K) *SortedMultiMap[K, V] {
+	l, ok := m.m.Get(key)
+	if !ok {
+		return m
+	}
+	return &SortedMultiMap[K, V]{size: m.size - l.Len(), m: m.m.Delete(key)}
+}
+
+// DeleteValue returns a map with the first value for key that is equal to
+// value removed. The key is removed if it has no remaining values. Returns the
+// original map if no matching value exists.
+func (m *SortedMultiMap[K, V]) DeleteValue(key K, value V, equal func(a, b V) bool) *SortedMultiMap[K, V] {
+	l, ok := m.m.Get(key)
+	if !ok {
+		return m
+	}
+
+	i := l.IndexOf(value, equal)
+	if i == -1 {
+		return m
+	} else if l.Len() == 1 {
+		return &SortedMultiMap[K, V]{size: m.size - 1, m: m.m.Delete(key)}
+	}
+	return &SortedMultiMap[K, V]{size: m.size - 1, m: m.m.Set(key, l.RemoveAt(i))}
+}
+
+// Iterator returns a new iterator for this map positioned at the first
+// key/value pair.
+func (m *SortedMultiMap[K, V]) Iterator() *SortedMultiMapIterator[K, V] {
+	itr := &SortedMultiMapIterator[K, V]{mi: m.m.Iterator()}
+	itr.First()
+	return itr
+}
+
+// SortedMultiMapIterator represents an iterator over a sorted multimap.
+// Pairs are returned in key order and then in value insertion order.
+type SortedMultiMapIterator[K, V any] struct {
+	mi  *SortedMapIterator[K, *List[V]] // key iterator
+	key K                               // current key
+	li  *ListIterator[V]                // value iterator for current key
+}
+
+// Done returns true if no more key/value pairs remain in the iterator.
+func (itr *SortedMultiMapIterator[K, V]) Done() bool {
+	return itr.li == nil || itr.li.Done()
+}
+
+// First moves the iterator to the first key/value pair.
+func (itr *SortedMultiMapIterator[K, V]) First() {
+	itr.mi.First()
+	itr.li = nil
+	itr.nextKey()
+}
+
+// Next returns the current key/value pair and moves the iterator forward.
+func (itr *SortedMultiMapIterator[K, V]) Next() (key K, value V, ok bool) {
+	if itr.Done() {
+		return key, value, false
+	}
+
+	_, value = itr.li.Next()
+	key = itr.key
+	if itr.li.Done() {
+		itr.nextKey()
+	}
+	return key, value, true
+}
+
+// nextKey moves the value iterator to the values of the next key.
+func (itr *SortedMultiMapIterator[K, V]) nextKey() {
+	if itr.mi.Done() {
+		return
+	}
+	key, l, _ := itr.mi.Next()
+	itr.key, itr.li = key, l.Iterator()
+}
+
+// SortedMultiMapBuilder represents an efficient builder for creating sorted multimaps.
+type SortedMultiMapBuilder[K, V any] struct {
+	size  int
+	b     *SortedMapBuilder[K, *List[V]]
+	owned map[*List[V]]struct{} // value lists which can be appended to in-place
+}
+
+// NewSortedMultiMapBuilder returns a new instance of SortedMultiMapBuilder.
+func NewSortedMultiMapBuilder[K, V any](comparer Comparer[K]) *SortedMultiMapBuilder[K, V] {
+	return &SortedMultiMapBuilder[K, V]{
+		b:     NewSortedMapBuilder[K, *List[V]](comparer),
+		owned: make(map[*List[V]]struct{}),
+	}
+}
+
+// Map returns the current copy of the map.
+// The builder should not be used again after this call.
+func (b *SortedMultiMapBuilder[K, V]) Map() *SortedMultiMap[K, V] {
+	assert(b.b != nil, "immutable.SortedMultiMapBuilder.Map(): duplicate call to fetch map")
+	m := &SortedMultiMap[K, V]{size: b.size, m: b.b.Map()}
+	b.b, b.owned = nil, nil
+	return m
+}
+
+// Len returns the total number of key/value pairs in the underlying map.
+func (b *SortedMultiMapBuilder[K, V]) Len() int {
+	assert(b.b != nil, "immutable.SortedMultiMapBuilder: builder invalid after Map() invocation")
+	return b.size
+}
+
+// Get returns the list of values for the given key. The returned list is not
+// affected by later changes to the builder.
+func (b *SortedMultiMapBuilder[K, V]) Get(key K) *List[V] {
+	assert(b.b != nil, "immutable.SortedMultiMapBuilder: builder invalid after Map() invocation")
+	if l, ok := b.b.Get(key); ok {
+		delete(b.owned, l)
+		return l
+	}
+	return NewList[V]()
+}
+
+// Add adds value to the end of the values for key.
+//
+// The first value added to a key's list copies the list so that later values
+// can be appended in-place.
+func (b *SortedMultiMapBuilder[K, V]) Add(key K, value V) {
+	assert(b.b != nil, "immutable.SortedMultiMapBuilder: builder invalid after Map() invocation")
+	if l, ok := b.b.Get(key); ok {
+		if _, owned := b.owned[l]; owned {
+			l.append(value, true)
+			b.size++
+			return
+		}
+	}
+
+	l := b.Get(key).Append(value)
+	b.owned[l] = struct{}{}
+	b.b.Set(key, l)
+	b.size++
+}
+
+// Delete removes all values for the given key.
+func (b *SortedMultiMapBuilder[K, V]) Delete(key K) {
+	assert(b.b != nil, "immutable.SortedMultiMapBuilder: builder invalid after Map() invocation")
+	if l, ok := b.b.Get(key); ok {
+		b.b.Delete(key)
+		delete(b.owned, l)
+		b.size -= l.Len()
+	}
+}
+
+// DeleteValue removes the first value for key that is equal to value. The key
+// is removed if it has no remaining values.
+func (b *SortedMultiMapBuilder[K, V]) DeleteValue(key K, value V, equal func(a, b V) bool) {
+	assert(b.b != nil, "immutable.SortedMultiMapBuilder: builder invalid after Map() invocation")
+	l, ok := b.b.Get(key)
+	if !ok {
+		return
+	}
+
+	i := l.IndexOf(value, equal)
+	if i == -1 {
+		return
+	}
+
+	delete(b.owned, l)
+	if l.Len() == 1 {
+		b.b.Delete(key)
+	} else {
+		b.b.Set(key, l.RemoveAt(i))
+	}
+	b.size--
+}
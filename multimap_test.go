@@ -0,0 +1,184 @@
+package immutable
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSortedMultiMap(t *testing.T) {
+	m := NewSortedMultiMap[string, int](nil)
+	m2 := m.Add("b", 1).Add("a", 2).Add("b", 3).Add("c", 4)
+	if m.Len() != 0 {
+		t.Fatalf("Unexpected mutation of map")
+	}
+	if m2.Len() != 4 {
+		t.Fatalf("Unexpected map length: %d", m2.Len())
+	}
+	if l := m2.Get("b"); l.Len() != 2 || l.Get(0) != 1 || l.Get(1) != 3 {
+		t.Fatalf("Unexpected values for key")
+	}
+	if l := m2.Get("z"); l.Len() != 0 {
+		t.Fatalf("Unexpected values for missing key")
+	}
+
+	type pair struct {
+		k string
+		v int
+	}
+	var pairs []pair
+	for itr := m2.Iterator(); !itr.Done(); {
+		k, v, _ := itr.Next()
+		pairs = append(pairs, pair{k, v})
+	}
+	exp := []pair{{"a", 2}, {"b", 1}, {"b", 3}, {"c", 4}}
+	if len(pairs) != len(exp) {
+		t.Fatalf("Unexpected iterator length: %d", len(pairs))
+	}
+	for i := range exp {
+		if pairs[i] != exp[i] {
+			t.Fatalf("Unexpected pair %d: %v", i, pairs[i])
+		}
+	}
+}
+
+func TestSortedMultiMapDelete(t *testing.T) {
+	equal := func(a, b int) bool { return a == b }
+	m := NewSortedMultiMap[string, int](nil).Add("a", 1).Add("a", 2).Add("b", 3)
+
+	m2 := m.DeleteValue("a", 1, equal)
+	if m2.Len() != 2 {
+		t.Fatalf("Unexpected map length: %d", m2.Len())
+	}
+	if l := m2.Get("a"); l.Len() != 1 || l.Get(0) != 2 {
+		t.Fatalf("Unexpected values after delete")
+	}
+	if m3 := m2.DeleteValue("a", 5, equal); m3 != m2 {
+		t.Fatalf("Unexpected change after deleting missing value")
+	}
+
+	m3 := m2.DeleteValue("a", 2, equal)
+	if m3.Len() != 1 || m3.Get("a").Len() != 0 {
+		t.Fatalf("Unexpected key after deleting last value")
+	}
+
+	m4 := m.Delete("a")
+	if m4.Len() != 1 || m.Len() != 3 {
+		t.Fatalf("Unexpected map lengths: %d, %d", m4.Len(), m.Len())
+	}
+}
+
+func TestSortedMultiMapBuilder(t *testing.T) {
+	b := NewSortedMultiMapBuilder[int, string](nil)
+	b.Add(2, "foo")
+	b.Add(1, "bar")
+	b.Add(2, "baz")
+	b.Add(3, "bat")
+	b.Delete(3)
+
+	// Lists returned by Get() must not change as values are added.
+	l := b.Get(2)
+	b.Add(2, "qux")
+	if l.Len() != 2 || b.Get(2).Len() != 3 {
+		t.Fatalf("Unexpected list lengths: %d, %d", l.Len(), b.Get(2).Len())
+	}
+
+	m := b.Map()
+	if m.Len() != 4 {
+		t.Fatalf("Unexpected map length: %d", m.Len())
+	}
+	itr := m.Iterator()
+	for _, exp := range []string{"bar", "foo", "baz", "qux"} {
+		if _, v, ok := itr.Next(); !ok || v != exp {
+			t.Fatalf("Unexpected value: %v, expected %v", v, exp)
+		}
+	}
+	if !itr.Done() {
+		t.Fatalf("Expected iterator to be done")
+	}
+
+	var r string
+	func() {
+		defer func() { r = recover().(string) }()
+		b.Add(4, "qux")
+	}()
+	if r != `immutable.SortedMultiMapBuilder: builder invalid after Map() invocation` {
+		t.Fatalf("unexpected panic: %q", r)
+	}
+
+	func() {
+		defer func() { r = recover().(string) }()
+		b.Map()
+	}()
+	if r != `immutable.SortedMultiMapBuilder.Map(): duplicate call to fetch map` {
+		t.Fatalf("unexpected panic: %q", r)
+	}
+}
+
+func TestSortedMultiMapBuilderLarge(t *testing.T) {
+	const n = 1000
+	b := NewSortedMultiMapBuilder[int, int](nil)
+	for i := 0; i < n; i++ {
+		b.Add(i%3, i)
+	}
+
+	m := b.Map()
+	if m.Len() != n {
+		t.Fatalf("Unexpected map length: %d", m.Len())
+	}
+	for k := 0; k < 3; k++ {
+		l := m.Get(k)
+		for i := 0; i < l.Len(); i++ {
+			if v := l.Get(i); v != k+i*3 {
+				t.Fatalf("Unexpected value for key %d at %d: %d", k, i, v)
+			}
+		}
+	}
+}
+
+func TestSortedMultiMapBuilderDeleteValue(t *testing.T) {
+	equal := func(a, b int) bool { return a == b }
+	b := NewSortedMultiMapBuilder[string, int](nil)
+	b.Add("a", 1)
+	b.Add("a", 2)
+	b.Add("b", 3)
+
+	b.DeleteValue("a", 1, equal)
+	b.DeleteValue("a", 5, equal)
+	b.DeleteValue("z", 1, equal)
+	if b.Len() != 2 {
+		t.Fatalf("Unexpected builder length: %d", b.Len())
+	}
+	if l := b.Get("a"); l.Len() != 1 || l.Get(0) != 2 {
+		t.Fatalf("Unexpected values after delete")
+	}
+
+	b.Add("a", 4)
+	b.DeleteValue("b", 3, equal)
+	if m := b.Map(); m.Len() != 2 || m.Get("b").Len() != 0 {
+		t.Fatalf("Unexpected key after deleting last value")
+	} else if l := m.Get("a"); l.Len() != 2 || l.Get(1) != 4 {
+		t.Fatalf("Unexpected values after add")
+	}
+}
+
+func TestSortedMultiMapStructKeys(t *testing.T) {
+	type key struct {
+		region string
+		id     int
+	}
+	c := ComparerFunc(func(a, b key) bool {
+		return a.region < b.region || (a.region == b.region && a.id < b.id)
+	})
+
+	m := NewSortedMultiMap[key, string](c)
+	m = m.Add(key{"us", 2}, "b").Add(key{"eu", 1}, "a").Add(key{"us", 2}, "c")
+
+	var values []string
+	for itr := m.Iterator(); !itr.Done(); {
+		_, v, _ := itr.Next()
+		values = append(values, v)
+	}
+	if got, exp := strings.Join(values, ","), "a,b,c"; got != exp {
+		t.Fatalf("Unexpected values: %s, expected %s", got, exp)
+	}
+}
@@ -0,0 +1,629 @@
+package immutable
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RRBList is a dense, ordered, indexed collection similar to List. Unlike
+// List, leaf nodes are not required to be full. Instead, each branch node
+// tracks the size of its subtrees so that an index can be located by searching
+// its size table. Every node other than the root is kept at least half full so
+// the depth of the tree is O(log n) regardless of the edits applied to it.
+//
+// This relaxation allows InsertAt(), RemoveAt(), Slice(), and Concat() to
+// operate in O(log n) time at any position by only copying the nodes along the
+// affected paths. The trade off is that Get() and Set() are slightly slower
+// than on List as each level must search its size table.
+type RRBList[T any] struct {
+	root rrbNode[T] // root node, nil if empty
+}
+
+// NewRRBList returns a new list containing values. Values are copied into
+// leaf nodes in bulk so the list is built in O(n) time.
+func NewRRBList[T any](values ...T) *RRBList[T] {
+	if len(values) == 0 {
+		return &RRBList[T]{}
+	}
+
+	// Split values evenly into leaves.
+	g := rrbGroups(len(values))
+	nodes := make([]rrbNode[T], g)
+	for i := range nodes {
+		lo, hi := len(values)*i/g, len(values)*(i+1)/g
+		leaf := &rrbLeafNode[T]{values: make([]T, hi-lo)}
+		copy(leaf.values, values[lo:hi])
+		nodes[i] = leaf
+	}
+
+	// Group each level of nodes into parent branches until the root is reached.
+	for d := uint(1); len(nodes) > 1; d++ {
+		g := rrbGroups(len(nodes))
+		parents := make([]rrbNode[T], g)
+		for i := range parents {
+			lo, hi := len(nodes)*i/g, len(nodes)*(i+1)/g
+			parents[i] = newRRBBranchNode(d, nodes[lo:hi:hi])
+		}
+		nodes = parents
+	}
+	return &RRBList[T]{root: nodes[0]}
+}
+
+// rrbGroups returns the number of nodes needed to hold n entries. Dividing the
+// entries evenly between the nodes ensures that none of them are underfull.
+func rrbGroups(n int) int {
+	return (n + rrbNodeSize - 1) / rrbNodeSize
+}
+
+// Len returns the number of elements in the list.
+func (l *RRBList[T]) Len() int {
+	if l.root == nil {
+		return 0
+	}
+	return l.root.len()
+}
+
+// IsEmpty returns true if the list contains no elements.
+func (l *RRBList[T]) IsEmpty() bool {
+	return l.root == nil
+}
+
+// Get returns the value at the given index. Similar to slices, this method will
+// panic if index is below zero or is greater than or equal to the list size.
+func (l *RRBList[T]) Get(index int) T {
+	if index < 0 || index >= l.Len() {
+		panic(fmt.Sprintf("immutable.RRBList.Get: index %d out of bounds", index))
+	}
+	return l.root.get(index)
+}
+
+// Set returns a new list with value set at index. Similar to slices, this
+// method will panic if index is below zero or if the index is greater than or
+// equal to the list size.
+func (l *RRBList[T]) Set(index int, value T) *RRBList[T] {
+	if index < 0 || index >= l.Len() {
+		panic(fmt.Sprintf("immutable.RRBList.Set: index %d out of bounds", index))
+	}
+	return &RRBList[T]{root: l.root.set(index, value)}
+}
+
+// Append returns a new list with value added to the end of the list.
+func (l *RRBList[T]) Append(value T) *RRBList[T] {
+	return l.InsertAt(l.Len(), value)
+}
+
+// Prepend returns a new list with value added to the beginning of the list.
+func (l *RRBList[T]) Prepend(value T) *RRBList[T] {
+	return l.InsertAt(0, value)
+}
+
+// InsertAt returns a new list with value inserted at index. Elements at and
+// after index are shifted up by one. Inserting at an index equal to the list
+// size is the same as appending. This method will panic if index is below zero
+// or greater than the list size.
+func (l *RRBList[T]) InsertAt(index int, value T) *RRBList[T] {
+	if index < 0 || index > l.Len() {
+		panic(fmt.Sprintf("immutable.RRBList.InsertAt: index %d out of bounds", index))
+	} else if l.root == nil {
+		return &RRBList[T]{root: &rrbLeafNode[T]{values: []T{value}}}
+	}
+
+	// If the root splits then grow the tree from the root.
+	newRoot, splitNode := l.root.insertAt(index, value)
+	if splitNode != nil {
+		newRoot = newRRBBranchNode(newRoot.depth()+1, []rrbNode[T]{newRoot, splitNode})
+	}
+	return &RRBList[T]{root: newRoot}
+}
+
+// RemoveAt returns a new list with the element at index removed. Elements
+// after index are shifted down by one. This method will panic if index is
+// below zero or greater than or equal to the list size.
+func (l *RRBList[T]) RemoveAt(index int) *RRBList[T] {
+	if index < 0 || index >= l.Len() {
+		panic(fmt.Sprintf("immutable.RRBList.RemoveAt: index %d out of bounds", index))
+	}
+	return &RRBList[T]{root: rrbCollapse(l.root.removeAt(index))}
+}
+
+// Slice returns a new list of elements between start index and end index.
+// Similar to slices, this method will panic if start or end are below zero or
+// greater than the list size. A panic will also occur if start is greater than
+// end.
+//
+// Nodes entirely within the range are shared with the original list and the
+// remaining nodes are joined back together in O(log n) time.
+func (l *RRBList[T]) Slice(start, end int) *RRBList[T] {
+	if start < 0 || start > l.Len() {
+		panic(fmt.Sprintf("immutable.RRBList.Slice: start index %d out of bounds", start))
+	} else if end < 0 || end > l.Len() {
+		panic(fmt.Sprintf("immutable.RRBList.Slice: end index %d out of bounds", end))
+	} else if start > end {
+		panic(fmt.Sprintf("immutable.RRBList.Slice: invalid slice index: [%d:%d]", start, end))
+	}
+
+	// Return the original list if the range covers all elements.
+	if start == 0 && end == l.Len() {
+		return l
+	} else if start == end {
+		return &RRBList[T]{}
+	}
+	return &RRBList[T]{root: rrbDrop(rrbTake(l.root, end), start)}
+}
+
+// Concat returns a new list with the elements of other added to the end of the
+// list. Only the nodes along the right edge of l and the left edge of other
+// are copied so this method runs in O(log n) time.
+func (l *RRBList[T]) Concat(other *RRBList[T]) *RRBList[T] {
+	if other.root == nil {
+		return l
+	} else if l.root == nil {
+		return other
+	}
+	return &RRBList[T]{root: rrbJoin(l.root, other.root)}
+}
+
+// ToSlice returns a new slice containing the elements of the list in order.
+func (l *RRBList[T]) ToSlice() []T {
+	a := make([]T, 0, l.Len())
+	if l.root != nil {
+		a = l.root.appendTo(a)
+	}
+	return a
+}
+
+// Iterator returns a new iterator for this list positioned at the first index.
+func (l *RRBList[T]) Iterator() *RRBListIterator[T] {
+	itr := &RRBListIterator[T]{list: l}
+	itr.First()
+	return itr
+}
+
+// RRBListIterator represents an ordered iterator over an RRBList.
+type RRBListIterator[T any] struct {
+	list  *RRBList[T]     // source list
+	index int             // current index position
+	leaf  *rrbLeafNode[T] // leaf containing the current index
+	pos   int             // position of the current index within leaf
+}
+
+// Done returns true if no more elements remain in the iterator.
+func (itr *RRBListIterator[T]) Done() bool {
+	return itr.index < 0 || itr.index >= itr.list.Len()
+}
+
+// First positions the iterator on the first index.
+// If source list is empty then no change is made.
+func (itr *RRBListIterator[T]) First() {
+	if itr.list.Len() != 0 {
+		itr.Seek(0)
+	}
+}
+
+// Last positions the iterator on the last index.
+// If source list is empty then no change is made.
+func (itr *RRBListIterator[T]) Last() {
+	if n := itr.list.Len(); n != 0 {
+		itr.Seek(n - 1)
+	}
+}
+
+// Seek moves the iterator position to the given index in the list.
+// Similar to Go slices, this method will panic if index is below zero or if
+// the index is greater than or equal to the list size.
+func (itr *RRBListIterator[T]) Seek(index int) {
+	if index < 0 || index >= itr.list.Len() {
+		panic(fmt.Sprintf("immutable.RRBListIterator.Seek: index %d out of bounds", index))
+	}
+	itr.index = index
+	itr.seek()
+}
+
+// Next returns the current index and its value & moves the iterator forward.
+// Returns an index of -1 if the there are no more elements to return.
+func (itr *RRBListIterator[T]) Next() (index int, value T) {
+	if itr.Done() {
+		return -1, value
+	}
+	index, value = itr.index, itr.leaf.values[itr.pos]
+
+	// Move to the next leaf once the current one is exhausted.
+	itr.index, itr.pos = itr.index+1, itr.pos+1
+	if itr.pos >= len(itr.leaf.values) && !itr.Done() {
+		itr.seek()
+	}
+	return index, value
+}
+
+// Prev returns the current index and value and moves the iterator backward.
+// Returns an index of -1 if the there are no more elements to return.
+func (itr *RRBListIterator[T]) Prev() (index int, value T) {
+	if itr.Done() {
+		return -1, value
+	}
+	index, value = itr.index, itr.leaf.values[itr.pos]
+
+	// Move to the previous leaf once the current one is exhausted.
+	itr.index, itr.pos = itr.index-1, itr.pos-1
+	if itr.pos < 0 && !itr.Done() {
+		itr.seek()
+	}
+	return index, value
+}
+
+// seek positions the iterator on the leaf containing the current index.
+func (itr *RRBListIterator[T]) seek() {
+	node, index := itr.list.root, itr.index
+	for {
+		switch n := node.(type) {
+		case *rrbBranchNode[T]:
+			i := n.indexOf(index)
+			node, index = n.children[i], index-n.offset(i)
+		case *rrbLeafNode[T]:
+			itr.leaf, itr.pos = n, index
+			return
+		}
+	}
+}
+
+// Maximum number of elements in a leaf or children in a branch.
+const rrbNodeSize = 32
+
+// Minimum number of elements in a leaf or children in a branch, except for
+// the root node which may hold fewer.
+const rrbMinNodeSize = rrbNodeSize / 2
+
+// rrbNode represents either a branch or leaf node in an RRBList.
+type rrbNode[T any] interface {
+	depth() uint
+	len() int
+	width() int
+	get(index int) T
+	set(index int, v T) rrbNode[T]
+	insertAt(index int, v T) (rrbNode[T], rrbNode[T])
+	removeAt(index int) rrbNode[T]
+	appendTo(a []T) []T
+}
+
+var _ rrbNode[string] = (*rrbBranchNode[string])(nil)
+var _ rrbNode[string] = (*rrbLeafNode[string])(nil)
+
+// rrbBranchNode represents a branch of an RRBList. Each entry of the size
+// table holds the total number of elements in the children up to and
+// including the child at the same index.
+type rrbBranchNode[T any] struct {
+	d        uint         // depth
+	children []rrbNode[T] // child nodes
+	sizes    []int        // cumulative child sizes
+}
+
+// newRRBBranchNode returns a new branch node with the given children and a
+// size table computed from them.
+func newRRBBranchNode[T any](d uint, children []rrbNode[T]) *rrbBranchNode[T] {
+	n := &rrbBranchNode[T]{d: d, children: children, sizes: make([]int, len(children))}
+	var size int
+	for i, child := range children {
+		size += child.len()
+		n.sizes[i] = size
+	}
+	return n
+}
+
+// depth returns the depth of this branch node from the leaf level.
+func (n *rrbBranchNode[T]) depth() uint { return n.d }
+
+// len returns the number of elements in this node's tree.
+func (n *rrbBranchNode[T]) len() int { return n.sizes[len(n.sizes)-1] }
+
+// width returns the number of children of the node.
+func (n *rrbBranchNode[T]) width() int { return len(n.children) }
+
+// indexOf returns the index of the child containing the element at index.
+// An index equal to the node's length returns the last child.
+func (n *rrbBranchNode[T]) indexOf(index int) int {
+	if i := sort.SearchInts(n.sizes, index+1); i < len(n.children) {
+		return i
+	}
+	return len(n.children) - 1
+}
+
+// offset returns the number of elements before the child at index i.
+func (n *rrbBranchNode[T]) offset(i int) int {
+	if i == 0 {
+		return 0
+	}
+	return n.sizes[i-1]
+}
+
+// get returns the element at index.
+func (n *rrbBranchNode[T]) get(index int) T {
+	i := n.indexOf(index)
+	return n.children[i].get(index - n.offset(i))
+}
+
+// set returns a copy of the node with the element at index set to v.
+func (n *rrbBranchNode[T]) set(index int, v T) rrbNode[T] {
+	i := n.indexOf(index)
+	other := *n
+	other.children = make([]rrbNode[T], len(n.children))
+	copy(other.children, n.children)
+	other.children[i] = n.children[i].set(index-n.offset(i), v)
+	return &other
+}
+
+// insertAt returns a copy of the node with v inserted at index. If the node
+// exceeds its capacity then it is split and the second half is returned.
+func (n *rrbBranchNode[T]) insertAt(index int, v T) (rrbNode[T], rrbNode[T]) {
+	i := n.indexOf(index)
+	newChild, splitChild := n.children[i].insertAt(index-n.offset(i), v)
+
+	children := make([]rrbNode[T], 0, len(n.children)+1)
+	children = append(children, n.children[:i]...)
+	children = append(children, newChild)
+	if splitChild != nil {
+		children = append(children, splitChild)
+	}
+	children = append(children, n.children[i+1:]...)
+	return n.split(children)
+}
+
+// removeAt returns a copy of the node with the element at index removed.
+// If the child becomes underfull then it is merged with a sibling so the
+// returned node may itself be underfull.
+func (n *rrbBranchNode[T]) removeAt(index int) rrbNode[T] {
+	i := n.indexOf(index)
+	newChild := n.children[i].removeAt(index - n.offset(i))
+
+	// Determine the range of children replaced by the new child & its sibling.
+	lo, hi := i, i+1
+	var node, split rrbNode[T]
+	switch {
+	case newChild.width() >= rrbMinNodeSize:
+		node = newChild
+	case i > 0:
+		lo--
+		node, split = rrbMerge(n.children[i-1], newChild)
+	default:
+		hi++
+		node, split = rrbMerge(newChild, n.children[i+1])
+	}
+
+	children := make([]rrbNode[T], 0, len(n.children))
+	children = append(children, n.children[:lo]...)
+	children = append(children, node)
+	if split != nil {
+		children = append(children, split)
+	}
+	children = append(children, n.children[hi:]...)
+	return newRRBBranchNode(n.d, children)
+}
+
+// appendTo appends all elements of the node to a and returns the result.
+func (n *rrbBranchNode[T]) appendTo(a []T) []T {
+	for _, child := range n.children {
+		a = child.appendTo(a)
+	}
+	return a
+}
+
+// split returns a new branch containing children. If there are more children
+// than fit in a single node then they are split evenly between two nodes.
+func (n *rrbBranchNode[T]) split(children []rrbNode[T]) (rrbNode[T], rrbNode[T]) {
+	if len(children) <= rrbNodeSize {
+		return newRRBBranchNode(n.d, children), nil
+	}
+	splitIdx := len(children) / 2
+	return newRRBBranchNode(n.d, children[:splitIdx:splitIdx]), newRRBBranchNode(n.d, children[splitIdx:])
+}
+
+// rrbLeafNode represents a leaf of an RRBList. Leaves hold between one and
+// rrbNodeSize elements.
+type rrbLeafNode[T any] struct {
+	values []T
+}
+
+// depth always returns 0 for leaf nodes.
+func (n *rrbLeafNode[T]) depth() uint { return 0 }
+
+// len returns the number of elements in the leaf.
+func (n *rrbLeafNode[T]) len() int { return len(n.values) }
+
+// width returns the number of elements in the leaf.
+func (n *rrbLeafNode[T]) width() int { return len(n.values) }
+
+// get returns the element at index.
+func (n *rrbLeafNode[T]) get(index int) T {
+	return n.values[index]
+}
+
+// set returns a copy of the leaf with the element at index set to v.
+func (n *rrbLeafNode[T]) set(index int, v T) rrbNode[T] {
+	other := &rrbLeafNode[T]{values: make([]T, len(n.values))}
+	copy(other.values, n.values)
+	other.values[index] = v
+	return other
+}
+
+// insertAt returns a copy of the leaf with v inserted at index. If the leaf
+// exceeds its capacity then it is split and the second half is returned.
+func (n *rrbLeafNode[T]) insertAt(index int, v T) (rrbNode[T], rrbNode[T]) {
+	values := make([]T, len(n.values)+1)
+	copy(values, n.values[:index])
+	values[index] = v
+	copy(values[index+1:], n.values[index:])
+	return rrbSplitLeaf(values)
+}
+
+// removeAt returns a copy of the leaf with the element at index removed.
+// Returns nil if the leaf becomes empty.
+func (n *rrbLeafNode[T]) removeAt(index int) rrbNode[T] {
+	if len(n.values) == 1 {
+		return nil
+	}
+	other := &rrbLeafNode[T]{values: make([]T, 0, len(n.values)-1)}
+	other.values = append(other.values, n.values[:index]...)
+	other.values = append(other.values, n.values[index+1:]...)
+	return other
+}
+
+// appendTo appends all elements of the leaf to a and returns the result.
+func (n *rrbLeafNode[T]) appendTo(a []T) []T {
+	return append(a, n.values...)
+}
+
+// rrbSplitLeaf returns a new leaf containing values. If there are more values
+// than fit in a single leaf then they are split evenly between two leaves.
+func rrbSplitLeaf[T any](values []T) (rrbNode[T], rrbNode[T]) {
+	if len(values) <= rrbNodeSize {
+		return &rrbLeafNode[T]{values: values}, nil
+	}
+	splitIdx := len(values) / 2
+	return &rrbLeafNode[T]{values: values[:splitIdx:splitIdx]}, &rrbLeafNode[T]{values: values[splitIdx:]}
+}
+
+// rrbCollapse removes branch nodes with a single child from the top of the
+// tree so that the root is as shallow as possible. Only the root of a tree may
+// have a single child after a removal.
+func rrbCollapse[T any](node rrbNode[T]) rrbNode[T] {
+	for {
+		n, ok := node.(*rrbBranchNode[T])
+		if !ok || len(n.children) > 1 {
+			return node
+		}
+		node = n.children[0]
+	}
+}
+
+// rrbTake returns a tree containing the first n elements of node. Children
+// before the one containing the last element are shared and joined with the
+// remainder of that child. The n argument must be between 1 and the node size.
+func rrbTake[T any](node rrbNode[T], n int) rrbNode[T] {
+	if n == node.len() {
+		return node
+	} else if leaf, ok := node.(*rrbLeafNode[T]); ok {
+		// Leaves are never modified so the underlying array is shared.
+		return &rrbLeafNode[T]{values: leaf.values[:n:n]}
+	}
+
+	branch := node.(*rrbBranchNode[T])
+	i := branch.indexOf(n - 1)
+	part := rrbTake(branch.children[i], n-branch.offset(i))
+	switch i {
+	case 0:
+		return part
+	case 1:
+		return rrbJoin(branch.children[0], part)
+	default:
+		return rrbJoin[T](newRRBBranchNode(branch.d, branch.children[:i:i]), part)
+	}
+}
+
+// rrbDrop returns a tree containing the elements of node after the first n.
+// See rrbTake() for more details. The n argument must be less than the node size.
+func rrbDrop[T any](node rrbNode[T], n int) rrbNode[T] {
+	if n == 0 {
+		return node
+	} else if leaf, ok := node.(*rrbLeafNode[T]); ok {
+		return &rrbLeafNode[T]{values: leaf.values[n:]}
+	}
+
+	branch := node.(*rrbBranchNode[T])
+	i := branch.indexOf(n)
+	part := rrbDrop(branch.children[i], n-branch.offset(i))
+	switch rest := branch.children[i+1:]; len(rest) {
+	case 0:
+		return part
+	case 1:
+		return rrbJoin(part, rest[0])
+	default:
+		return rrbJoin[T](part, newRRBBranchNode(branch.d, rest))
+	}
+}
+
+// rrbJoin returns a tree containing the elements of left followed by the
+// elements of right. The shorter tree is attached along the facing edge of the
+// taller tree so that all leaves remain at the same depth. Both trees must
+// only have an underfull node at their root.
+func rrbJoin[T any](left, right rrbNode[T]) rrbNode[T] {
+	var node, split rrbNode[T]
+	switch {
+	case left.depth() > right.depth():
+		node, split = rrbJoinRight(left.(*rrbBranchNode[T]), right)
+	case left.depth() < right.depth():
+		node, split = rrbJoinLeft(left, right.(*rrbBranchNode[T]))
+	default:
+		node, split = rrbMerge(left, right)
+	}
+
+	// Grow the tree if the join overflowed the root.
+	if split != nil {
+		return newRRBBranchNode(node.depth()+1, []rrbNode[T]{node, split})
+	}
+	return node
+}
+
+// rrbJoinRight attaches right along the right edge of n. Right must be
+// shorter than n.
+func rrbJoinRight[T any](n *rrbBranchNode[T], right rrbNode[T]) (rrbNode[T], rrbNode[T]) {
+	last := n.children[len(n.children)-1]
+
+	var node, split rrbNode[T]
+	if last.depth() == right.depth() {
+		node, split = rrbMerge(last, right)
+	} else {
+		node, split = rrbJoinRight(last.(*rrbBranchNode[T]), right)
+	}
+
+	children := make([]rrbNode[T], 0, len(n.children)+1)
+	children = append(children, n.children[:len(n.children)-1]...)
+	children = append(children, node)
+	if split != nil {
+		children = append(children, split)
+	}
+	return n.split(children)
+}
+
+// rrbJoinLeft attaches left along the left edge of n. Left must be shorter
+// than n.
+func rrbJoinLeft[T any](left rrbNode[T], n *rrbBranchNode[T]) (rrbNode[T], rrbNode[T]) {
+	first := n.children[0]
+
+	var node, split rrbNode[T]
+	if first.depth() == left.depth() {
+		node, split = rrbMerge(left, first)
+	} else {
+		node, split = rrbJoinLeft(left, first.(*rrbBranchNode[T]))
+	}
+
+	children := make([]rrbNode[T], 0, len(n.children)+1)
+	children = append(children, node)
+	if split != nil {
+		children = append(children, split)
+	}
+	children = append(children, n.children[1:]...)
+	return n.split(children)
+}
+
+// rrbMerge combines two nodes of the same depth into a single node if their
+// contents fit. Otherwise the contents are divided evenly between two nodes so
+// that neither is underfull. Nodes which are both at least half full are
+// returned unchanged.
+func rrbMerge[T any](left, right rrbNode[T]) (rrbNode[T], rrbNode[T]) {
+	if left.width() >= rrbMinNodeSize && right.width() >= rrbMinNodeSize {
+		return left, right
+	}
+
+	if l, ok := left.(*rrbBranchNode[T]); ok {
+		r := right.(*rrbBranchNode[T])
+		children := make([]rrbNode[T], 0, len(l.children)+len(r.children))
+		children = append(children, l.children...)
+		children = append(children, r.children...)
+		return l.split(children)
+	}
+
+	l, r := left.(*rrbLeafNode[T]), right.(*rrbLeafNode[T])
+	values := make([]T, 0, len(l.values)+len(r.values))
+	values = append(values, l.values...)
+	values = append(values, r.values...)
+	return rrbSplitLeaf(values)
+}
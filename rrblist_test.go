@@ -0,0 +1,285 @@
+package immutable
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestRRBList(t *testing.T) {
+	t.Run("Empty", func(t *testing.T) {
+		l := NewRRBList[int]()
+		if l.Len() != 0 || !l.IsEmpty() {
+			t.Fatalf("unexpected size: %d", l.Len())
+		} else if itr := l.Iterator(); !itr.Done() {
+			t.Fatal("expected iterator done")
+		}
+	})
+
+	t.Run("Bulk", func(t *testing.T) {
+		for _, n := range []int{1, 31, 32, 33, 1024, 1025, 40000} {
+			a := make([]int, n)
+			for i := range a {
+				a[i] = i
+			}
+			l := NewRRBList(a...)
+			if err := validateRRBList(l); err != nil {
+				t.Fatalf("n=%d: %s", n, err)
+			} else if got, exp := fmt.Sprint(l.ToSlice()), fmt.Sprint(a); got != exp {
+				t.Fatalf("n=%d: unexpected values", n)
+			}
+		}
+	})
+
+	t.Run("InsertRemove", func(t *testing.T) {
+		l := NewRRBList[string]()
+		l = l.Append("b").Prepend("a").Append("d").InsertAt(2, "c")
+		if got, exp := fmt.Sprint(l.ToSlice()), "[a b c d]"; got != exp {
+			t.Fatalf("unexpected values: %s, expected %s", got, exp)
+		}
+
+		other := l.RemoveAt(1).Set(0, "z")
+		if got, exp := fmt.Sprint(other.ToSlice()), "[z c d]"; got != exp {
+			t.Fatalf("unexpected values: %s, expected %s", got, exp)
+		} else if got, exp := fmt.Sprint(l.ToSlice()), "[a b c d]"; got != exp {
+			t.Fatalf("original list changed: %s", got)
+		}
+	})
+
+	t.Run("SliceConcat", func(t *testing.T) {
+		a := make([]int, 5000)
+		for i := range a {
+			a[i] = i
+		}
+		l := NewRRBList(a...)
+
+		head, tail := l.Slice(0, 1234), l.Slice(1234, 5000)
+		if head.Get(1233) != 1233 || tail.Get(0) != 1234 {
+			t.Fatal("unexpected slice values")
+		}
+		other := head.Concat(NewRRBList(-1)).Concat(tail)
+		if err := validateRRBList(other); err != nil {
+			t.Fatal(err)
+		} else if other.Len() != 5001 || other.Get(1234) != -1 || other.Get(1235) != 1234 {
+			t.Fatalf("unexpected concat result")
+		}
+		if l.Slice(0, 5000) != l {
+			t.Fatal("expected full slice to return original list")
+		} else if !l.Slice(10, 10).IsEmpty() {
+			t.Fatal("expected empty slice")
+		}
+	})
+
+	t.Run("SliceConcatDepth", func(t *testing.T) {
+		a := make([]int, 5000)
+		for i := range a {
+			a[i] = i
+		}
+		l := NewRRBList(a...)
+
+		// Repeatedly slice across the first child boundary and concatenate
+		// copies of the result. The depth should not grow with each round.
+		for i := 0; i < 30; i++ {
+			off := l.root.(*rrbBranchNode[int]).sizes[0]
+			pair := l.Slice(off-1, off+1)
+			l = pair
+			for j := 0; j < 40; j++ {
+				l = l.Concat(pair)
+			}
+			if err := validateRRBList(l); err != nil {
+				t.Fatalf("%d: %s", i, err)
+			} else if err := validateRRBDepth(l); err != nil {
+				t.Fatalf("%d: %s", i, err)
+			}
+		}
+	})
+
+	t.Run("RemoveMerge", func(t *testing.T) {
+		a := make([]int, 32000)
+		for i := range a {
+			a[i] = i
+		}
+
+		// Remove all but one element of each full leaf.
+		l := NewRRBList(a...)
+		for i := 1000; i > 0; i-- {
+			for j := 31; j > 0; j-- {
+				l = l.RemoveAt((i-1)*32 + j)
+			}
+		}
+		if l.Len() != 1000 {
+			t.Fatalf("unexpected len: %d", l.Len())
+		} else if err := validateRRBList(l); err != nil {
+			t.Fatal(err)
+		} else if err := validateRRBDepth(l); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < l.Len(); i++ {
+			if v := l.Get(i); v != i*32 {
+				t.Fatalf("Get(%d)=%d, expected %d", i, v, i*32)
+			}
+		}
+	})
+
+	t.Run("Iterator", func(t *testing.T) {
+		l := NewRRBList[int]()
+		for i := 0; i < 200; i++ {
+			l = l.InsertAt(l.Len()/2, i)
+		}
+		exp := l.ToSlice()
+
+		itr := l.Iterator()
+		for i := 0; !itr.Done(); i++ {
+			if index, v := itr.Next(); index != i || v != exp[i] {
+				t.Fatalf("Next()=<%d,%d>, expected <%d,%d>", index, v, i, exp[i])
+			}
+		}
+		for itr.Last(); !itr.Done(); {
+			i := itr.index
+			if index, v := itr.Prev(); index != i || v != exp[i] {
+				t.Fatalf("Prev()=<%d,%d>, expected <%d,%d>", index, v, i, exp[i])
+			}
+		}
+		if index, _ := itr.Next(); index != -1 {
+			t.Fatalf("unexpected index: %d", index)
+		}
+	})
+
+	t.Run("Panics", func(t *testing.T) {
+		l := NewRRBList(1, 2, 3)
+		for _, tt := range []struct {
+			fn  func()
+			exp string
+		}{
+			{func() { l.Get(3) }, `immutable.RRBList.Get: index 3 out of bounds`},
+			{func() { l.Set(-1, 0) }, `immutable.RRBList.Set: index -1 out of bounds`},
+			{func() { l.InsertAt(4, 0) }, `immutable.RRBList.InsertAt: index 4 out of bounds`},
+			{func() { l.RemoveAt(3) }, `immutable.RRBList.RemoveAt: index 3 out of bounds`},
+			{func() { l.Slice(2, 1) }, `immutable.RRBList.Slice: invalid slice index: [2:1]`},
+			{func() { l.Iterator().Seek(3) }, `immutable.RRBListIterator.Seek: index 3 out of bounds`},
+		} {
+			var r string
+			func() {
+				defer func() { r = fmt.Sprint(recover()) }()
+				tt.fn()
+			}()
+			if r != tt.exp {
+				t.Fatalf("unexpected panic: %q, expected %q", r, tt.exp)
+			}
+		}
+	})
+
+	RunRandom(t, "Random", func(t *testing.T, rand *rand.Rand) {
+		l, std := NewRRBList[int](), []int{}
+		for i := 0; i < 10000; i++ {
+			switch rnd := rand.Intn(10); {
+			case rnd == 0 && len(std) > 0: // remove
+				index := rand.Intn(len(std))
+				l, std = l.RemoveAt(index), append(std[:index:index], std[index+1:]...)
+			case rnd == 1 && len(std) > 0: // set
+				index, v := rand.Intn(len(std)), rand.Intn(10000)
+				l = l.Set(index, v)
+				std = append([]int(nil), std...)
+				std[index] = v
+			case rnd == 2: // slice
+				start := rand.Intn(len(std) + 1)
+				end := start + rand.Intn(len(std)-start+1)
+				l, std = l.Slice(start, end), std[start:end:end]
+			case rnd == 3: // concat
+				a := randomInts(rand, rand.Intn(2000))
+				if rand.Intn(2) == 0 {
+					l, std = l.Concat(NewRRBList(a...)), append(std[:len(std):len(std)], a...)
+				} else {
+					l, std = NewRRBList(a...).Concat(l), append(a, std...)
+				}
+			default: // insert
+				index, v := rand.Intn(len(std)+1), rand.Intn(10000)
+				l = l.InsertAt(index, v)
+				std = append(std[:index:index], append([]int{v}, std[index:]...)...)
+			}
+
+			if l.Len() != len(std) {
+				t.Fatalf("unexpected len: %d, expected %d", l.Len(), len(std))
+			} else if err := validateRRBDepth(l); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := validateRRBList(l); err != nil {
+			t.Fatal(err)
+		} else if got, exp := fmt.Sprint(l.ToSlice()), fmt.Sprint(std); got != exp {
+			t.Fatal("unexpected values")
+		}
+		for i := range std {
+			if v := l.Get(i); v != std[i] {
+				t.Fatalf("Get(%d)=%d, expected %d", i, v, std[i])
+			}
+		}
+	})
+}
+
+// validateRRBList checks that the size tables of every branch match their
+// children, that all leaves are at the same depth, and that every node other
+// than the root is at least half full.
+func validateRRBList[T any](l *RRBList[T]) error {
+	if l.root == nil {
+		return nil
+	} else if n, ok := l.root.(*rrbBranchNode[T]); ok && len(n.children) < 2 {
+		return fmt.Errorf("root branch has %d children", len(n.children))
+	}
+	_, err := validateRRBNode(l.root, l.root.depth(), true)
+	return err
+}
+
+func validateRRBNode[T any](node rrbNode[T], depth uint, root bool) (int, error) {
+	if node.depth() != depth {
+		return 0, fmt.Errorf("unexpected node depth: %d, expected %d", node.depth(), depth)
+	} else if !root && node.width() < rrbMinNodeSize {
+		return 0, fmt.Errorf("underfull node at depth %d: %d", depth, node.width())
+	}
+
+	switch n := node.(type) {
+	case *rrbBranchNode[T]:
+		if len(n.children) == 0 || len(n.children) > rrbNodeSize {
+			return 0, fmt.Errorf("invalid branch child count: %d", len(n.children))
+		} else if len(n.sizes) != len(n.children) {
+			return 0, fmt.Errorf("size table mismatch: %d != %d", len(n.sizes), len(n.children))
+		}
+
+		var size int
+		for i, child := range n.children {
+			sz, err := validateRRBNode(child, depth-1, false)
+			if err != nil {
+				return 0, err
+			}
+			if size += sz; n.sizes[i] != size {
+				return 0, fmt.Errorf("size table entry %d: %d, expected %d", i, n.sizes[i], size)
+			}
+		}
+		return size, nil
+
+	case *rrbLeafNode[T]:
+		if len(n.values) == 0 || len(n.values) > rrbNodeSize {
+			return 0, fmt.Errorf("invalid leaf size: %d", len(n.values))
+		}
+		return len(n.values), nil
+	}
+	return 0, fmt.Errorf("unexpected node type: %T", node)
+}
+
+// validateRRBDepth checks that the depth of the list is no more than the
+// number of times the list size can be divided by the minimum node size.
+func validateRRBDepth[T any](l *RRBList[T]) error {
+	if l.root == nil {
+		return nil
+	}
+
+	var max uint
+	for n := l.Len(); n > rrbMinNodeSize; n /= rrbMinNodeSize {
+		max++
+	}
+	if d := l.root.depth(); d > max {
+		return fmt.Errorf("depth %d exceeds %d for %d elements", d, max, l.Len())
+	}
+	return nil
+}
@@ -1,5 +1,9 @@
 package immutable
 
+import (
+	"sort"
+)
+
 // Set represents a collection of unique values. The set uses a Hasher
 // to generate hashes and check for equality of key values.
 //
@@ -10,8 +14,10 @@ type Set[T any] struct {
 
 // NewSet returns a new instance of Set.
 //
-// If hasher is nil, a default hasher implementation will automatically be chosen based on the first key added.
-// Default hasher implementations only exist for int, string, and byte slice types.
+// If hasher is nil, a default hasher implementation is chosen based on the
+// value type when the set is created. For interface value types, it is instead
+// chosen based on the first value added. Default hasher implementations only
+// exist for int, string, and byte slice types.
 // NewSet can also take some initial values as varargs.
 func NewSet[T any](hasher Hasher[T], values ...T) Set[T] {
 	m := NewMap[T, struct{}](hasher)
@@ -21,11 +27,44 @@ func NewSet[T any](hasher Hasher[T], values ...T) Set[T] {
 	return Set[T]{m}
 }
 
-// Add returns a set containing the new value.
+// NewSetFromSlice returns a new instance of Set containing the values of the
+// slice. Like NewSet(), values are added in place without creating
+// intermediate sets.
+func NewSetFromSlice[T any](hasher Hasher[T], values []T) Set[T] {
+	return NewSet(hasher, values...)
+}
+
+// NewSetE returns a new instance of Set. Unlike NewSet(), an
+// UnsupportedKeyTypeError is returned if hasher is nil and no default hasher
+// exists for the value type.
+func NewSetE[T any](hasher Hasher[T], values ...T) (Set[T], error) {
+	m, err := NewMapE[T, struct{}](hasher)
+	if err != nil {
+		return Set[T]{}, err
+	}
+	for _, value := range values {
+		m = m.set(value, struct{}{}, true)
+	}
+	return Set[T]{m}, nil
+}
+
+// Add returns a set containing the new values. Returns the original set if
+// no values are given.
 //
-// This function will return a new set even if the set already contains the value.
-func (s Set[T]) Add(value T) Set[T] {
-	return Set[T]{s.m.Set(value, struct{}{})}
+// This function will return a new set even if the set already contains the
+// values. The set is copied once and values are added in-place. Nodes shared
+// with the original set are copied the first time they are updated.
+func (s Set[T]) Add(values ...T) Set[T] {
+	if len(values) == 0 {
+		return s
+	}
+
+	m := s.m.clone()
+	owned := make(map[mapNode[T, struct{}]]struct{})
+	for _, value := range values {
+		m.setOwned(value, struct{}{}, owned)
+	}
+	return Set[T]{m}
 }
 
 // Delete returns a set with the given key removed.
@@ -33,6 +72,24 @@ func (s Set[T]) Delete(value T) Set[T] {
 	return Set[T]{s.m.Delete(value)}
 }
 
+// DifferenceSlice returns a set with all of the given values removed.
+// The returned set uses the same hasher as the original set.
+//
+// The set is copied once and values are removed in-place. Nodes shared with
+// the original set are copied the first time they are updated.
+func (s Set[T]) DifferenceSlice(values []T) Set[T] {
+	if len(values) == 0 {
+		return s
+	}
+
+	m := s.m.clone()
+	owned := make(map[mapNode[T, struct{}]]struct{})
+	for _, value := range values {
+		m.deleteOwned(value, owned)
+	}
+	return Set[T]{m}
+}
+
 // Has returns true when the set contains the given value
 func (s Set[T]) Has(val T) bool {
 	_, ok := s.m.Get(val)
@@ -44,6 +101,12 @@ func (s Set[K]) Len() int {
 	return s.m.Len()
 }
 
+// IsEmpty returns true if the set contains no elements. The zero value of
+// Set is empty.
+func (s Set[T]) IsEmpty() bool {
+	return s.m == nil || s.m.IsEmpty()
+}
+
 // Items returns a slice of the items inside the set
 func (s Set[T]) Items() []T {
 	r := make([]T, 0, s.Len())
@@ -55,6 +118,52 @@ func (s Set[T]) Items() []T {
 	return r
 }
 
+// ToSlice returns a slice of the items inside the set. The order of the items
+// is unspecified. It is the inverse of NewSetFromSlice().
+func (s Set[T]) ToSlice() []T {
+	return s.Items()
+}
+
+// SortedSlice returns a slice of the items inside the set sorted by cmp.
+// The cmp function returns a negative number if a sorts before b, a positive
+// number if a sorts after b, and zero if they are equal.
+func (s Set[T]) SortedSlice(cmp func(a, b T) int) []T {
+	r := s.Items()
+	sort.Slice(r, func(i, j int) bool { return cmp(r[i], r[j]) < 0 })
+	return r
+}
+
+// Equal returns true if s and other contain the same values.
+func (s Set[T]) Equal(other Set[T]) bool {
+	if s.Len() != other.Len() {
+		return false
+	}
+	for itr := s.Iterator(); !itr.Done(); {
+		if v, _ := itr.Next(); !other.Has(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubsetOf returns true if every value in s is also in other.
+func (s Set[T]) IsSubsetOf(other Set[T]) bool {
+	if s.Len() > other.Len() {
+		return false
+	}
+	for itr := s.Iterator(); !itr.Done(); {
+		if v, _ := itr.Next(); !other.Has(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSupersetOf returns true if every value in other is also in s.
+func (s Set[T]) IsSupersetOf(other Set[T]) bool {
+	return other.IsSubsetOf(s)
+}
+
 // Iterator returns a new iterator for this set positioned at the first value.
 func (s Set[T]) Iterator() *SetIterator[T] {
 	itr := &SetIterator[T]{mi: s.m.Iterator()}
@@ -63,7 +172,8 @@ func (s Set[T]) Iterator() *SetIterator[T] {
 }
 
 // SetIterator represents an iterator over a set.
-// Iteration can occur in natural or reverse order based on use of Next() or Prev().
+// Unlike ListIterator, values are returned without an index since sets are
+// unordered.
 type SetIterator[T any] struct {
 	mi *MapIterator[T, struct{}]
 }
@@ -78,45 +188,114 @@ func (itr *SetIterator[T]) First() {
 	itr.mi.First()
 }
 
-// Next moves the iterator to the next value.
+// Next returns the current value and moves the iterator forward.
+// Returns false if there are no more values to return.
 func (itr *SetIterator[T]) Next() (val T, ok bool) {
 	val, _, ok = itr.mi.Next()
 	return
 }
 
 type SetBuilder[T any] struct {
-	s Set[T]
+	s      Set[T]
+	shared bool // true if set nodes are shared with another set
+
+	owned map[mapNode[T, struct{}]]struct{} // nodes copied since the set became shared
 }
 
 func NewSetBuilder[T any](hasher Hasher[T]) *SetBuilder[T] {
 	return &SetBuilder[T]{s: NewSet(hasher)}
 }
 
-func (s SetBuilder[T]) Set(val T) {
+// NewSetBuilderFromSet returns a new builder seeded with the values of s.
+// The builder shares the nodes of s and only copies the nodes along the path
+// to each value as it is added or removed. Mutating the builder never affects
+// s.
+func NewSetBuilderFromSet[T any](s Set[T]) *SetBuilder[T] {
+	return &SetBuilder[T]{s: s, shared: true}
+}
+
+func (s *SetBuilder[T]) Set(val T) {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
+	if s.own() {
+		s.s.m.setOwned(val, struct{}{}, s.owned)
+		return
+	}
 	s.s.m = s.s.m.set(val, struct{}{}, true)
 }
 
-func (s SetBuilder[T]) Delete(val T) {
+func (s *SetBuilder[T]) Delete(val T) {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
+	if s.own() {
+		s.s.m.deleteOwned(val, s.owned)
+		return
+	}
 	s.s.m = s.s.m.delete(val, true)
 }
 
-func (s SetBuilder[T]) Has(val T) bool {
+// Build returns the current state of the builder as an immutable set. The
+// builder remains valid after the call and later changes to the builder do
+// not affect the returned set.
+//
+// The returned set shares its nodes with the builder so later mutations copy
+// nodes as described in NewSetBuilderFromSet().
+func (s *SetBuilder[T]) Build() Set[T] {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
+	s.shared, s.owned = true, nil
+	return Set[T]{s.s.m.clone()}
+}
+
+// own returns true if the set nodes are shared with another set. The map is
+// copied on first use so that updates can be made in-place through setOwned()
+// and deleteOwned(), which copy shared nodes and track them in owned.
+func (s *SetBuilder[T]) own() bool {
+	if !s.shared {
+		return false
+	} else if s.owned == nil {
+		s.s = Set[T]{s.s.m.clone()}
+		s.owned = make(map[mapNode[T, struct{}]]struct{})
+	}
+	return true
+}
+
+func (s *SetBuilder[T]) Has(val T) bool {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
 	return s.s.Has(val)
 }
 
-func (s SetBuilder[T]) Len() int {
+func (s *SetBuilder[T]) Len() int {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
 	return s.s.Len()
 }
 
+// Iterator returns a new iterator for the underlying set positioned at the
+// first value.
+func (s *SetBuilder[T]) Iterator() *SetIterator[T] {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
+	return s.s.Iterator()
+}
+
+// IsEmpty returns true if the underlying set contains no elements.
+func (s *SetBuilder[T]) IsEmpty() bool {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
+	return s.s.IsEmpty()
+}
+
+// Clear removes all values from the underlying set while keeping its hasher.
+func (s *SetBuilder[T]) Clear() {
+	assert(s.s.m != nil, "immutable.SetBuilder: builder not initialized, use NewSetBuilder()")
+	s.s, s.shared, s.owned = NewSet(s.s.m.hasher), false, nil
+}
+
 type SortedSet[T any] struct {
 	m *SortedMap[T, struct{}]
 }
 
 // NewSortedSet returns a new instance of SortedSet.
 //
-// If comparer is nil then
-// a default comparer is set after the first key is inserted. Default comparers
-// exist for int, string, and byte slice keys.
+// If comparer is nil then a default comparer is chosen based on the value type
+// when the set is created. For interface value types, it is instead chosen
+// after the first value is inserted. Default comparers exist for int, string,
+// and byte slice values.
 // NewSortedSet can also take some initial values as varargs.
 func NewSortedSet[T any](comparer Comparer[T], values ...T) SortedSet[T] {
 	m := NewSortedMap[T, struct{}](comparer)
@@ -126,11 +305,39 @@ func NewSortedSet[T any](comparer Comparer[T], values ...T) SortedSet[T] {
 	return SortedSet[T]{m}
 }
 
-// Add returns a set containing the new value.
-//
-// This function will return a new set even if the set already contains the value.
-func (s SortedSet[T]) Add(value T) SortedSet[T] {
-	return SortedSet[T]{s.m.Set(value, struct{}{})}
+// NewSortedSetFromSlice returns a new instance of SortedSet containing the
+// values of the slice.
+func NewSortedSetFromSlice[T any](comparer Comparer[T], values []T) SortedSet[T] {
+	return NewSortedSet(comparer, values...)
+}
+
+// NewSortedSetE returns a new instance of SortedSet. Unlike NewSortedSet(), an
+// UnsupportedKeyTypeError is returned if comparer is nil and no default
+// comparer exists for the value type.
+func NewSortedSetE[T any](comparer Comparer[T], values ...T) (SortedSet[T], error) {
+	m, err := NewSortedMapE[T, struct{}](comparer)
+	if err != nil {
+		return SortedSet[T]{}, err
+	}
+	for _, value := range values {
+		m = m.set(value, struct{}{}, true)
+	}
+	return SortedSet[T]{m}, nil
+}
+
+// Add returns a set containing the new values. Returns the original set if
+// no values are given. See Set.Add() for more details.
+func (s SortedSet[T]) Add(values ...T) SortedSet[T] {
+	if len(values) == 0 {
+		return s
+	}
+
+	m := s.m.clone()
+	owned := make(map[sortedMapNode[T, struct{}]]struct{})
+	for _, value := range values {
+		m.setOwned(value, struct{}{}, owned)
+	}
+	return SortedSet[T]{m}
 }
 
 // Delete returns a set with the given key removed.
@@ -149,6 +356,48 @@ func (s SortedSet[K]) Len() int {
 	return s.m.Len()
 }
 
+// IsEmpty returns true if the set contains no elements. The zero value of
+// SortedSet is empty.
+func (s SortedSet[T]) IsEmpty() bool {
+	return s.m == nil || s.m.IsEmpty()
+}
+
+// Min returns the smallest value in the set. Returns false if the set is empty.
+func (s SortedSet[T]) Min() (val T, ok bool) {
+	if s.IsEmpty() {
+		return val, false
+	}
+	val, _, ok = s.m.Min()
+	return val, ok
+}
+
+// Max returns the largest value in the set. Returns false if the set is empty.
+func (s SortedSet[T]) Max() (val T, ok bool) {
+	if s.IsEmpty() {
+		return val, false
+	}
+	val, _, ok = s.m.Max()
+	return val, ok
+}
+
+// PopMin returns the smallest value in the set along with a set with that
+// value removed. Returns the original set and false if the set is empty.
+func (s SortedSet[T]) PopMin() (val T, other SortedSet[T], ok bool) {
+	if val, ok = s.Min(); !ok {
+		return val, s, false
+	}
+	return val, s.Delete(val), true
+}
+
+// PopMax returns the largest value in the set along with a set with that
+// value removed. Returns the original set and false if the set is empty.
+func (s SortedSet[T]) PopMax() (val T, other SortedSet[T], ok bool) {
+	if val, ok = s.Max(); !ok {
+		return val, s, false
+	}
+	return val, s.Delete(val), true
+}
+
 // Items returns a slice of the items inside the set
 func (s SortedSet[T]) Items() []T {
 	r := make([]T, 0, s.Len())
@@ -160,6 +409,172 @@ func (s SortedSet[T]) Items() []T {
 	return r
 }
 
+// ToSlice returns a slice of the items inside the set in ascending order.
+// It is the inverse of NewSortedSetFromSlice().
+func (s SortedSet[T]) ToSlice() []T {
+	return s.Items()
+}
+
+// Split returns two sets partitioned at val. The first set contains all values
+// less than val and the second contains all values greater than or equal to
+// val. Both sets use the same comparer as the original set.
+func (s SortedSet[T]) Split(val T) (less, greaterOrEqual SortedSet[T]) {
+	lb := NewSortedSetBuilder(s.m.comparer)
+	gb := NewSortedSetBuilder(s.m.comparer)
+	for itr := s.Iterator(); !itr.Done(); {
+		v, _ := itr.Next()
+		if s.m.comparer.Compare(v, val) < 0 {
+			lb.Set(v)
+		} else {
+			gb.Set(v)
+		}
+	}
+	return lb.SortedSet(), gb.SortedSet()
+}
+
+// ForEachRange invokes fn for each value in the range [lo, hi) in ascending
+// order. Iteration stops if fn returns false. Empty or inverted ranges do not
+// invoke fn.
+func (s SortedSet[T]) ForEachRange(lo, hi T, fn func(T) bool) {
+	s.m.ascendRange(lo, hi, func(key T, _ struct{}) bool { return fn(key) })
+}
+
+// Equal returns true if s and other contain the same values. Values are
+// compared with the comparer of s in a single pass over both sets.
+func (s SortedSet[T]) Equal(other SortedSet[T]) bool {
+	if s.Len() != other.Len() {
+		return false
+	} else if s.Len() == 0 {
+		return true
+	}
+
+	for a, o := s.Iterator(), other.Iterator(); !a.Done(); {
+		av, _ := a.Next()
+		ov, _ := o.Next()
+		if s.m.comparer.Compare(av, ov) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IsSubsetOf returns true if every value in s is also in other. Values are
+// compared with the comparer of s in a single pass over both sets.
+func (s SortedSet[T]) IsSubsetOf(other SortedSet[T]) bool {
+	if s.Len() > other.Len() {
+		return false
+	} else if s.Len() == 0 {
+		return true
+	}
+
+	a, o := s.Iterator(), other.Iterator()
+	for !a.Done() {
+		av, _ := a.Next()
+		for {
+			ov, ok := o.Next()
+			if !ok {
+				return false
+			} else if cmp := s.m.comparer.Compare(av, ov); cmp == 0 {
+				break
+			} else if cmp < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsSupersetOf returns true if every value in other is also in s.
+func (s SortedSet[T]) IsSupersetOf(other SortedSet[T]) bool {
+	return other.IsSubsetOf(s)
+}
+
+// Union returns a set containing the values in either s or other.
+// The returned set uses the comparer of s.
+func (s SortedSet[T]) Union(other SortedSet[T]) SortedSet[T] {
+	return s.merge(other, true, true, true)
+}
+
+// Intersect returns a set containing the values in both s and other.
+// The returned set uses the comparer of s.
+func (s SortedSet[T]) Intersect(other SortedSet[T]) SortedSet[T] {
+	return s.merge(other, false, true, false)
+}
+
+// Difference returns a set containing the values in s which are not in other.
+// The returned set uses the comparer of s.
+func (s SortedSet[T]) Difference(other SortedSet[T]) SortedSet[T] {
+	return s.merge(other, true, false, false)
+}
+
+// merge walks s and other in sorted order and returns a set of the values
+// selected by the flags. Values only in s are kept if left is true, values in
+// both sets are kept if both is true, and values only in other are kept if
+// right is true.
+func (s SortedSet[T]) merge(other SortedSet[T], left, both, right bool) SortedSet[T] {
+	comparer := s.m.comparer
+	if comparer == nil {
+		comparer = other.m.comparer
+	}
+
+	// The walk below requires both sets to iterate in the order of comparer so
+	// other is rebuilt if it was created with a different comparer.
+	if comparer != nil && !other.sortedBy(comparer) {
+		other = NewSortedSet(comparer, other.Items()...)
+	}
+
+	// Values are produced in sorted order so the result can be bulk loaded.
+	var values []T
+	a, o := s.Iterator(), other.Iterator()
+	av, aok := a.Next()
+	ov, ook := o.Next()
+	for aok || ook {
+		var cmp int
+		if !aok {
+			cmp = 1
+		} else if !ook {
+			cmp = -1
+		} else {
+			cmp = comparer.Compare(av, ov)
+		}
+
+		switch {
+		case cmp < 0:
+			if left {
+				values = append(values, av)
+			}
+			av, aok = a.Next()
+		case cmp > 0:
+			if right {
+				values = append(values, ov)
+			}
+			ov, ook = o.Next()
+		default:
+			if both {
+				values = append(values, av)
+			}
+			av, aok = a.Next()
+			ov, ook = o.Next()
+		}
+	}
+	return SortedSet[T]{NewSortedMapBuilderFromSorted(comparer, values, make([]struct{}, len(values))).Map()}
+}
+
+// sortedBy returns true if the values of s are in increasing order as
+// determined by comparer.
+func (s SortedSet[T]) sortedBy(comparer Comparer[T]) bool {
+	itr := s.Iterator()
+	prev, ok := itr.Next()
+	for ok {
+		var v T
+		if v, ok = itr.Next(); ok && comparer.Compare(prev, v) >= 0 {
+			return false
+		}
+		prev = v
+	}
+	return true
+}
+
 // Iterator returns a new iterator for this set positioned at the first value.
 func (s SortedSet[T]) Iterator() *SortedSetIterator[T] {
 	itr := &SortedSetIterator[T]{mi: s.m.Iterator()}
@@ -208,8 +623,19 @@ func (itr *SortedSetIterator[T]) Seek(val T) {
 	itr.mi.Seek(val)
 }
 
+// SeekReverse moves the iterator to the given value for iteration with Prev().
+//
+// If the value does not exist then the previous value is used. If no previous
+// values exist then the iterator is marked as done.
+func (itr *SortedSetIterator[T]) SeekReverse(val T) {
+	itr.mi.SeekReverse(val)
+}
+
 type SortedSetBuilder[T any] struct {
-	s *SortedSet[T]
+	s      *SortedSet[T]
+	shared bool // true if set nodes are shared with another set
+
+	owned map[sortedMapNode[T, struct{}]]struct{} // nodes copied since the set became shared
 }
 
 func NewSortedSetBuilder[T any](comparer Comparer[T]) *SortedSetBuilder[T] {
@@ -217,27 +643,92 @@ func NewSortedSetBuilder[T any](comparer Comparer[T]) *SortedSetBuilder[T] {
 	return &SortedSetBuilder[T]{s: &s}
 }
 
-func (s SortedSetBuilder[T]) Set(val T) {
+// NewSortedSetBuilderFromSet returns a new builder seeded with the values of s.
+// The builder shares the nodes of s and only copies the nodes along the path
+// to each value as it is added or removed. Mutating the builder never affects
+// s.
+func NewSortedSetBuilderFromSet[T any](s SortedSet[T]) *SortedSetBuilder[T] {
+	return &SortedSetBuilder[T]{s: &s, shared: true}
+}
+
+func (s *SortedSetBuilder[T]) Set(val T) {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
+	if s.own() {
+		s.s.m.setOwned(val, struct{}{}, s.owned)
+		return
+	}
 	s.s.m = s.s.m.set(val, struct{}{}, true)
 }
 
-func (s SortedSetBuilder[T]) Delete(val T) {
+func (s *SortedSetBuilder[T]) Delete(val T) {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
+	if s.own() {
+		s.s.m.deleteOwned(val, s.owned)
+		return
+	}
 	s.s.m = s.s.m.delete(val, true)
 }
 
-func (s SortedSetBuilder[T]) Has(val T) bool {
+// own returns true if the set nodes are shared with another set. See
+// SetBuilder.own() for more details.
+func (s *SortedSetBuilder[T]) own() bool {
+	if !s.shared {
+		return false
+	} else if s.owned == nil {
+		s.s = &SortedSet[T]{s.s.m.clone()}
+		s.owned = make(map[sortedMapNode[T, struct{}]]struct{})
+	}
+	return true
+}
+
+func (s *SortedSetBuilder[T]) Has(val T) bool {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
 	return s.s.Has(val)
 }
 
-func (s SortedSetBuilder[T]) Len() int {
+func (s *SortedSetBuilder[T]) Len() int {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
 	return s.s.Len()
 }
 
+// IsEmpty returns true if the underlying set contains no elements.
+func (s *SortedSetBuilder[T]) IsEmpty() bool {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
+	return s.s.IsEmpty()
+}
+
+// Clear removes all values from the underlying set while keeping its comparer.
+func (s *SortedSetBuilder[T]) Clear() {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
+	set := NewSortedSet(s.s.m.comparer)
+	s.s, s.shared, s.owned = &set, false, nil
+}
+
 // SortedSet returns the current copy of the set.
 // The builder should not be used again after the list after this call.
-func (s SortedSetBuilder[T]) SortedSet() SortedSet[T] {
+func (s *SortedSetBuilder[T]) SortedSet() SortedSet[T] {
 	assert(s.s != nil, "immutable.SortedSetBuilder.SortedSet(): duplicate call to fetch sorted set")
 	set := s.s
 	s.s = nil
 	return *set
 }
+
+// Iterator returns a new iterator for the underlying set positioned at the
+// first value. The iterator supports reverse iteration and seeking.
+func (s *SortedSetBuilder[T]) Iterator() *SortedSetIterator[T] {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
+	return s.s.Iterator()
+}
+
+// Build returns the current state of the builder as an immutable set.
+// Unlike SortedSet(), the builder remains valid after the call and later
+// changes to the builder do not affect the returned set.
+//
+// The returned set shares its nodes with the builder so later mutations copy
+// nodes as described in NewSortedSetBuilderFromSet(). Use SortedSet() instead
+// if the builder is no longer needed.
+func (s *SortedSetBuilder[T]) Build() SortedSet[T] {
+	assert(s.s != nil, "immutable.SortedSetBuilder: builder invalid after SortedSet() invocation")
+	s.shared, s.owned = true, nil
+	return SortedSet[T]{s.s.m.clone()}
+}
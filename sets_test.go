@@ -1,6 +1,9 @@
 package immutable
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -23,13 +26,51 @@ func TestSetsPut(t *testing.T) {
 	itr := s2.Iterator()
 	counter := 0
 	for !itr.Done() {
-		i, v := itr.Next()
-		t.Log(i, v)
+		v, ok := itr.Next()
+		if !ok || v != "1" {
+			t.Fatalf("Unexpected iterator value: %q, %v", v, ok)
+		}
 		counter++
 	}
 	if counter != 1 {
 		t.Fatalf("iterator wrong length")
 	}
+	if v, ok := itr.Next(); ok || v != "" {
+		t.Fatalf("Unexpected value from done iterator: %q, %v", v, ok)
+	}
+}
+
+func TestSetsAddMany(t *testing.T) {
+	s := NewSet[string](nil, "1")
+	s2 := s.Add("2", "3", "1")
+	if s.Len() != 1 {
+		t.Fatalf("Unexpected mutation of set")
+	} else if s2.Len() != 3 || !s2.Has("1") || !s2.Has("2") || !s2.Has("3") {
+		t.Fatalf("Unexpected set contents")
+	}
+
+	ss := NewSortedSet[string](nil, "1").Add("3", "2")
+	if got, exp := fmt.Sprint(ss.Items()), "[1 2 3]"; got != exp {
+		t.Fatalf("Unexpected sorted set items: %s, expected %s", got, exp)
+	}
+
+	// Ensure no copy is made without values.
+	if s.Add().m != s.m || ss.Add().m != ss.m {
+		t.Fatalf("Expected original set")
+	}
+
+	// Ensure large additions do not affect the nodes of the original sets.
+	values := make([]int, 5000)
+	for i := range values {
+		values[i] = i
+	}
+	a, sa := NewSet[int](nil, values[:2500]...), NewSortedSet[int](nil, values[:2500]...)
+	a2, sa2 := a.Add(values...), sa.Add(values...)
+	if a.Len() != 2500 || sa.Len() != 2500 || a.Has(2500) || sa.Has(2500) {
+		t.Fatalf("Unexpected mutation of set")
+	} else if a2.Len() != 5000 || sa2.Len() != 5000 || !a2.Has(4999) || !sa2.Has(4999) {
+		t.Fatalf("Unexpected set contents")
+	}
 }
 
 func TestSetsDelete(t *testing.T) {
@@ -50,6 +91,114 @@ func TestSetsDelete(t *testing.T) {
 	}
 }
 
+func TestSetBuilder(t *testing.T) {
+	b := NewSetBuilder[int](nil)
+	for i := 0; i < 100; i++ {
+		b.Set(i)
+	}
+	b.Delete(0)
+	if b.Len() != 99 {
+		t.Fatalf("Unexpected builder length: %d", b.Len())
+	} else if b.Has(0) || !b.Has(1) || !b.Has(99) {
+		t.Fatalf("Unexpected builder contents")
+	}
+}
+
+func TestSetsDifferenceSlice(t *testing.T) {
+	s := NewSet[string](nil, "1", "2", "3")
+	s2 := s.DifferenceSlice([]string{"1", "3", "4"})
+	if s.Len() != 3 {
+		t.Fatalf("Unexpected mutation of set")
+	}
+	if s2.Len() != 1 {
+		t.Fatalf("Unexpected set length after difference")
+	}
+	if !s2.Has("2") {
+		t.Fatalf("Set element missing")
+	}
+	if s3 := NewSet[string](nil).DifferenceSlice([]string{"1"}); s3.Len() != 0 {
+		t.Fatalf("Unexpected set length after difference")
+	}
+
+	// Ensure shared nodes of every type are copied before removal.
+	h := HasherFunc(func(v int) uint32 { return uint32(v % 300) }, func(a, b int) bool { return a == b })
+	for _, n := range []int{5, 20, 200, 1000} {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = i
+		}
+		s := NewSet(h, values...)
+		other := s.DifferenceSlice(values[:n/2])
+		if s.Len() != n || other.Len() != n-n/2 {
+			t.Fatalf("n=%d: unexpected sizes: %d, %d", n, s.Len(), other.Len())
+		}
+		for _, v := range values {
+			if !s.Has(v) {
+				t.Fatalf("n=%d: value %d removed from original set", n, v)
+			} else if other.Has(v) != (v >= n/2) {
+				t.Fatalf("n=%d: unexpected membership for %d", n, v)
+			}
+		}
+	}
+}
+
+func TestSetsSortedSlice(t *testing.T) {
+	s := NewSet[string](nil, "c", "a", "d", "b")
+	items := s.SortedSlice(func(a, b string) int {
+		return strings.Compare(a, b)
+	})
+	if len(items) != 4 {
+		t.Fatalf("Set has wrong number of items")
+	}
+	for i, exp := range []string{"a", "b", "c", "d"} {
+		if items[i] != exp {
+			t.Fatalf("Item %d incorrectly sorted: %s", i, items[i])
+		}
+	}
+}
+
+func TestNewSetFromSlice(t *testing.T) {
+	values := []int{3, 1, 2, 1}
+	s := NewSetFromSlice(nil, values)
+	if s.Len() != 3 || !s.Has(1) || !s.Has(2) || !s.Has(3) {
+		t.Fatalf("unexpected set: %v", s.Items())
+	}
+
+	items := s.ToSlice()
+	sort.Ints(items)
+	if got, exp := fmt.Sprint(items), "[1 2 3]"; got != exp {
+		t.Fatalf("ToSlice()=%s, expected %s", got, exp)
+	} else if items := NewSetFromSlice[int](nil, nil).ToSlice(); len(items) != 0 {
+		t.Fatalf("unexpected items: %v", items)
+	}
+}
+
+func TestSetBuilderBuild(t *testing.T) {
+	b := NewSetBuilder[int](nil)
+	b.Set(1)
+	b.Set(2)
+	s := b.Build()
+	b.Delete(1)
+	b.Set(3)
+	if s.Len() != 2 || !s.Has(1) || !s.Has(2) {
+		t.Fatalf("built set changed: %v", s.Items())
+	} else if b.Len() != 2 || b.Has(1) || !b.Has(3) {
+		t.Fatalf("unexpected builder items: %v", b.Build().Items())
+	}
+
+	sb := NewSortedSetBuilder[int](nil)
+	sb.Set(1)
+	sb.Set(2)
+	ss := sb.Build()
+	sb.Delete(1)
+	sb.Set(3)
+	if got, exp := fmt.Sprint(ss.Items()), "[1 2]"; got != exp {
+		t.Fatalf("built set changed: %s", got)
+	} else if got, exp := fmt.Sprint(sb.SortedSet().Items()), "[2 3]"; got != exp {
+		t.Fatalf("unexpected builder items: %s, expected %s", got, exp)
+	}
+}
+
 func TestSortedSetsPut(t *testing.T) {
 	s := NewSortedSet[string](nil)
 	s2 := s.Add("1").Add("1").Add("0")
@@ -69,12 +218,14 @@ func TestSortedSetsPut(t *testing.T) {
 	itr := s2.Iterator()
 	counter := 0
 	for !itr.Done() {
-		i, v := itr.Next()
-		t.Log(i, v)
-		if counter == 0 && i != "0" {
+		v, ok := itr.Next()
+		if !ok {
+			t.Fatalf("Unexpected end of iterator")
+		}
+		if counter == 0 && v != "0" {
 			t.Fatalf("sort did not work for first el")
 		}
-		if counter == 1 && i != "1" {
+		if counter == 1 && v != "1" {
 			t.Fatalf("sort did not work for second el")
 		}
 		counter++
@@ -107,6 +258,9 @@ func TestSortedSetBuilder(t *testing.T) {
 	b.Set("test3")
 	b.Set("test1")
 	b.Set("test2")
+	if b.Len() != 3 {
+		t.Fatalf("Unexpected builder length: %d", b.Len())
+	}
 
 	s := b.SortedSet()
 	items := s.Items()
@@ -123,4 +277,499 @@ func TestSortedSetBuilder(t *testing.T) {
 	if items[2] != "test3" {
 		t.Fatalf("Third item incorrectly sorted")
 	}
+
+	for name, fn := range map[string]func(){
+		"Set":     func() { b.Set("test4") },
+		"Delete":  func() { b.Delete("test1") },
+		"Has":     func() { b.Has("test1") },
+		"Len":     func() { b.Len() },
+		"IsEmpty": func() { b.IsEmpty() },
+		"Clear":   func() { b.Clear() },
+	} {
+		var r string
+		func() {
+			defer func() { r = recover().(string) }()
+			fn()
+		}()
+		if r != `immutable.SortedSetBuilder: builder invalid after SortedSet() invocation` {
+			t.Fatalf("%s: unexpected panic: %q", name, r)
+		}
+	}
+}
+
+func TestSortedSetToSlice(t *testing.T) {
+	s := NewSortedSetFromSlice(nil, []int{5, 3, 1, 4, 3})
+	if got, exp := fmt.Sprint(s.ToSlice()), "[1 3 4 5]"; got != exp {
+		t.Fatalf("ToSlice()=%s, expected %s", got, exp)
+	} else if items := s.ToSlice(); cap(items) != s.Len() {
+		t.Fatalf("unexpected capacity: %d", cap(items))
+	}
+
+	desc := NewSortedSetFromSlice(ReverseComparer(NewComparer(0)), []int{1, 3, 2})
+	if got, exp := fmt.Sprint(desc.ToSlice()), "[3 2 1]"; got != exp {
+		t.Fatalf("ToSlice()=%s, expected %s", got, exp)
+	} else if items := NewSortedSet[int](nil).ToSlice(); len(items) != 0 {
+		t.Fatalf("unexpected items: %v", items)
+	}
+}
+
+func TestSortedSetSplit(t *testing.T) {
+	s := NewSortedSet[int](nil, 5, 1, 4, 2, 3)
+	less, gte := s.Split(3)
+	if items := less.Items(); len(items) != 2 || items[0] != 1 || items[1] != 2 {
+		t.Fatalf("Unexpected less items: %v", items)
+	}
+	if items := gte.Items(); len(items) != 3 || items[0] != 3 || items[1] != 4 || items[2] != 5 {
+		t.Fatalf("Unexpected greater or equal items: %v", items)
+	}
+	if s.Len() != 5 {
+		t.Fatalf("Unexpected mutation of set")
+	}
+
+	less, gte = NewSortedSet[int](nil).Split(3)
+	if less.Len() != 0 || gte.Len() != 0 {
+		t.Fatalf("Unexpected non-empty split of empty set")
+	}
+}
+
+func TestSortedSetForEachRange(t *testing.T) {
+	s := NewSortedSet[int](nil)
+	for i := 0; i < 1000; i += 2 {
+		s = s.Add(i)
+	}
+
+	var items []int
+	s.ForEachRange(99, 111, func(v int) bool {
+		items = append(items, v)
+		return true
+	})
+	if got, exp := fmt.Sprint(items), "[100 102 104 106 108 110]"; got != exp {
+		t.Fatalf("Unexpected items: %s, expected %s", got, exp)
+	}
+
+	items = nil
+	s.ForEachRange(0, 1000, func(v int) bool {
+		items = append(items, v)
+		return v < 4
+	})
+	if got, exp := fmt.Sprint(items), "[0 2 4]"; got != exp {
+		t.Fatalf("Unexpected items: %s, expected %s", got, exp)
+	}
+
+	s.ForEachRange(10, 10, func(v int) bool {
+		t.Fatalf("Unexpected callback for empty range")
+		return true
+	})
+	s.ForEachRange(20, 10, func(v int) bool {
+		t.Fatalf("Unexpected callback for inverted range")
+		return true
+	})
+	NewSortedSet[int](nil).ForEachRange(0, 10, func(v int) bool {
+		t.Fatalf("Unexpected callback for empty set")
+		return true
+	})
+}
+
+func TestSortedSetIteratorSeekReverse(t *testing.T) {
+	s := NewSortedSet(nil, 1, 3, 5, 7)
+
+	var items []int
+	itr := s.Iterator()
+	for itr.SeekReverse(6); !itr.Done(); {
+		v, _ := itr.Prev()
+		items = append(items, v)
+	}
+	if got, exp := fmt.Sprint(items), "[5 3 1]"; got != exp {
+		t.Fatalf("Unexpected items: %s, expected %s", got, exp)
+	}
+}
+
+func TestSortedSetMinMax(t *testing.T) {
+	s := NewSortedSet(nil, 3, 1, 2)
+	if v, ok := s.Min(); !ok || v != 1 {
+		t.Fatalf("Min()=<%v,%v>, expected <1,true>", v, ok)
+	} else if v, ok := s.Max(); !ok || v != 3 {
+		t.Fatalf("Max()=<%v,%v>, expected <3,true>", v, ok)
+	}
+
+	v, other, ok := s.PopMin()
+	if !ok || v != 1 {
+		t.Fatalf("PopMin()=<%v,%v>, expected <1,true>", v, ok)
+	} else if got, exp := fmt.Sprint(other.Items()), "[2 3]"; got != exp {
+		t.Fatalf("Unexpected items: %s, expected %s", got, exp)
+	} else if s.Len() != 3 {
+		t.Fatal("original set changed")
+	}
+
+	v, other, ok = s.PopMax()
+	if !ok || v != 3 {
+		t.Fatalf("PopMax()=<%v,%v>, expected <3,true>", v, ok)
+	} else if got, exp := fmt.Sprint(other.Items()), "[1 2]"; got != exp {
+		t.Fatalf("Unexpected items: %s, expected %s", got, exp)
+	}
+
+	var empty SortedSet[int]
+	if _, ok := empty.Min(); ok {
+		t.Fatal("expected no min for empty set")
+	} else if _, ok := NewSortedSet[int](nil).Max(); ok {
+		t.Fatal("expected no max for empty set")
+	} else if _, _, ok := empty.PopMin(); ok {
+		t.Fatal("expected no pop for empty set")
+	} else if _, _, ok := NewSortedSet[int](nil).PopMax(); ok {
+		t.Fatal("expected no pop for empty set")
+	}
+}
+
+func TestSortedSetAlgebra(t *testing.T) {
+	a := NewSortedSet[int](nil, 1, 2, 3, 4, 5)
+	b := NewSortedSet[int](nil, 4, 5, 6, 7)
+	empty := NewSortedSet[int](nil)
+
+	for _, tt := range []struct {
+		name string
+		s    SortedSet[int]
+		exp  string
+	}{
+		{"Union", a.Union(b), "[1 2 3 4 5 6 7]"},
+		{"Intersect", a.Intersect(b), "[4 5]"},
+		{"Difference", a.Difference(b), "[1 2 3]"},
+		{"ReverseDifference", b.Difference(a), "[6 7]"},
+		{"UnionEmpty", empty.Union(b), "[4 5 6 7]"},
+		{"IntersectEmpty", a.Intersect(empty), "[]"},
+		{"DifferenceEmpty", a.Difference(empty), "[1 2 3 4 5]"},
+	} {
+		if got := fmt.Sprint(tt.s.Items()); got != tt.exp {
+			t.Fatalf("%s: got %s, expected %s", tt.name, got, tt.exp)
+		}
+	}
+
+	if a.Len() != 5 || b.Len() != 4 {
+		t.Fatalf("Unexpected mutation of sets")
+	}
+
+	// Ensure the comparer of other is used if the set has none yet.
+	desc := ComparerFunc(func(a, b any) bool { return a.(int) > b.(int) })
+	u := NewSortedSet[any](nil).Union(NewSortedSet[any](desc, 1, 2, 3))
+	if got, exp := fmt.Sprint(u.Add(10).Items()), "[10 3 2 1]"; got != exp {
+		t.Fatalf("Union comparer: got %s, expected %s", got, exp)
+	}
+
+	// Ensure large results are bulk loaded into a valid tree.
+	var evens, odds []int
+	for i := 0; i < 5000; i += 2 {
+		evens, odds = append(evens, i), append(odds, i+1)
+	}
+	all := NewSortedSet(nil, evens...).Union(NewSortedSet(nil, odds...))
+	if all.Len() != 5000 {
+		t.Fatalf("Unexpected union size: %d", all.Len())
+	}
+	itr := all.Iterator()
+	for i := 0; !itr.Done(); i++ {
+		if v, _ := itr.Next(); v != i {
+			t.Fatalf("Unexpected union value at %d: %d", i, v)
+		}
+	}
+	if other := all.Add(5000).Delete(0); other.Len() != 5000 || !other.Has(5000) || other.Has(0) {
+		t.Fatalf("Unexpected set after update")
+	}
+
+	// Ensure sets with different comparers use the comparer of the receiver.
+	asc, rev := NewSortedSet(nil, 1, 3, 5, 6), NewSortedSet(ReverseComparer(NewComparer(0)), 2, 4, 6)
+	if got, exp := fmt.Sprint(asc.Union(rev).Items()), "[1 2 3 4 5 6]"; got != exp {
+		t.Fatalf("Mixed Union: got %s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(asc.Intersect(rev).Items()), "[6]"; got != exp {
+		t.Fatalf("Mixed Intersect: got %s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(asc.Difference(rev).Items()), "[1 3 5]"; got != exp {
+		t.Fatalf("Mixed Difference: got %s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(rev.Union(asc).Items()), "[6 5 4 3 2 1]"; got != exp {
+		t.Fatalf("Mixed reverse Union: got %s, expected %s", got, exp)
+	}
+}
+
+func TestSetEqual(t *testing.T) {
+	a := NewSet[int](nil, 1, 2, 3)
+	if !a.Equal(NewSet[int](nil, 3, 2, 1)) {
+		t.Fatalf("Expected sets to be equal")
+	} else if a.Equal(NewSet[int](nil, 1, 2)) {
+		t.Fatalf("Expected sets with different lengths to be unequal")
+	} else if a.Equal(NewSet[int](nil, 1, 2, 4)) {
+		t.Fatalf("Expected sets with different values to be unequal")
+	} else if !NewSet[int](nil).Equal(NewSet[int](nil)) {
+		t.Fatalf("Expected empty sets to be equal")
+	}
+}
+
+func TestSortedSetEqual(t *testing.T) {
+	a := NewSortedSet[int](nil, 1, 2, 3)
+	if !a.Equal(NewSortedSet[int](nil, 3, 2, 1)) {
+		t.Fatalf("Expected sets to be equal")
+	} else if a.Equal(NewSortedSet[int](nil, 1, 2)) {
+		t.Fatalf("Expected sets with different lengths to be unequal")
+	} else if a.Equal(NewSortedSet[int](nil, 1, 2, 4)) {
+		t.Fatalf("Expected sets with different values to be unequal")
+	} else if !NewSortedSet[int](nil).Equal(NewSortedSet[int](nil)) {
+		t.Fatalf("Expected empty sets to be equal")
+	}
+}
+
+func TestSetSubset(t *testing.T) {
+	a := NewSet[int](nil, 1, 2)
+	b := NewSet[int](nil, 1, 2, 3)
+	if !a.IsSubsetOf(b) || a.IsSupersetOf(b) {
+		t.Fatalf("Expected a to be a proper subset of b")
+	} else if b.IsSubsetOf(a) || !b.IsSupersetOf(a) {
+		t.Fatalf("Expected b to be a proper superset of a")
+	} else if !a.IsSubsetOf(a) || !a.IsSupersetOf(a) {
+		t.Fatalf("Expected set to be a subset and superset of itself")
+	} else if NewSet[int](nil, 1, 4).IsSubsetOf(b) {
+		t.Fatalf("Expected set with extra value not to be a subset")
+	} else if !NewSet[int](nil).IsSubsetOf(a) {
+		t.Fatalf("Expected empty set to be a subset")
+	}
+}
+
+func TestSortedSetSubset(t *testing.T) {
+	a := NewSortedSet[int](nil, 1, 3)
+	b := NewSortedSet[int](nil, 1, 2, 3)
+	if !a.IsSubsetOf(b) || a.IsSupersetOf(b) {
+		t.Fatalf("Expected a to be a proper subset of b")
+	} else if b.IsSubsetOf(a) || !b.IsSupersetOf(a) {
+		t.Fatalf("Expected b to be a proper superset of a")
+	} else if !a.IsSubsetOf(a) || !a.IsSupersetOf(a) {
+		t.Fatalf("Expected set to be a subset and superset of itself")
+	} else if NewSortedSet[int](nil, 0, 1).IsSubsetOf(b) {
+		t.Fatalf("Expected set with smaller value not to be a subset")
+	} else if NewSortedSet[int](nil, 3, 4).IsSubsetOf(b) {
+		t.Fatalf("Expected set with larger value not to be a subset")
+	} else if !NewSortedSet[int](nil).IsSubsetOf(a) || !a.IsSupersetOf(NewSortedSet[int](nil)) {
+		t.Fatalf("Expected empty set to be a subset")
+	}
+}
+
+func TestSetBuilderFromSet(t *testing.T) {
+	s := NewSet[int](nil, 1, 2, 3)
+	b := NewSetBuilderFromSet(s)
+	if b.Len() != 3 || !b.Has(1) || !b.Has(2) || !b.Has(3) {
+		t.Fatalf("Unexpected builder contents")
+	}
+	if s.Len() != 3 {
+		t.Fatalf("Unexpected mutation of set")
+	}
+
+	b.Set(4)
+	b.Delete(1)
+	if b.Len() != 3 || b.Has(1) || !b.Has(4) {
+		t.Fatalf("Unexpected builder contents after mutation")
+	} else if s.Len() != 3 || !s.Has(1) || s.Has(4) {
+		t.Fatalf("Unexpected mutation of set")
+	}
+
+	// Ensure removing values from a large set does not affect the set.
+	var values []int
+	for i := 0; i < 2000; i++ {
+		values = append(values, i)
+	}
+	s = NewSet(nil, values...)
+	b = NewSetBuilderFromSet(s)
+	for i := 0; i < 2000; i += 2 {
+		b.Delete(i)
+	}
+	if b.Len() != 1000 || b.Has(0) || !b.Has(1) {
+		t.Fatalf("Unexpected builder contents after delete")
+	}
+	for _, v := range values {
+		if !s.Has(v) {
+			t.Fatalf("Unexpected mutation of set: %d removed", v)
+		}
+	}
+
+	// Ensure snapshots of a large set are unaffected by later changes.
+	b = NewSetBuilderFromSet(NewSet[int](nil))
+	var snapshots []Set[int]
+	for i := 0; i < 2000; i++ {
+		b.Set(i)
+		b.Delete(i - 10)
+		if i%100 == 0 {
+			snapshots = append(snapshots, b.Build())
+		}
+	}
+	for i, snapshot := range snapshots {
+		if n := i * 100; !snapshot.Has(n) || snapshot.Has(n+1) || snapshot.Has(n-10) {
+			t.Fatalf("Unexpected snapshot %d contents", i)
+		}
+	}
+}
+
+func TestSortedSetBuilderFromSet(t *testing.T) {
+	s := NewSortedSet[int](nil, 3, 1, 2)
+	b := NewSortedSetBuilderFromSet(s)
+	b.Set(4)
+	b.Delete(1)
+
+	if got, exp := fmt.Sprint(b.SortedSet().Items()), "[2 3 4]"; got != exp {
+		t.Fatalf("Unexpected builder items: %s, expected %s", got, exp)
+	} else if got, exp := fmt.Sprint(s.Items()), "[1 2 3]"; got != exp {
+		t.Fatalf("Unexpected mutation of set: %s", got)
+	}
+
+	// Ensure removing values from a large set does not affect the set.
+	var values []int
+	for i := 0; i < 2000; i++ {
+		values = append(values, i)
+	}
+	s = NewSortedSet(nil, values...)
+	b = NewSortedSetBuilderFromSet(s)
+	for i := 0; i < 2000; i += 2 {
+		b.Delete(i)
+	}
+	if b.Len() != 1000 || b.Has(0) || !b.Has(1) {
+		t.Fatalf("Unexpected builder contents after delete")
+	}
+	for _, v := range values {
+		if !s.Has(v) {
+			t.Fatalf("Unexpected mutation of set: %d removed", v)
+		}
+	}
+
+	// Ensure snapshots of a large set are unaffected by later changes.
+	b = NewSortedSetBuilderFromSet(NewSortedSet[int](nil))
+	var snapshots []SortedSet[int]
+	for i := 0; i < 2000; i++ {
+		b.Set(i)
+		b.Delete(i - 10)
+		if i%100 == 0 {
+			snapshots = append(snapshots, b.Build())
+		}
+	}
+	for i, snapshot := range snapshots {
+		if n := i * 100; !snapshot.Has(n) || snapshot.Has(n+1) || snapshot.Has(n-10) {
+			t.Fatalf("Unexpected snapshot %d contents", i)
+		} else if v, _ := snapshot.Max(); v != n {
+			t.Fatalf("Unexpected snapshot %d max: %d", i, v)
+		}
+	}
+}
+
+func TestSetBuilderIterator(t *testing.T) {
+	b := NewSetBuilder[int](nil)
+	b.Set(1)
+	b.Set(2)
+	b.Set(3)
+
+	var sum int
+	for itr := b.Iterator(); !itr.Done(); {
+		v, _ := itr.Next()
+		sum += v
+	}
+	if sum != 6 {
+		t.Fatalf("unexpected sum: %d", sum)
+	}
+
+}
+
+func TestSetBuilderNotInitialized(t *testing.T) {
+	b := &SetBuilder[int]{}
+	for name, fn := range map[string]func(){
+		"Set":      func() { b.Set(1) },
+		"Delete":   func() { b.Delete(1) },
+		"Build":    func() { b.Build() },
+		"Has":      func() { b.Has(1) },
+		"Len":      func() { b.Len() },
+		"Iterator": func() { b.Iterator() },
+		"IsEmpty":  func() { b.IsEmpty() },
+		"Clear":    func() { b.Clear() },
+	} {
+		var r string
+		func() {
+			defer func() { r = recover().(string) }()
+			fn()
+		}()
+		if r != `immutable.SetBuilder: builder not initialized, use NewSetBuilder()` {
+			t.Fatalf("%s: unexpected panic: %q", name, r)
+		}
+	}
+}
+
+func TestSortedSetBuilderIterator(t *testing.T) {
+	b := NewSortedSetBuilder[int](nil)
+	for _, v := range []int{3, 1, 4, 2} {
+		b.Set(v)
+	}
+
+	itr := b.Iterator()
+	if v, _ := itr.Next(); v != 1 {
+		t.Fatalf("unexpected first value: %d", v)
+	}
+	itr.Seek(3)
+	if v, _ := itr.Next(); v != 3 {
+		t.Fatalf("unexpected seek value: %d", v)
+	}
+	itr.Last()
+	if v, _ := itr.Prev(); v != 4 {
+		t.Fatalf("unexpected last value: %d", v)
+	}
+}
+
+func TestSetsIsEmpty(t *testing.T) {
+	var s Set[int]
+	var ss SortedSet[int]
+	if !s.IsEmpty() || !ss.IsEmpty() {
+		t.Fatalf("Expected zero value sets to be empty")
+	}
+	if NewSet[int](nil, 1).IsEmpty() || NewSortedSet[int](nil, 1).IsEmpty() {
+		t.Fatalf("Expected non-empty sets")
+	}
+
+	b, sb := NewSetBuilder[int](nil), NewSortedSetBuilder[int](nil)
+	if !b.IsEmpty() || !sb.IsEmpty() {
+		t.Fatalf("Expected new builders to be empty")
+	}
+	b.Set(1)
+	sb.Set(1)
+	if b.IsEmpty() || sb.IsEmpty() {
+		t.Fatalf("Expected non-empty builders")
+	}
+
+}
+
+func TestSetBuildersClear(t *testing.T) {
+	b := NewSetBuilder[int](nil)
+	b.Set(1)
+	b.Clear()
+	if b.Len() != 0 || b.Has(1) {
+		t.Fatalf("Unexpected set builder contents after clear")
+	}
+	b.Set(2)
+	if b.Len() != 1 || !b.Has(2) {
+		t.Fatalf("Unexpected set builder contents after reuse")
+	}
+
+	sb := NewSortedSetBuilder(ReverseComparer(NewComparer(0)))
+	sb.Set(1)
+	sb.Clear()
+	sb.Set(2)
+	sb.Set(3)
+	if got, exp := fmt.Sprint(sb.SortedSet().Items()), "[3 2]"; got != exp {
+		t.Fatalf("Unexpected sorted set items: %s, expected %s", got, exp)
+	}
+}
+
+func TestNewSetE(t *testing.T) {
+	if s, err := NewSetE[string](nil, "a", "b"); err != nil {
+		t.Fatal(err)
+	} else if s.Len() != 2 {
+		t.Fatalf("Unexpected set length: %d", s.Len())
+	}
+	if _, err := NewSetE[float64](nil); err == nil {
+		t.Fatalf("Expected error for unsupported value type")
+	}
+
+	if s, err := NewSortedSetE[int](nil, 2, 1); err != nil {
+		t.Fatal(err)
+	} else if got, exp := fmt.Sprint(s.Items()), "[1 2]"; got != exp {
+		t.Fatalf("Unexpected items: %s, expected %s", got, exp)
+	}
+	if _, err := NewSortedSetE[float64](nil); err == nil {
+		t.Fatalf("Expected error for unsupported value type")
+	}
 }